@@ -0,0 +1,122 @@
+package main
+
+import (
+	"Backend-Recything/controllers"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Fungsi untuk menjalankan subcommand CLI dan mengembalikan exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "regions":
+		return runRegionsCommand(args[1:])
+	case "admin":
+		return runAdminCommand(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage:")
+	fmt.Fprintln(os.Stderr, "  Backend-Recything                                   start the HTTP server")
+	fmt.Fprintln(os.Stderr, "  Backend-Recything regions import -level <level> [-name-prop NAME] [-code-prop CODE] <file.geojson>")
+	fmt.Fprintln(os.Stderr, "  Backend-Recything regions backfill [-all]")
+	fmt.Fprintln(os.Stderr, "  Backend-Recything admin create -email EMAIL -name NAME [-phone PHONE] [-birth-date YYYY-MM-DD]")
+}
+
+// Subcommand untuk impor batas wilayah dan penentuan wilayah laporan lama
+func runRegionsCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+
+	switch args[0] {
+	case "import":
+		fs := flag.NewFlagSet("regions import", flag.ContinueOnError)
+		level := fs.String("level", "", "region level: kelurahan, kecamatan, city or province")
+		nameProp := fs.String("name-prop", "name", "feature property holding the region name")
+		codeProp := fs.String("code-prop", "code", "feature property holding the unique region code")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			printUsage()
+			return 2
+		}
+
+		count, err := controllers.ImportRegionsFromGeoJSON(fs.Arg(0), controllers.RegionImportOptions{
+			Level:        *level,
+			NameProperty: *nameProp,
+			CodeProperty: *codeProp,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Imported %d %s regions. Run \"regions backfill -all\" to reassign existing reports.\n", count, *level)
+		return 0
+
+	case "backfill":
+		fs := flag.NewFlagSet("regions backfill", flag.ContinueOnError)
+		all := fs.Bool("all", false, "recompute the region of every report, not only unassigned ones")
+		if err := fs.Parse(args[1:]); err != nil {
+			printUsage()
+			return 2
+		}
+
+		count, err := controllers.BackfillReportRegions(*all)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "backfill failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Updated the region of %d reports.\n", count)
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown regions command %q\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+// Subcommand untuk membuat admin pertama. Admin berikutnya diundang lewat /admin/invitations.
+func runAdminCommand(args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		printUsage()
+		return 2
+	}
+
+	fs := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	email := fs.String("email", "", "admin email")
+	name := fs.String("name", "", "admin full name")
+	phone := fs.String("phone", "", "admin phone number")
+	birthDate := fs.String("birth-date", "", "admin birth date (YYYY-MM-DD)")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+		printUsage()
+		return 2
+	}
+
+	// Password dibaca dari environment agar tidak tersimpan di riwayat shell
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "set the ADMIN_PASSWORD environment variable to the new admin's password")
+		return 2
+	}
+
+	user, err := controllers.CreateAdminUser(controllers.RegisterInput{
+		NamaLengkap:  *name,
+		Email:        *email,
+		Password:     password,
+		TanggalLahir: *birthDate,
+		NoTelepon:    *phone,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create admin failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Created admin %s (id %d).\n", user.Email, user.ID)
+	return 0
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"Backend-Recything/helper"
 	"Backend-Recything/models"
 	"fmt"
 	"os"
@@ -42,14 +43,137 @@ func InitDB() error {
 		return fmt.Errorf("failed to connect to the database: %w", err)
 	}
 
+	// User lama dibuat sebelum verifikasi email ada, sehingga dianggap sudah terverifikasi
+	verifyExistingUsers := !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
 	// Auto-migrate models
-	if err := db.AutoMigrate(&models.User{}, &models.ReportRubbish{}, &models.Article{}, &models.Points{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.ReportRubbish{}, &models.Article{}, &models.PointTransaction{}, &models.ReportStatusChange{}, &models.PointRule{}, &models.Reward{}, &models.Redemption{},
+		&models.Achievement{}, &models.UserAchievement{}, &models.Notification{}, &models.ReportConfirmation{}, &models.GeocodeCache{}, &models.Region{}, &models.ExportJob{},
+		&models.ReportPhoto{}, &models.ReportCleanup{}, &models.Crew{}, &models.CrewMember{},
+		&models.ReportAssignment{}, &models.ReportAssignmentEvent{}, &models.RefreshToken{}, &models.RevokedToken{},
+		&models.UserSession{}, &models.AdminInvitation{}, &models.RolePermission{},
+		&models.EmailVerification{}); err != nil {
 		return fmt.Errorf("failed to migrate database models: %w", err)
 	}
 
+	if verifyExistingUsers {
+		if err := db.Model(&models.User{}).Where("email_verified_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			return fmt.Errorf("failed to mark existing users as verified: %w", err)
+		}
+	}
+
+	// Ubah status laporan lama ke status siklus hidup yang baru
+	if err := migrateLegacyReportStatuses(db); err != nil {
+		return fmt.Errorf("failed to migrate report statuses: %w", err)
+	}
+
+	// Isi geohash untuk laporan lama yang sudah memiliki koordinat
+	if err := backfillReportGeohashes(db); err != nil {
+		return fmt.Errorf("failed to backfill report geohashes: %w", err)
+	}
+
+	// Pindahkan foto tunggal laporan lama ke galeri foto
+	if err := backfillReportPhotos(db); err != nil {
+		return fmt.Errorf("failed to backfill report photos: %w", err)
+	}
+
+	// Samakan ledger poin dengan saldo yang sudah ada di users.points
+	if err := backfillPointLedger(db); err != nil {
+		return fmt.Errorf("failed to backfill point ledger: %w", err)
+	}
+
+	// Buat definisi achievement bawaan jika belum ada
+	if err := seedAchievements(db); err != nil {
+		return fmt.Errorf("failed to seed achievements: %w", err)
+	}
+
+	// Isi permission bawaan setiap role saat RBAC pertama kali dipakai
+	if err := seedRolePermissions(db); err != nil {
+		return fmt.Errorf("failed to seed role permissions: %w", err)
+	}
+
 	DB = db
 	return nil
 }
+
+// migrateLegacyReportStatuses memetakan status lama ("process", "pending") ke "submitted"
+func migrateLegacyReportStatuses(db *gorm.DB) error {
+	return db.Model(&models.ReportRubbish{}).
+		Where("status IN ? OR status IS NULL", []string{"", "process", "pending"}).
+		Update("status", models.ReportStatusSubmitted).Error
+}
+
+// backfillReportGeohashes menghitung geohash untuk laporan yang belum memilikinya
+func backfillReportGeohashes(db *gorm.DB) error {
+	var reports []models.ReportRubbish
+	return db.Select("id", "latitude", "longitude").
+		Where("(geohash IS NULL OR geohash = '') AND (latitude <> 0 OR longitude <> 0)").
+		FindInBatches(&reports, 500, func(tx *gorm.DB, batch int) error {
+			for _, report := range reports {
+				hash := helper.EncodeGeohash(report.Latitude, report.Longitude, models.ReportGeohashPrecision)
+				if err := db.Model(&models.ReportRubbish{}).Where("id = ?", report.ID).Update("geohash", hash).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// backfillReportPhotos menyalin kolom photo laporan yang belum punya galeri menjadi foto pertama di report_photos
+func backfillReportPhotos(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO report_photos (report_id, url, caption, position, created_at)
+		SELECT r.id, r.photo, '', 0, r.created_at
+		FROM report_rubbishes r
+		WHERE r.photo IS NOT NULL AND r.photo <> ''
+		AND NOT EXISTS (SELECT 1 FROM report_photos p WHERE p.report_id = r.id)`).Error
+}
+
+// backfillPointLedger membuat entri saldo awal untuk user yang saldonya belum tercatat di ledger.
+// Aman dijalankan berulang kali karena hanya menyentuh user yang selisihnya tidak nol.
+func backfillPointLedger(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO point_transactions (user_id, delta, balance_after, reason, created_at)
+		SELECT u.id, CAST(u.points AS SIGNED) - COALESCE(SUM(pt.delta), 0), u.points, ?, NOW()
+		FROM users u
+		LEFT JOIN point_transactions pt ON pt.user_id = u.id
+		GROUP BY u.id, u.points
+		HAVING CAST(u.points AS SIGNED) <> COALESCE(SUM(pt.delta), 0)`,
+		models.PointReasonOpeningBalance).Error
+}
+
+// seedAchievements membuat badge bawaan tanpa menimpa perubahan yang dibuat admin
+func seedAchievements(db *gorm.DB) error {
+	for _, achievement := range models.DefaultAchievements {
+		achievement := achievement
+		if err := db.Where(models.Achievement{Code: achievement.Code}).FirstOrCreate(&achievement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedRolePermissions mengisi models.DefaultRolePermissions hanya jika tabel role_permissions masih kosong,
+// sehingga perubahan yang dibuat admin lewat API tidak ditimpa saat aplikasi dijalankan ulang
+func seedRolePermissions(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.RolePermission{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	for role, permissions := range models.DefaultRolePermissions {
+		for _, permission := range permissions {
+			if err := db.Create(&models.RolePermission{Role: role, Permission: permission}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func InitCloudinary() (*cloudinary.Cloudinary, error) {
 	cld, err := cloudinary.NewFromURL(os.Getenv("CLOUDINARY_URL"))
 	if err != nil {
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// GetEnvFloat membaca variabel environment bertipe angka, atau fallback jika kosong/tidak valid
+func GetEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvInt membaca variabel environment bertipe bilangan bulat, atau fallback jika kosong/tidak valid
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/models"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Metrik yang perlu dihitung ulang untuk setiap event
+var achievementEventMetrics = map[string][]string{
+	models.AchievementEventReportCreated: {
+		models.AchievementMetricReportsCreated,
+		models.AchievementMetricDistinctDistricts,
+		models.AchievementMetricReportStreakDays,
+	},
+	models.AchievementEventReportApproved: {
+		models.AchievementMetricReportsApproved,
+	},
+	models.AchievementEventRedemption: {
+		models.AchievementMetricRedemptions,
+	},
+}
+
+// Struct untuk respons badge di profil user
+type BadgeResponse struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IconURL     string `json:"icon_url"`
+	Progress    int    `json:"progress"`
+	Threshold   int    `json:"threshold"`
+	Unlocked    bool   `json:"unlocked"`
+	UnlockedAt  string `json:"unlocked_at,omitempty"`
+}
+
+// Struct untuk badge yang sudah dan belum terbuka
+type UserBadges struct {
+	Earned     []BadgeResponse `json:"earned"`
+	InProgress []BadgeResponse `json:"in_progress"`
+}
+
+// computeAchievementMetric menghitung nilai metrik achievement untuk seorang user
+func computeAchievementMetric(tx *gorm.DB, userID uint, metric string) (int, error) {
+	var value int64
+	switch metric {
+	case models.AchievementMetricReportsCreated:
+		err := tx.Model(&models.ReportRubbish{}).Where("user_id = ?", userID).Count(&value).Error
+		return int(value), err
+
+	case models.AchievementMetricReportsApproved:
+		// Laporan dihitung disetujui dari statusnya, bukan dari poin, agar persetujuan tanpa poin tetap terhitung.
+		// Laporan closed hanya dihitung jika sebelumnya sudah dibersihkan (closed juga bisa berasal dari rejected).
+		cleanedUp := tx.Model(&models.ReportStatusChange{}).Select("report_id").Where("to_status = ?", models.ReportStatusCleanedUp)
+		err := tx.Model(&models.ReportRubbish{}).
+			Where("user_id = ?", userID).
+			Where("status IN ? OR (status = ? AND id IN (?))",
+				[]string{models.ReportStatusApproved, models.ReportStatusCleanedUp}, models.ReportStatusClosed, cleanedUp).
+			Count(&value).Error
+		return int(value), err
+
+	case models.AchievementMetricDistinctDistricts:
+		// Kecamatan dihitung per kota karena nama kecamatan bisa sama di kota berbeda.
+		// Laporan lama tanpa kecamatan dihitung per kota.
+		err := tx.Model(&models.ReportRubbish{}).
+			Select("COUNT(DISTINCT city, kecamatan)").
+			Where("user_id = ? AND city <> ''", userID).
+			Scan(&value).Error
+		return int(value), err
+
+	case models.AchievementMetricReportStreakDays:
+		var days []time.Time
+		if err := tx.Model(&models.ReportRubbish{}).
+			Where("user_id = ?", userID).
+			Distinct().
+			Order("DATE(created_at) ASC").
+			Pluck("DATE(created_at)", &days).Error; err != nil {
+			return 0, err
+		}
+		return longestDailyStreak(days), nil
+
+	case models.AchievementMetricRedemptions:
+		err := tx.Model(&models.Redemption{}).
+			Where("user_id = ? AND status <> ?", userID, models.RedemptionStatusCancelled).
+			Count(&value).Error
+		return int(value), err
+	}
+
+	return 0, fmt.Errorf("unknown achievement metric: %s", metric)
+}
+
+// longestDailyStreak menghitung rangkaian hari berturut-turut terpanjang dari daftar tanggal terurut
+func longestDailyStreak(days []time.Time) int {
+	longest, current := 0, 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			current++
+		} else {
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// evaluateAchievements memperbarui progres badge yang relevan dengan event
+// dan mengirim notifikasi untuk badge yang baru terbuka.
+func evaluateAchievements(tx *gorm.DB, userID uint, event string) ([]models.Achievement, error) {
+	metrics := achievementEventMetrics[event]
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	var achievements []models.Achievement
+	if err := tx.Where("active = ? AND metric IN ?", true, metrics).Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+
+	var unlocked []models.Achievement
+	values := make(map[string]int)
+	for _, achievement := range achievements {
+		value, ok := values[achievement.Metric]
+		if !ok {
+			var err error
+			value, err = computeAchievementMetric(tx, userID, achievement.Metric)
+			if err != nil {
+				return nil, err
+			}
+			values[achievement.Metric] = value
+		}
+
+		userAchievement := models.UserAchievement{UserID: userID, AchievementID: achievement.ID}
+		if err := tx.Where(userAchievement).FirstOrCreate(&userAchievement).Error; err != nil {
+			return nil, err
+		}
+
+		// Badge yang sudah terbuka tidak dikunci lagi meskipun progres turun
+		userAchievement.Progress = value
+		if userAchievement.UnlockedAt == nil && value >= achievement.Threshold {
+			now := time.Now()
+			userAchievement.UnlockedAt = &now
+			unlocked = append(unlocked, achievement)
+
+			if err := tx.Create(&models.Notification{
+				UserID:  userID,
+				Type:    models.NotificationTypeAchievementUnlocked,
+				Title:   "Badge baru: " + achievement.Name,
+				Message: achievement.Description,
+			}).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		if err := tx.Save(&userAchievement).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return unlocked, nil
+}
+
+// runAchievementEvaluation menjalankan evaluasi badge setelah aksi utama selesai.
+// Kegagalan hanya dicatat di log agar tidak menggagalkan aksi user.
+func runAchievementEvaluation(userID uint, event string) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := evaluateAchievements(tx, userID, event)
+		return err
+	})
+	if err != nil {
+		log.Printf("Achievement evaluation failed for user %d on %s: %v", userID, event, err)
+	}
+}
+
+// loadUserBadges mengambil badge user yang sudah terbuka dan yang masih dalam progres
+func loadUserBadges(userID uint) (UserBadges, error) {
+	badges := UserBadges{Earned: []BadgeResponse{}, InProgress: []BadgeResponse{}}
+
+	var achievements []models.Achievement
+	if err := config.DB.Where("active = ?", true).Order("id ASC").Find(&achievements).Error; err != nil {
+		return badges, err
+	}
+
+	var userAchievements []models.UserAchievement
+	if err := config.DB.Where("user_id = ?", userID).Find(&userAchievements).Error; err != nil {
+		return badges, err
+	}
+	progressByID := make(map[uint]models.UserAchievement, len(userAchievements))
+	for _, ua := range userAchievements {
+		progressByID[ua.AchievementID] = ua
+	}
+
+	for _, achievement := range achievements {
+		ua := progressByID[achievement.ID]
+		badge := BadgeResponse{
+			Code:        achievement.Code,
+			Name:        achievement.Name,
+			Description: achievement.Description,
+			IconURL:     achievement.IconURL,
+			Progress:    ua.Progress,
+			Threshold:   achievement.Threshold,
+			Unlocked:    ua.UnlockedAt != nil,
+			UnlockedAt:  formatOptionalDateTime(ua.UnlockedAt),
+		}
+		if badge.Unlocked {
+			badges.Earned = append(badges.Earned, badge)
+		} else {
+			badges.InProgress = append(badges.InProgress, badge)
+		}
+	}
+
+	return badges, nil
+}
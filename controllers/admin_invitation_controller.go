@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Masa berlaku bawaan undangan admin, bisa diubah lewat ADMIN_INVITE_TTL_HOURS
+const defaultAdminInviteTTLHours = 72
+
+// Error untuk undangan admin
+var (
+	ErrEmailAlreadyRegistered = errors.New("email is already registered")
+	ErrInvalidInvitation      = errors.New("invitation is invalid, expired or already used")
+	ErrInvitationEmail        = errors.New("email does not match the invitation")
+)
+
+// Struct untuk respons undangan admin
+type AdminInvitationResponse struct {
+	ID          uint   `json:"id"`
+	Email       string `json:"email"`
+	Status      string `json:"status"` // pending, accepted, expired atau revoked
+	InvitedByID uint   `json:"invited_by_id"`
+	ExpiresAt   string `json:"expires_at"`
+	CreatedAt   string `json:"created_at"`
+	Token       string `json:"token,omitempty"` // Hanya dikirim sekali saat undangan dibuat
+}
+
+func toAdminInvitationResponse(invitation models.AdminInvitation) AdminInvitationResponse {
+	status := "pending"
+	switch {
+	case invitation.AcceptedAt != nil:
+		status = "accepted"
+	case invitation.RevokedAt != nil:
+		status = "revoked"
+	case time.Now().After(invitation.ExpiresAt):
+		status = "expired"
+	}
+	return AdminInvitationResponse{
+		ID:          invitation.ID,
+		Email:       invitation.Email,
+		Status:      status,
+		InvitedByID: invitation.InvitedByID,
+		ExpiresAt:   invitation.ExpiresAt.Format("2006-01-02 15:04:05"),
+		CreatedAt:   invitation.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func emailRegistered(tx *gorm.DB, email string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+// Fungsi untuk mengundang admin baru (admin). Undangan sebelumnya untuk email yang sama dibatalkan.
+func CreateAdminInvitation(c echo.Context) error {
+	input := struct {
+		Email string `json:"email" validate:"required,email"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	input.Email = normalizeEmail(input.Email)
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	adminID, _ := c.Get("userID").(uint)
+	token, err := helper.RandomToken(32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create invitation", http.StatusInternalServerError, "error", nil))
+	}
+	ttl := config.GetEnvInt("ADMIN_INVITE_TTL_HOURS", defaultAdminInviteTTLHours)
+	if ttl < 1 {
+		ttl = defaultAdminInviteTTLHours
+	}
+	invitation := models.AdminInvitation{
+		Email:       input.Email,
+		TokenHash:   helper.HashToken(token),
+		InvitedByID: adminID,
+		ExpiresAt:   time.Now().Add(time.Duration(ttl) * time.Hour),
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		registered, err := emailRegistered(tx, input.Email)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return ErrEmailAlreadyRegistered
+		}
+		if err := tx.Model(&models.AdminInvitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", input.Email).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if errors.Is(err, ErrEmailAlreadyRegistered) {
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create invitation", http.StatusInternalServerError, "error", nil))
+	}
+
+	response := toAdminInvitationResponse(invitation)
+	response.Token = token
+	return c.JSON(http.StatusCreated, helper.APIResponse("Invitation created successfully", http.StatusCreated, "success", response))
+}
+
+// Fungsi untuk menampilkan undangan admin terbaru (admin)
+func GetAdminInvitations(c echo.Context) error {
+	var invitations []models.AdminInvitation
+	if err := config.DB.Order("created_at DESC, id DESC").Limit(100).Find(&invitations).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve invitations", http.StatusInternalServerError, "error", nil))
+	}
+
+	responses := []AdminInvitationResponse{}
+	for _, invitation := range invitations {
+		responses = append(responses, toAdminInvitationResponse(invitation))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Invitations retrieved successfully", http.StatusOK, "success", responses))
+}
+
+// Fungsi untuk membatalkan undangan admin yang belum dipakai (admin)
+func RevokeAdminInvitation(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid invitation ID", http.StatusBadRequest, "error", nil))
+	}
+
+	result := config.DB.Model(&models.AdminInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to revoke invitation", http.StatusInternalServerError, "error", nil))
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Pending invitation not found", http.StatusNotFound, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Invitation revoked successfully", http.StatusOK, "success", nil))
+}
+
+// Struct untuk validasi input pendaftaran admin lewat undangan
+type AcceptAdminInvitationInput struct {
+	Token string `json:"token" validate:"required"`
+	RegisterInput
+}
+
+// Fungsi untuk menyelesaikan pendaftaran admin dengan token undangan (publik).
+// Email harus sama dengan email undangan dan token hanya bisa dipakai sekali.
+func AcceptAdminInvitation(c echo.Context) error {
+	var input AcceptAdminInvitationInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid request", http.StatusBadRequest, "error", nil))
+	}
+	input.Email = normalizeEmail(input.Email)
+	if err := validator.New().Struct(input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", err.Error()))
+	}
+
+	user, err := newUserFromInput(input.RegisterInput, models.RoleAdmin)
+	if errors.Is(err, errInvalidBirthDate) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid birth date format", http.StatusBadRequest, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to hash password", http.StatusInternalServerError, "error", nil))
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var invitation models.AdminInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", helper.HashToken(input.Token)).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidInvitation
+			}
+			return err
+		}
+		if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || time.Now().After(invitation.ExpiresAt) {
+			return ErrInvalidInvitation
+		}
+		if invitation.Email != input.Email {
+			return ErrInvitationEmail
+		}
+
+		registered, err := emailRegistered(tx, input.Email)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return ErrEmailAlreadyRegistered
+		}
+		// Undangan terikat ke email ini sehingga email dianggap sudah terverifikasi
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&invitation).Updates(map[string]interface{}{
+			"accepted_at":      &now,
+			"accepted_user_id": user.ID,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, ErrInvalidInvitation):
+		return c.JSON(http.StatusGone, helper.APIResponse(err.Error(), http.StatusGone, "error", nil))
+	case errors.Is(err, ErrInvitationEmail):
+		return c.JSON(http.StatusForbidden, helper.APIResponse(err.Error(), http.StatusForbidden, "error", nil))
+	case errors.Is(err, ErrEmailAlreadyRegistered):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to register", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Registration successful", http.StatusOK, "success", toRegisterResponse(user)))
+}
+
+// CreateAdminUser membuat admin langsung tanpa undangan, dipakai oleh subcommand "admin create"
+// untuk membuat admin pertama. Tanggal lahir, nomor telepon dan foto boleh dikosongkan.
+func CreateAdminUser(input RegisterInput) (models.User, error) {
+	input.Email = normalizeEmail(input.Email)
+	validate := validator.New()
+	if err := validate.Var(input.Email, "required,email"); err != nil {
+		return models.User{}, errors.New("a valid email is required")
+	}
+	if err := validate.Var(input.NamaLengkap, "required"); err != nil {
+		return models.User{}, errors.New("a name is required")
+	}
+	if err := validate.Var(input.Password, "min=6"); err != nil {
+		return models.User{}, errors.New("password must be at least 6 characters")
+	}
+	if input.TanggalLahir == "" {
+		input.TanggalLahir = "0001-01-01"
+	}
+	user, err := newUserFromInput(input, models.RoleAdmin)
+	if err != nil {
+		return user, err
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		registered, err := emailRegistered(tx, input.Email)
+		if err != nil {
+			return err
+		}
+		if registered {
+			return ErrEmailAlreadyRegistered
+		}
+		return tx.Create(&user).Error
+	})
+	return user, err
+}
@@ -4,6 +4,7 @@ import (
 	"Backend-Recything/config"
 	"Backend-Recything/helper"
 	"Backend-Recything/models"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Struct untuk response login
@@ -27,12 +29,19 @@ type LoginResponseData struct {
 	Token        string `json:"token"`
 	Role         string `json:"role"`
 	Photo        string `json:"photo"`
+
+	TokenExpiresAt   time.Time `json:"token_expires_at"`
+	RefreshToken     string    `json:"refresh_token"` // Ditukar di /token/refresh saat access token kedaluwarsa
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+
+	EmailVerified bool `json:"email_verified"` // Laporan dan penukaran hadiah butuh email terverifikasi
 }
 
 // Struct untuk validasi input login
 type LoginInput struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	DeviceName string `json:"device_name" validate:"max=100"` // Opsional, ditampilkan di daftar sesi
 }
 
 type UserResponse struct {
@@ -52,18 +61,18 @@ type RegisterInput struct {
 	Password     string `json:"password" validate:"required,min=6"`
 	TanggalLahir string `json:"tanggal_lahir" validate:"required"`
 	NoTelepon    string `json:"no_telepon" validate:"required"`
-	Role         string `json:"role" validate:"oneof=admin user"` // Validasi untuk admin/user
 	Photo        string `json:"photo" validate:"required,url"`
 }
 
 type RegisterResponse struct {
-	IDUser       uint   `json:"id_user"`
-	NamaLengkap  string `json:"nama_lengkap"`
-	TanggalLahir string `json:"tanggal_lahir"`
-	NoTelepon    string `json:"no_telepon"`
-	Email        string `json:"email"`
-	Role         string `json:"role"`
-	Photo        string `json:"photo"`
+	IDUser        uint   `json:"id_user"`
+	NamaLengkap   string `json:"nama_lengkap"`
+	TanggalLahir  string `json:"tanggal_lahir"`
+	NoTelepon     string `json:"no_telepon"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	Photo         string `json:"photo"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type UpdateUserDataInput struct {
@@ -77,9 +86,10 @@ type UpdateUserDataInput struct {
 
 // Struct untuk JWT Claims
 type jwtCustomClaims struct {
-	Name   string `json:"name"`
-	UserID uint   `json:"userID"`
-	Role   string `json:"role"`
+	Name      string `json:"name"`
+	UserID    uint   `json:"userID"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"` // Family refresh token asal access token
 	jwt.RegisteredClaims
 }
 
@@ -112,8 +122,16 @@ func LoginHandler(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, response)
 	}
 
-	// Generate token JWT
-	token, err := GenerateJWT(user.ID, user.NamaLengkap, user.Role)
+	// Catat sesi perangkat lalu generate access token JWT dan refresh token
+	var tokens TokenPair
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		session, err := startSession(tx, c, user.ID, input.DeviceName)
+		if err != nil {
+			return err
+		}
+		tokens, err = issueTokenPair(tx, user, session.FamilyID)
+		return err
+	})
 	if err != nil {
 		response := helper.APIResponse("Failed to generate token", http.StatusInternalServerError, "error", nil)
 		return c.JSON(http.StatusInternalServerError, response)
@@ -126,9 +144,15 @@ func LoginHandler(c echo.Context) error {
 		Email:        user.Email,
 		NoTelepon:    user.NoTelepon,
 		TanggalLahir: user.TanggalLahir.Format("2006-01-02"),
-		Token:        token,
+		Token:        tokens.Token,
 		Role:         user.Role,
 		Photo:        user.Photo, // Tambahkan photo ke respons
+
+		TokenExpiresAt:   tokens.TokenExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+
+		EmailVerified: user.EmailVerifiedAt != nil,
 	}
 
 	response := helper.APIResponse("Login successful", http.StatusOK, "success", data)
@@ -143,11 +167,7 @@ func RegisterHandler(c echo.Context) error {
 		response := helper.APIResponse("Invalid request", http.StatusBadRequest, "error", nil)
 		return c.JSON(http.StatusBadRequest, response)
 	}
-
-	// Jika role kosong, isi default menjadi "user"
-	if input.Role == "" {
-		input.Role = "user"
-	}
+	input.Email = normalizeEmail(input.Email) // Sama seperti alur undangan, agar email tidak terdaftar ganda karena beda huruf
 
 	// Validasi input
 	validate := validator.New()
@@ -156,56 +176,71 @@ func RegisterHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, response)
 	}
 
-	// Hash password
-	hash, err := HashPassword(input.Password)
+	// Registrasi publik selalu membuat user biasa; admin hanya lewat undangan atau CLI
+	user, err := newUserFromInput(input, models.RoleUser)
+	if errors.Is(err, errInvalidBirthDate) {
+		response := helper.APIResponse("Invalid birth date format", http.StatusBadRequest, "error", nil)
+		return c.JSON(http.StatusBadRequest, response)
+	}
 	if err != nil {
 		response := helper.APIResponse("Failed to hash password", http.StatusInternalServerError, "error", nil)
 		return c.JSON(http.StatusInternalServerError, response)
 	}
 
+	// Simpan ke database
+	result := config.DB.Create(&user)
+	if result.Error != nil {
+		response := helper.APIResponse("Failed to register", http.StatusInternalServerError, "error", nil)
+		return c.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Akun baru belum terverifikasi sampai link atau kode dari email dipakai
+	if err := startEmailVerification(user.ID); err != nil {
+		log.Printf("Failed to start email verification for user %d: %v", user.ID, err)
+	}
+
+	response := helper.APIResponse("Registration successful, check your email to verify your account", http.StatusOK, "success", toRegisterResponse(user))
+	return c.JSON(http.StatusOK, response)
+}
+
+var errInvalidBirthDate = errors.New("invalid birth date format")
+
+// newUserFromInput menyiapkan user baru dari input registrasi dengan role yang ditentukan server
+func newUserFromInput(input RegisterInput, role string) (models.User, error) {
 	// Parse tanggal lahir
 	tanggalLahir, err := time.Parse("2006-01-02", input.TanggalLahir)
 	if err != nil {
-		response := helper.APIResponse("Invalid birth date format", http.StatusBadRequest, "error", nil)
-		return c.JSON(http.StatusBadRequest, response)
+		return models.User{}, errInvalidBirthDate
 	}
 
-	// Set TanggalLahir to nil if it's zero value
-	if tanggalLahir.IsZero() {
-		tanggalLahir = time.Time{} // Set to zero value (January 1, 0001)
+	// Hash password
+	hash, err := HashPassword(input.Password)
+	if err != nil {
+		return models.User{}, err
 	}
 
-	// Membuat user baru
-	user := models.User{
+	return models.User{
 		NamaLengkap:  input.NamaLengkap,
 		Email:        input.Email,
 		NoTelepon:    input.NoTelepon,
 		Password:     hash,
-		TanggalLahir: tanggalLahir, // Assign the parsed date
-		Role:         input.Role,
+		TanggalLahir: tanggalLahir,
+		Role:         role,
 		Photo:        input.Photo, // Simpan URL foto langsung
-	}
+	}, nil
+}
 
-	// Simpan ke database
-	result := config.DB.Create(&user)
-	if result.Error != nil {
-		response := helper.APIResponse("Failed to register", http.StatusInternalServerError, "error", nil)
-		return c.JSON(http.StatusInternalServerError, response)
+func toRegisterResponse(user models.User) RegisterResponse {
+	return RegisterResponse{
+		IDUser:        user.ID,
+		NamaLengkap:   user.NamaLengkap,
+		TanggalLahir:  user.TanggalLahir.Format("2006-01-02"),
+		NoTelepon:     user.NoTelepon,
+		Email:         user.Email,
+		Role:          user.Role,
+		Photo:         user.Photo,
+		EmailVerified: user.EmailVerifiedAt != nil,
 	}
-
-	// Format respons
-	responseData := RegisterResponse{
-		IDUser:       user.ID,
-		NamaLengkap:  user.NamaLengkap,
-		TanggalLahir: user.TanggalLahir.Format("2006-01-02"),
-		NoTelepon:    user.NoTelepon,
-		Email:        user.Email,
-		Role:         user.Role,
-		Photo:        user.Photo,
-	}
-
-	response := helper.APIResponse("Registration successful", http.StatusOK, "success", responseData)
-	return c.JSON(http.StatusOK, response)
 }
 
 // GetAllUsers mengembalikan daftar semua pengguna
@@ -298,7 +333,7 @@ func GetUserByID(c echo.Context) error {
 	var reports []models.ReportRubbish
 
 	// Ambil semua laporan untuk user tanpa pagination
-	config.DB.Where("user_id = ?", id).Find(&reports)
+	config.DB.Preload("Photos", orderedReportPhotos).Where("user_id = ?", id).Find(&reports)
 
 	// Format laporan ke dalam respons
 	var reportsResponse []ReportResponse
@@ -312,22 +347,40 @@ func GetUserByID(c echo.Context) error {
 			Location:       report.Location,
 			Description:    report.Description,
 			Photo:          report.Photo,
+			Photos:         toReportPhotoResponses(report.Photos),
 			Status:         report.Status, // This field is now included in the response
 			Longitude:      report.Longitude,
 			Latitude:       report.Latitude,
 		})
 	}
 
+	// Ambil badge yang sudah dan sedang dikejar user
+	badges, err := loadUserBadges(user.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to retrieve user badges", http.StatusInternalServerError, "error", nil)
+		return c.JSON(http.StatusInternalServerError, response)
+	}
+
+	// Perangkat tempat user sedang login, agar admin bisa mengakhiri sesi akun yang bermasalah
+	sessions, err := loadUserSessions(user.ID, "")
+	if err != nil {
+		response := helper.APIResponse("Failed to retrieve user sessions", http.StatusInternalServerError, "error", nil)
+		return c.JSON(http.StatusInternalServerError, response)
+	}
+
 	// Format data untuk respons
 	userResponse := struct {
-		IDUser       uint             `json:"id_user"`
-		NamaLengkap  string           `json:"nama_lengkap"`
-		TanggalLahir string           `json:"tanggal_lahir"`
-		NoTelepon    string           `json:"no_telepon"`
-		Email        string           `json:"email"`
-		Role         string           `json:"role"`
-		Photo        string           `json:"photo"`
-		Reports      []ReportResponse `json:"reports"` // Include reports here
+		IDUser       uint                  `json:"id_user"`
+		NamaLengkap  string                `json:"nama_lengkap"`
+		TanggalLahir string                `json:"tanggal_lahir"`
+		NoTelepon    string                `json:"no_telepon"`
+		Email        string                `json:"email"`
+		Role         string                `json:"role"`
+		Photo        string                `json:"photo"`
+		Points       uint                  `json:"points"`
+		Reports      []ReportResponse      `json:"reports"` // Include reports here
+		Badges       UserBadges            `json:"badges"`
+		Sessions     []UserSessionResponse `json:"sessions"`
 	}{
 		IDUser:       user.ID,
 		NamaLengkap:  user.NamaLengkap,
@@ -336,7 +389,10 @@ func GetUserByID(c echo.Context) error {
 		Email:        user.Email,
 		Role:         user.Role,
 		Photo:        user.Photo,
+		Points:       user.Points,
 		Reports:      reportsResponse,
+		Badges:       badges,
+		Sessions:     sessions,
 	}
 
 	// Response berhasil
@@ -344,19 +400,29 @@ func GetUserByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
-// GenerateJWT membuat token JWT
-func GenerateJWT(userID uint, name string, role string) (string, error) {
+// GenerateJWT membuat access token JWT berumur pendek dengan jti unik agar bisa dicabut
+func GenerateJWT(userID uint, name string, role string, sessionID string) (string, time.Time, error) {
+	jti, err := helper.RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL())
 	claims := &jwtCustomClaims{
-		Name:   name,
-		UserID: userID,
-		Role:   role,
+		Name:      name,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 72)),
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+	signed, err := token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
+	return signed, expiresAt, err
 }
 
 // HashPassword mengenkripsi password
@@ -422,7 +488,7 @@ func UpdateUserPhoto(c echo.Context) error {
 	}
 
 	user.Photo = uploadResult.SecureURL
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := config.DB.Model(&user).Update("photo", user.Photo).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"message": "Failed to save user photo",
 		})
@@ -434,15 +500,24 @@ func UpdateUserPhoto(c echo.Context) error {
 	})
 }
 
+// Logout mencabut family refresh token milik login ini dan menolak access token yang sedang dipakai
 func Logout(c echo.Context) error {
-	cookie := &http.Cookie{
-		Name:     "token",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-	}
-	c.SetCookie(cookie)
+	userID, _ := c.Get("userID").(uint)
+	jti, _ := c.Get("tokenID").(string)
+	sessionID, _ := c.Get("sessionID").(string)
+	expiresAt, _ := c.Get("tokenExpiresAt").(time.Time)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if sessionID != "" {
+			if err := endSession(tx, sessionID); err != nil {
+				return err
+			}
+		}
+		return revokeAccessToken(tx, jti, userID, expiresAt)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to logout", http.StatusInternalServerError, "error", nil))
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "Berhasil Logout",
 	})
@@ -493,6 +568,13 @@ func UpdateUserData(c echo.Context) error {
 		user.Password = hashedNewPassword
 	}
 
+	// Hanya kolom profil yang ditulis agar poin, role dan status verifikasi yang berubah
+	// bersamaan (misalnya posting ledger poin) tidak tertimpa nilai lama
+	columns := []string{"nama_lengkap", "tanggal_lahir", "no_telepon", "updated_at"}
+	if input.OldPassword != "" && input.NewPassword != "" {
+		columns = append(columns, "password")
+	}
+
 	// Periksa setiap field input apakah diisi, jika iya, baru update
 	if input.NamaLengkap != "" {
 		user.NamaLengkap = input.NamaLengkap
@@ -511,15 +593,25 @@ func UpdateUserData(c echo.Context) error {
 		user.NoTelepon = input.NoTelepon
 	}
 
-	if input.Email != "" {
+	// Email baru harus diverifikasi ulang
+	emailChanged := false
+	if input.Email != "" && input.Email != user.Email {
 		user.Email = input.Email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
+		columns = append(columns, "email", "email_verified_at")
 	}
 
 	// Simpan perubahan ke database
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err := config.DB.Model(&user).Select(columns).Updates(&user).Error; err != nil {
 		response := helper.APIResponse("Failed to update user data", http.StatusInternalServerError, "error", nil)
 		return c.JSON(http.StatusInternalServerError, response)
 	}
+	if emailChanged {
+		if err := startEmailVerification(user.ID); err != nil {
+			log.Printf("Failed to start email verification for user %d: %v", user.ID, err)
+		}
+	}
 
 	// Format response data
 	userResponse := UserResponse{
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/models"
+	"errors"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Error untuk penugasan tim kebersihan
+var (
+	ErrNoCrewAvailable       = errors.New("no active crew is available for this report")
+	ErrReportNotAssignable   = errors.New("only approved reports can be assigned to a crew")
+	ErrInvalidAssignmentStep = errors.New("task cannot move to this step from its current status")
+	ErrNotCrewMember         = errors.New("you are not a member of the crew assigned to this task")
+)
+
+// Struct untuk satu langkah penugasan
+type ReportAssignmentEventResponse struct {
+	Action    string `json:"action"`
+	ActorID   *uint  `json:"actor_id"`
+	ActorName string `json:"actor_name"`
+	Note      string `json:"note"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Struct untuk respons penugasan laporan
+type ReportAssignmentResponse struct {
+	ID           uint                            `json:"id"`
+	ReportID     uint                            `json:"report_id"`
+	CrewID       uint                            `json:"crew_id"`
+	CrewName     string                          `json:"crew_name"`
+	Method       string                          `json:"method"`
+	Status       string                          `json:"status"`
+	AssignedByID *uint                           `json:"assigned_by_id"`
+	AcceptedByID *uint                           `json:"accepted_by_id"`
+	CreatedAt    string                          `json:"created_at"`
+	Events       []ReportAssignmentEventResponse `json:"events,omitempty"`
+}
+
+func toReportAssignmentResponse(assignment models.ReportAssignment) ReportAssignmentResponse {
+	return ReportAssignmentResponse{
+		ID:           assignment.ID,
+		ReportID:     assignment.ReportID,
+		CrewID:       assignment.CrewID,
+		CrewName:     assignment.Crew.Name,
+		Method:       assignment.Method,
+		Status:       assignment.Status,
+		AssignedByID: assignment.AssignedByID,
+		AcceptedByID: assignment.AcceptedByID,
+		CreatedAt:    assignment.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// loadReportAssignments mengambil semua penugasan sebuah laporan beserta langkah-langkahnya, yang terbaru lebih dulu
+func loadReportAssignments(reportID uint) ([]ReportAssignmentResponse, error) {
+	var assignments []models.ReportAssignment
+	if err := config.DB.Preload("Crew").Where("report_id = ?", reportID).
+		Order("created_at DESC, id DESC").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	var events []models.ReportAssignmentEvent
+	if err := config.DB.Preload("Actor").Where("report_id = ?", reportID).
+		Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	eventsByAssignment := make(map[uint][]ReportAssignmentEventResponse)
+	for _, event := range events {
+		response := ReportAssignmentEventResponse{
+			Action:    event.Action,
+			ActorID:   event.ActorID,
+			Note:      event.Note,
+			CreatedAt: event.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if event.Actor != nil {
+			response.ActorName = event.Actor.NamaLengkap
+		}
+		eventsByAssignment[event.AssignmentID] = append(eventsByAssignment[event.AssignmentID], response)
+	}
+
+	responses := []ReportAssignmentResponse{}
+	for _, assignment := range assignments {
+		response := toReportAssignmentResponse(assignment)
+		response.Events = eventsByAssignment[assignment.ID]
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// recordAssignmentEvent mencatat satu langkah penugasan
+func recordAssignmentEvent(tx *gorm.DB, assignment models.ReportAssignment, action string, actorID *uint, note string) error {
+	return tx.Create(&models.ReportAssignmentEvent{
+		AssignmentID: assignment.ID,
+		ReportID:     assignment.ReportID,
+		Action:       action,
+		ActorID:      actorID,
+		Note:         note,
+	}).Error
+}
+
+// autoAssignEnabled menentukan apakah laporan yang disetujui langsung ditugaskan ke tim (AUTO_ASSIGN_CREWS=true)
+func autoAssignEnabled() bool {
+	return os.Getenv("AUTO_ASSIGN_CREWS") == "true"
+}
+
+// pickCrewForReport memilih tim aktif untuk laporan: tim yang wilayahnya memuat laporan lebih diutamakan,
+// lalu tim tanpa wilayah. Di antara kandidat, tim dengan beban kerja aktif paling sedikit yang dipilih.
+func pickCrewForReport(tx *gorm.DB, report models.ReportRubbish) (*models.Crew, error) {
+	staffed := tx.Model(&models.CrewMember{}).Select("crew_id")
+	candidates := func(db *gorm.DB) ([]models.Crew, error) {
+		var crews []models.Crew
+		err := db.Where("active = ? AND id IN (?)", true, staffed).Order("id ASC").Find(&crews).Error
+		return crews, err
+	}
+
+	var crews []models.Crew
+	if report.RegionID != nil {
+		regionIDs, err := regionAncestorIDs(tx, *report.RegionID)
+		if err != nil {
+			return nil, err
+		}
+		if crews, err = candidates(tx.Where("region_id IN ?", regionIDs)); err != nil {
+			return nil, err
+		}
+	}
+	if len(crews) == 0 {
+		var err error
+		if crews, err = candidates(tx.Where("region_id IS NULL")); err != nil {
+			return nil, err
+		}
+	}
+	if len(crews) == 0 {
+		return nil, ErrNoCrewAvailable
+	}
+
+	workloads, err := crewWorkloads(tx)
+	if err != nil {
+		return nil, err
+	}
+	best := &crews[0]
+	for i := range crews {
+		if workloads[crews[i].ID] < workloads[best.ID] {
+			best = &crews[i]
+		}
+	}
+	return best, nil
+}
+
+// crewWorkloads menghitung jumlah penugasan aktif per tim
+func crewWorkloads(tx *gorm.DB) (map[uint]int64, error) {
+	var rows []struct {
+		CrewID uint
+		Count  int64
+	}
+	if err := tx.Model(&models.ReportAssignment{}).
+		Select("crew_id, COUNT(*) AS count").
+		Where("status IN ?", models.ActiveAssignmentStatuses).
+		Group("crew_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	workloads := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		workloads[row.CrewID] = row.Count
+	}
+	return workloads, nil
+}
+
+// assignReportToCrew membuat penugasan baru dan membatalkan penugasan aktif sebelumnya
+func assignReportToCrew(tx *gorm.DB, report models.ReportRubbish, crew models.Crew, method string, actorID *uint) (models.ReportAssignment, error) {
+	if report.Status != models.ReportStatusApproved {
+		return models.ReportAssignment{}, ErrReportNotAssignable
+	}
+	if err := cancelActiveAssignments(tx, report.ID, actorID, "reassigned to "+crew.Name); err != nil {
+		return models.ReportAssignment{}, err
+	}
+
+	assignment := models.ReportAssignment{
+		ReportID:     report.ID,
+		CrewID:       crew.ID,
+		Method:       method,
+		Status:       models.AssignmentStatusAssigned,
+		AssignedByID: actorID,
+		Crew:         crew,
+	}
+	if err := tx.Omit("Crew", "Report").Create(&assignment).Error; err != nil {
+		return assignment, err
+	}
+	return assignment, recordAssignmentEvent(tx, assignment, models.AssignmentEventAssigned, actorID, method+" assignment to "+crew.Name)
+}
+
+// autoAssignReport menugaskan laporan yang baru disetujui jika AUTO_ASSIGN_CREWS aktif.
+// Laporan tetap disetujui walaupun belum ada tim yang tersedia.
+func autoAssignReport(tx *gorm.DB, report models.ReportRubbish) error {
+	if !autoAssignEnabled() {
+		return nil
+	}
+	crew, err := pickCrewForReport(tx, report)
+	if errors.Is(err, ErrNoCrewAvailable) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = assignReportToCrew(tx, report, *crew, models.AssignmentMethodAuto, nil)
+	return err
+}
+
+// cancelActiveAssignments membatalkan penugasan laporan yang belum selesai
+func cancelActiveAssignments(tx *gorm.DB, reportID uint, actorID *uint, note string) error {
+	var assignments []models.ReportAssignment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("report_id = ? AND status IN ?", reportID, models.ActiveAssignmentStatuses).
+		Find(&assignments).Error; err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		if err := tx.Model(&assignment).Update("status", models.AssignmentStatusCancelled).Error; err != nil {
+			return err
+		}
+		if err := recordAssignmentEvent(tx, assignment, models.AssignmentEventCancelled, actorID, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crewIDForUser mengembalikan tim tempat user terdaftar
+func crewIDForUser(tx *gorm.DB, userID uint) (uint, error) {
+	var member models.CrewMember
+	if err := tx.Where("user_id = ?", userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrNotCrewMember
+		}
+		return 0, err
+	}
+	return member.CrewID, nil
+}
+
+// memberAssignment mengambil penugasan laporan yang sedang dikerjakan tim milik user
+func memberAssignment(tx *gorm.DB, reportID uint, userID uint) (*models.ReportAssignment, error) {
+	var assignment models.ReportAssignment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("report_id = ? AND status = ? AND crew_id IN (?)", reportID, models.AssignmentStatusInProgress,
+			tx.Model(&models.CrewMember{}).Select("crew_id").Where("user_id = ?", userID)).
+		First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// advanceAssignment memindahkan penugasan ke langkah berikutnya dan mencatatnya
+func advanceAssignment(tx *gorm.DB, assignment *models.ReportAssignment, action string, actorID uint, note string) error {
+	now := time.Now()
+	switch action {
+	case models.AssignmentEventAccepted:
+		if assignment.Status != models.AssignmentStatusAssigned {
+			return ErrInvalidAssignmentStep
+		}
+		assignment.Status = models.AssignmentStatusAccepted
+		assignment.AcceptedByID = &actorID
+		assignment.AcceptedAt = &now
+	case models.AssignmentEventStarted:
+		if assignment.Status != models.AssignmentStatusAccepted {
+			return ErrInvalidAssignmentStep
+		}
+		assignment.Status = models.AssignmentStatusInProgress
+		assignment.StartedAt = &now
+	case models.AssignmentEventCompleted:
+		if assignment.Status != models.AssignmentStatusInProgress {
+			return ErrInvalidAssignmentStep
+		}
+		assignment.Status = models.AssignmentStatusCompleted
+		assignment.CompletedAt = &now
+	case models.AssignmentEventReopened:
+		if assignment.Status != models.AssignmentStatusCompleted {
+			return ErrInvalidAssignmentStep
+		}
+		assignment.Status = models.AssignmentStatusInProgress
+		assignment.CompletedAt = nil
+	default:
+		return ErrInvalidAssignmentStep
+	}
+
+	if err := tx.Omit("Crew", "Report").Save(assignment).Error; err != nil {
+		return err
+	}
+	return recordAssignmentEvent(tx, *assignment, action, &actorID, note)
+}
+
+// reopenCompletedAssignment mengembalikan penugasan ke in_progress jika bukti pembersihan tim ditolak
+func reopenCompletedAssignment(tx *gorm.DB, reportID uint, actorID uint, note string) error {
+	var assignment models.ReportAssignment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("report_id = ? AND status = ?", reportID, models.AssignmentStatusCompleted).
+		Order("id DESC").First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return advanceAssignment(tx, &assignment, models.AssignmentEventReopened, actorID, note)
+}
@@ -0,0 +1,493 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Struct untuk anggota tim
+type CrewMemberResponse struct {
+	UserID      uint   `json:"user_id"`
+	NamaLengkap string `json:"nama_lengkap"`
+	Email       string `json:"email"`
+}
+
+// Struct untuk respons tim kebersihan
+type CrewResponse struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	RegionID    *uint                `json:"region_id"`
+	Active      bool                 `json:"active"`
+	ActiveTasks int64                `json:"active_tasks"` // Jumlah penugasan yang belum selesai
+	Members     []CrewMemberResponse `json:"members"`
+}
+
+func toCrewResponse(crew models.Crew, workload int64) CrewResponse {
+	members := []CrewMemberResponse{}
+	for _, member := range crew.Members {
+		members = append(members, CrewMemberResponse{
+			UserID:      member.UserID,
+			NamaLengkap: member.User.NamaLengkap,
+			Email:       member.User.Email,
+		})
+	}
+	return CrewResponse{
+		ID:          crew.ID,
+		Name:        crew.Name,
+		RegionID:    crew.RegionID,
+		Active:      crew.Active,
+		ActiveTasks: workload,
+		Members:     members,
+	}
+}
+
+// Struct untuk satu tugas di antrean tim
+type CrewTaskResponse struct {
+	ReportAssignmentResponse
+	Report    ReportResponse `json:"report"`
+	DistanceM *float64       `json:"distance_m"` // Kosong jika laporan tidak punya koordinat
+}
+
+// Fungsi untuk membuat tim kebersihan baru (admin)
+func CreateCrew(c echo.Context) error {
+	input := struct {
+		Name     string `json:"name" validate:"required,max=100"`
+		RegionID *uint  `json:"region_id"` // Kosong berarti tim melayani semua wilayah
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	if input.RegionID != nil {
+		var region models.Region
+		if err := config.DB.Select("id").First(&region, *input.RegionID).Error; err != nil {
+			return regionFilterErrorResponse(c, err)
+		}
+	}
+
+	var existing int64
+	if err := config.DB.Model(&models.Crew{}).Where("name = ?", input.Name).Count(&existing).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create crew", http.StatusInternalServerError, "error", nil))
+	}
+	if existing > 0 {
+		return c.JSON(http.StatusConflict, helper.APIResponse("Crew name already exists", http.StatusConflict, "error", nil))
+	}
+
+	crew := models.Crew{Name: input.Name, RegionID: input.RegionID, Active: true}
+	if err := config.DB.Create(&crew).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create crew", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusCreated, helper.APIResponse("Crew created successfully", http.StatusCreated, "success", toCrewResponse(crew, 0)))
+}
+
+// Fungsi untuk menampilkan semua tim beserta anggota dan beban kerjanya (admin)
+func GetCrews(c echo.Context) error {
+	var crews []models.Crew
+	if err := config.DB.Preload("Members.User").Order("name ASC").Find(&crews).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve crews", http.StatusInternalServerError, "error", nil))
+	}
+	workloads, err := crewWorkloads(config.DB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve crews", http.StatusInternalServerError, "error", nil))
+	}
+
+	responses := []CrewResponse{}
+	for _, crew := range crews {
+		responses = append(responses, toCrewResponse(crew, workloads[crew.ID]))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Crews retrieved successfully", http.StatusOK, "success", responses))
+}
+
+// Fungsi untuk mengaktifkan atau menonaktifkan tim (admin). Tim nonaktif tidak dipilih untuk penugasan baru,
+// tugas yang sudah berjalan tetap bisa diselesaikan.
+func SetCrewActive(c echo.Context) error {
+	crewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || crewID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid crew ID", http.StatusBadRequest, "error", nil))
+	}
+	input := struct {
+		Active *bool `json:"active" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var crew models.Crew
+	if err := config.DB.First(&crew, crewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, helper.APIResponse("Crew not found", http.StatusNotFound, "error", nil))
+		}
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update crew", http.StatusInternalServerError, "error", nil))
+	}
+	if err := config.DB.Model(&crew).Update("active", *input.Active).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update crew", http.StatusInternalServerError, "error", nil))
+	}
+
+	config.DB.Preload("Members.User").First(&crew, crew.ID)
+	workloads, _ := crewWorkloads(config.DB)
+	message := "Crew activated successfully"
+	if !crew.Active {
+		message = "Crew deactivated successfully"
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse(message, http.StatusOK, "success", toCrewResponse(crew, workloads[crew.ID])))
+}
+
+var (
+	errStaffCannotJoinCrew = errors.New("only regular users can be added to a crew")
+	errAlreadyCrewMember   = errors.New("user is already a member of a crew")
+)
+
+// Fungsi untuk menambahkan user ke tim (admin). Role user berubah menjadi crew
+// dan semua sesi user dicabut agar token lama dengan role sebelumnya tidak berlaku lagi.
+func AddCrewMember(c echo.Context) error {
+	crewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || crewID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid crew ID", http.StatusBadRequest, "error", nil))
+	}
+	input := struct {
+		UserID uint `json:"user_id" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var crew models.Crew
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&crew, crewID).Error; err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, input.UserID).Error; err != nil {
+			return err
+		}
+		if user.Role != models.RoleUser && user.Role != models.RoleCrew {
+			return errStaffCannotJoinCrew
+		}
+
+		var member models.CrewMember
+		err := tx.Where("user_id = ?", user.ID).First(&member).Error
+		if err == nil {
+			return errAlreadyCrewMember
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Create(&models.CrewMember{CrewID: crew.ID, UserID: user.ID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("role", models.RoleCrew).Error; err != nil {
+			return err
+		}
+		_, err = revokeSessions(tx, user.ID, 0)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Crew or user not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, errStaffCannotJoinCrew):
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	case errors.Is(err, errAlreadyCrewMember):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to add crew member", http.StatusInternalServerError, "error", nil))
+	}
+
+	config.DB.Preload("Members.User").First(&crew, crew.ID)
+	workloads, _ := crewWorkloads(config.DB)
+	return c.JSON(http.StatusOK, helper.APIResponse("Crew member added successfully", http.StatusOK, "success", toCrewResponse(crew, workloads[crew.ID])))
+}
+
+// Fungsi untuk mengeluarkan user dari tim (admin). Role user kembali menjadi user dan sesinya dicabut.
+func RemoveCrewMember(c echo.Context) error {
+	crewID, errCrew := strconv.Atoi(c.Param("id"))
+	userID, errUser := strconv.Atoi(c.Param("user_id"))
+	if errCrew != nil || errUser != nil || crewID <= 0 || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid crew or user ID", http.StatusBadRequest, "error", nil))
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("crew_id = ? AND user_id = ?", crewID, userID).Delete(&models.CrewMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.User{}).Where("id = ? AND role = ?", userID, models.RoleCrew).Update("role", models.RoleUser).Error; err != nil {
+			return err
+		}
+		_, err := revokeSessions(tx, uint(userID), 0)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Crew member not found", http.StatusNotFound, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to remove crew member", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Crew member removed successfully", http.StatusOK, "success", nil))
+}
+
+// Fungsi untuk menugaskan laporan yang sudah disetujui ke tim (admin).
+// Jika crew_id tidak diisi, tim dipilih otomatis berdasarkan wilayah dan beban kerja.
+func AssignReport(c echo.Context) error {
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reportID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
+	}
+	input := struct {
+		CrewID *uint `json:"crew_id"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+
+	adminID, _ := c.Get("userID").(uint)
+	var assignment models.ReportAssignment
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var report models.ReportRubbish
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return err
+		}
+
+		var crew models.Crew
+		method := models.AssignmentMethodManual
+		if input.CrewID != nil {
+			if err := tx.Where("active = ?", true).First(&crew, *input.CrewID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrNoCrewAvailable
+				}
+				return err
+			}
+		} else {
+			picked, err := pickCrewForReport(tx, report)
+			if err != nil {
+				return err
+			}
+			crew = *picked
+			method = models.AssignmentMethodAuto
+		}
+
+		var err error
+		assignment, err = assignReportToCrew(tx, report, crew, method, &adminID)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrNoCrewAvailable):
+		return c.JSON(http.StatusUnprocessableEntity, helper.APIResponse(err.Error(), http.StatusUnprocessableEntity, "error", nil))
+	case errors.Is(err, ErrReportNotAssignable):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to assign report", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Report assigned successfully", http.StatusOK, "success", toReportAssignmentResponse(assignment)))
+}
+
+// Fungsi untuk menugaskan semua laporan approved yang belum punya tim secara otomatis (admin)
+func AutoAssignReports(c echo.Context) error {
+	adminID, _ := c.Get("userID").(uint)
+
+	var reportIDs []uint
+	if err := config.DB.Model(&models.ReportRubbish{}).
+		Where("status = ?", models.ReportStatusApproved).
+		Where("id NOT IN (?)", config.DB.Model(&models.ReportAssignment{}).Select("report_id").
+			Where("status IN ?", models.ActiveAssignmentStatuses)).
+		Order("tanggal_laporan ASC, id ASC").
+		Pluck("id", &reportIDs).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve unassigned reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Setiap laporan ditugaskan dalam transaksi sendiri agar beban kerja terbaru ikut dihitung
+	assigned := []ReportAssignmentResponse{}
+	unassigned := []uint{}
+	for _, reportID := range reportIDs {
+		var assignment models.ReportAssignment
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var report models.ReportRubbish
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+				return err
+			}
+			crew, err := pickCrewForReport(tx, report)
+			if err != nil {
+				return err
+			}
+			assignment, err = assignReportToCrew(tx, report, *crew, models.AssignmentMethodAuto, &adminID)
+			return err
+		})
+		if errors.Is(err, ErrNoCrewAvailable) || errors.Is(err, ErrReportNotAssignable) || errors.Is(err, gorm.ErrRecordNotFound) {
+			unassigned = append(unassigned, reportID)
+			continue
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to assign reports", http.StatusInternalServerError, "error", nil))
+		}
+		assigned = append(assigned, toReportAssignmentResponse(assignment))
+	}
+
+	responseData := map[string]interface{}{
+		"assigned":   assigned,
+		"unassigned": unassigned, // Laporan tanpa tim yang tersedia
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Reports assigned successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi untuk menampilkan antrean tugas tim milik user (crew), diurutkan dari lokasi terdekat.
+// Laporan tanpa koordinat ditaruh di akhir.
+func GetCrewTasks(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	lat, okLat := parseCoordinateParam(c, "lat", -90, 90)
+	lng, okLng := parseCoordinateParam(c, "lng", -180, 180)
+	if !okLat || !okLng {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Valid lat and lng are required", http.StatusBadRequest, "error", nil))
+	}
+
+	crewID, err := crewIDForUser(config.DB, userID)
+	if errors.Is(err, ErrNotCrewMember) {
+		return c.JSON(http.StatusForbidden, helper.APIResponse("You are not a member of any crew", http.StatusForbidden, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve crew tasks", http.StatusInternalServerError, "error", nil))
+	}
+
+	var assignments []models.ReportAssignment
+	if err := config.DB.Preload("Crew").Preload("Report.User").Preload("Report.Photos", orderedReportPhotos).
+		Where("crew_id = ? AND status IN ?", crewID, models.ActiveAssignmentStatuses).
+		Find(&assignments).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve crew tasks", http.StatusInternalServerError, "error", nil))
+	}
+
+	tasks := []CrewTaskResponse{}
+	for _, assignment := range assignments {
+		report := assignment.Report
+		task := CrewTaskResponse{
+			ReportAssignmentResponse: toReportAssignmentResponse(assignment),
+			Report:                   toReportResponse(report),
+		}
+		if report.Latitude != 0 || report.Longitude != 0 {
+			distance := math.Round(helper.HaversineMeters(lat, lng, report.Latitude, report.Longitude))
+			task.DistanceM = &distance
+		}
+		tasks = append(tasks, task)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].DistanceM, tasks[j].DistanceM
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Crew tasks retrieved successfully", http.StatusOK, "success", tasks))
+}
+
+// findCrewTask mengambil penugasan milik tim user dan menguncinya
+func findCrewTask(tx *gorm.DB, assignmentID int, userID uint) (models.ReportAssignment, error) {
+	var assignment models.ReportAssignment
+	crewID, err := crewIDForUser(tx, userID)
+	if err != nil {
+		return assignment, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Crew").First(&assignment, assignmentID).Error; err != nil {
+		return assignment, err
+	}
+	if assignment.CrewID != crewID {
+		return assignment, ErrNotCrewMember
+	}
+	return assignment, nil
+}
+
+// crewTaskErrorResponse mengubah error penugasan menjadi respons HTTP
+func crewTaskErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Task not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrNotCrewMember):
+		return c.JSON(http.StatusForbidden, helper.APIResponse(err.Error(), http.StatusForbidden, "error", nil))
+	case errors.Is(err, ErrInvalidAssignmentStep):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	}
+	return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update task", http.StatusInternalServerError, "error", nil))
+}
+
+// Fungsi untuk menerima tugas (crew)
+func AcceptCrewTask(c echo.Context) error {
+	return advanceCrewTask(c, models.AssignmentEventAccepted, "Task accepted")
+}
+
+// Fungsi untuk mulai mengerjakan tugas yang sudah diterima (crew)
+func StartCrewTask(c echo.Context) error {
+	return advanceCrewTask(c, models.AssignmentEventStarted, "Task started")
+}
+
+func advanceCrewTask(c echo.Context, action string, message string) error {
+	assignmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || assignmentID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid task ID", http.StatusBadRequest, "error", nil))
+	}
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	var assignment models.ReportAssignment
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if assignment, err = findCrewTask(tx, assignmentID, userID); err != nil {
+			return err
+		}
+		return advanceAssignment(tx, &assignment, action, userID, "")
+	})
+	if err != nil {
+		return crewTaskErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse(message, http.StatusOK, "success", toReportAssignmentResponse(assignment)))
+}
+
+// Fungsi untuk menyelesaikan tugas (crew) dengan mengirim foto "after" dan koordinatnya.
+// Form sama dengan pengiriman bukti pembersihan; laporan pindah ke cleaned_up setelah diverifikasi admin.
+func CompleteCrewTask(c echo.Context) error {
+	assignmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || assignmentID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid task ID", http.StatusBadRequest, "error", nil))
+	}
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	assignment, err := findCrewTask(config.DB, assignmentID, userID)
+	if err != nil {
+		return crewTaskErrorResponse(c, err)
+	}
+	if assignment.Status != models.AssignmentStatusInProgress {
+		return crewTaskErrorResponse(c, ErrInvalidAssignmentStep)
+	}
+	return submitReportCleanup(c, assignment.ReportID)
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Batas bawaan verifikasi email, bisa diubah lewat EMAIL_VERIFY_TTL_HOURS,
+// EMAIL_VERIFY_RESEND_SECONDS dan EMAIL_VERIFY_MAX_PER_DAY
+const (
+	defaultEmailVerifyTTLHours      = 24
+	defaultEmailVerifyResendSeconds = 60
+	defaultEmailVerifyMaxPerDay     = 5
+	maxEmailVerifyCodeAttempts      = 5                                           // Kode dibatalkan setelah sekian kali salah
+	defaultEmailVerifyURL           = "http://localhost:8000/api/v1/verify-email" // Server lokal, lihat e.Start di main.go
+)
+
+// Error untuk verifikasi email
+var (
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrInvalidVerification  = errors.New("verification link or code is invalid or expired")
+)
+
+// VerificationThrottleError dikembalikan jika email verifikasi diminta terlalu sering
+type VerificationThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *VerificationThrottleError) Error() string {
+	return fmt.Sprintf("too many verification emails, try again in %d seconds", e.retryAfterSeconds())
+}
+
+func (e *VerificationThrottleError) retryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+var (
+	mailer     helper.Mailer
+	mailerOnce sync.Once
+)
+
+// getMailer mengembalikan mailer aplikasi yang dipilih dari environment
+func getMailer() helper.Mailer {
+	mailerOnce.Do(func() {
+		mailer = helper.NewMailerFromEnv()
+	})
+	return mailer
+}
+
+// checkVerificationThrottle menolak pengiriman ulang yang terlalu cepat atau melebihi batas harian
+func checkVerificationThrottle(tx *gorm.DB, userID uint) error {
+	var recent []models.EmailVerification
+	since := time.Now().Add(-24 * time.Hour)
+	if err := tx.Select("id, created_at").Where("user_id = ? AND created_at > ?", userID, since).
+		Order("created_at DESC").Find(&recent).Error; err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+
+	interval := time.Duration(config.GetEnvInt("EMAIL_VERIFY_RESEND_SECONDS", defaultEmailVerifyResendSeconds)) * time.Second
+	if wait := time.Until(recent[0].CreatedAt.Add(interval)); wait > 0 {
+		return &VerificationThrottleError{RetryAfter: wait}
+	}
+	maxPerDay := config.GetEnvInt("EMAIL_VERIFY_MAX_PER_DAY", defaultEmailVerifyMaxPerDay)
+	if maxPerDay > 0 && len(recent) >= maxPerDay {
+		oldest := recent[maxPerDay-1].CreatedAt
+		return &VerificationThrottleError{RetryAfter: time.Until(oldest.Add(24 * time.Hour))}
+	}
+	return nil
+}
+
+// startEmailVerification membuat link dan kode verifikasi baru lalu mengirimkannya ke email user.
+// Verifikasi lama yang belum dipakai tidak berlaku lagi. Gagal kirim hanya dicatat di log
+// karena user masih bisa meminta kirim ulang.
+func startEmailVerification(userID uint) error {
+	var user models.User
+	var token, code string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Baris user dikunci agar permintaan bersamaan tidak melewati throttle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return ErrEmailAlreadyVerified
+		}
+		if err := checkVerificationThrottle(tx, user.ID); err != nil {
+			return err
+		}
+
+		var err error
+		if token, err = helper.RandomToken(32); err != nil {
+			return err
+		}
+		if code, err = helper.RandomDigits(6); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&models.EmailVerification{}).
+			Where("user_id = ? AND used_at IS NULL AND expires_at > ?", user.ID, now).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		ttl := config.GetEnvInt("EMAIL_VERIFY_TTL_HOURS", defaultEmailVerifyTTLHours)
+		if ttl < 1 {
+			ttl = defaultEmailVerifyTTLHours
+		}
+		return tx.Create(&models.EmailVerification{
+			UserID:    user.ID,
+			Email:     user.Email,
+			TokenHash: helper.HashToken(token),
+			CodeHash:  helper.HashToken(code),
+			ExpiresAt: now.Add(time.Duration(ttl) * time.Hour),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := getMailer().Send(verificationMail(user, token, code)); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func verificationMail(user models.User, token, code string) helper.Mail {
+	link := os.Getenv("EMAIL_VERIFY_URL")
+	if link == "" {
+		link = defaultEmailVerifyURL
+	}
+	link += "?token=" + url.QueryEscape(token)
+
+	return helper.Mail{
+		To:      user.Email,
+		Subject: "Verify your Recything email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to verify your email address:\n%s\n\nOr enter this code in the app: %s\n\n"+
+			"The link and code expire in %d hours. If you did not create a Recything account, ignore this email.\n",
+			user.NamaLengkap, link, code, config.GetEnvInt("EMAIL_VERIFY_TTL_HOURS", defaultEmailVerifyTTLHours)),
+	}
+}
+
+// completeEmailVerification menandai verifikasi terpakai dan email user terverifikasi,
+// selama email user belum berubah sejak verifikasi dikirim
+func completeEmailVerification(tx *gorm.DB, verification models.EmailVerification) error {
+	now := time.Now()
+	if err := tx.Model(&verification).Update("used_at", &now).Error; err != nil {
+		return err
+	}
+	result := tx.Model(&models.User{}).Where("id = ? AND email = ?", verification.UserID, verification.Email).
+		Update("email_verified_at", &now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidVerification
+	}
+	return nil
+}
+
+func verificationErrorResponse(c echo.Context, err error) error {
+	var throttle *VerificationThrottleError
+	switch {
+	case errors.As(err, &throttle):
+		c.Response().Header().Set("Retry-After", strconv.Itoa(throttle.retryAfterSeconds()))
+		return c.JSON(http.StatusTooManyRequests, helper.APIResponse(err.Error(), http.StatusTooManyRequests, "error", map[string]int{
+			"retry_after_seconds": throttle.retryAfterSeconds(),
+		}))
+	case errors.Is(err, ErrEmailAlreadyVerified):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case errors.Is(err, ErrInvalidVerification):
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
+	default:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to verify email", http.StatusInternalServerError, "error", nil))
+	}
+}
+
+// Fungsi untuk memverifikasi email lewat link yang dikirim ke user (publik)
+func VerifyEmailByToken(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Verification token is required", http.StatusBadRequest, "error", nil))
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var verification models.EmailVerification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", helper.HashToken(token)).
+			First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidVerification
+			}
+			return err
+		}
+		if verification.UsedAt != nil || time.Now().After(verification.ExpiresAt) {
+			return ErrInvalidVerification
+		}
+		return completeEmailVerification(tx, verification)
+	})
+	if err != nil {
+		return verificationErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Email verified successfully", http.StatusOK, "success", nil))
+}
+
+// Fungsi untuk memverifikasi email dengan kode 6 digit (user yang sedang login)
+func VerifyEmailCode(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	input := struct {
+		Code string `json:"code" validate:"required,len=6,numeric"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	wrongCode := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return ErrEmailAlreadyVerified
+		}
+
+		var verification models.EmailVerification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND email = ? AND used_at IS NULL AND expires_at > ?", user.ID, user.Email, time.Now()).
+			Order("created_at DESC, id DESC").
+			First(&verification).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidVerification
+			}
+			return err
+		}
+		if verification.Attempts >= maxEmailVerifyCodeAttempts {
+			return ErrInvalidVerification
+		}
+		if verification.CodeHash != helper.HashToken(input.Code) {
+			// Percobaan yang salah tetap di-commit agar kode tidak bisa ditebak terus-menerus
+			wrongCode = true
+			return tx.Model(&verification).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		return completeEmailVerification(tx, verification)
+	})
+	if err == nil && wrongCode {
+		err = ErrInvalidVerification
+	}
+	if err != nil {
+		return verificationErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Email verified successfully", http.StatusOK, "success", nil))
+}
+
+// Fungsi untuk mengirim ulang email verifikasi ke user yang sedang login
+func ResendMyVerificationEmail(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	if err := startEmailVerification(userID); err != nil {
+		return verificationErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Verification email sent", http.StatusOK, "success", nil))
+}
+
+// Fungsi untuk mengirim ulang email verifikasi berdasarkan alamat email (publik).
+// Responsnya selalu sama agar tidak bisa dipakai untuk mengecek email yang terdaftar.
+func ResendVerificationEmail(c echo.Context) error {
+	input := struct {
+		Email string `json:"email" validate:"required,email"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	input.Email = normalizeEmail(input.Email)
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var user models.User
+	err := config.DB.Select("id").Where("email = ? AND email_verified_at IS NULL", input.Email).First(&user).Error
+	if err == nil {
+		err = startEmailVerification(user.ID)
+	}
+	var throttle *VerificationThrottleError
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, ErrEmailAlreadyVerified) && !errors.As(err, &throttle) {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to send verification email", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("If the email is registered and not yet verified, a verification email has been sent", http.StatusOK, "success", nil))
+}
@@ -0,0 +1,308 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Data zona waktu tetap tersedia di image tanpa /usr/share/zoneinfo
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+var (
+	errInvalidExportFilter    = errors.New("invalid export filter")
+	errInvalidExportDateRange = fmt.Errorf("%w: use start_date/end_date in YYYY-MM-DD", errInvalidExportFilter)
+)
+
+// Struct untuk respons job ekspor
+type ExportJobResponse struct {
+	models.ExportJob
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func toExportJobResponse(job models.ExportJob) ExportJobResponse {
+	response := ExportJobResponse{ExportJob: job}
+	if job.Status == models.ExportJobCompleted {
+		response.DownloadURL = fmt.Sprintf("/api/v1/admin/exports/jobs/%d/download", job.ID)
+	}
+	return response
+}
+
+// parseExportOptions membaca format, kolom, bahasa judul dan zona waktu ekspor dari query
+func parseExportOptions(c echo.Context, export tableExport) (exportOptions, error) {
+	options := exportOptions{
+		Format: c.QueryParam("format"),
+		Lang:   c.QueryParam("lang"),
+	}
+	if options.Format == "" {
+		options.Format = "csv"
+	}
+	if options.Format != "csv" && options.Format != "xlsx" {
+		return options, fmt.Errorf("%w: format must be 'csv' or 'xlsx'", errInvalidExportFilter)
+	}
+	if options.Lang == "" {
+		options.Lang = "en"
+	}
+	if options.Lang != "en" && options.Lang != "id" {
+		return options, fmt.Errorf("%w: lang must be 'en' or 'id'", errInvalidExportFilter)
+	}
+
+	tz := c.QueryParam("tz")
+	if tz == "" {
+		tz = os.Getenv("EXPORT_TIMEZONE")
+	}
+	if tz == "" {
+		tz = "Asia/Jakarta"
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return options, fmt.Errorf("%w: unknown tz %q", errInvalidExportFilter, tz)
+	}
+	options.Location = location
+
+	available := export.columnKeys()
+	if param := c.QueryParam("columns"); param != "" {
+		for _, key := range strings.Split(param, ",") {
+			key = strings.TrimSpace(key)
+			if !slices.Contains(available, key) {
+				return options, fmt.Errorf("%w: unknown column %q, available: %s", errInvalidExportFilter, key, strings.Join(available, ","))
+			}
+			options.Columns = append(options.Columns, key)
+		}
+	} else {
+		options.Columns = available
+	}
+	return options, nil
+}
+
+// exportErrorResponse mengubah error saat menyiapkan ekspor menjadi respons HTTP
+func exportErrorResponse(c echo.Context, err error) error {
+	if errors.Is(err, errInvalidExportFilter) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+	return regionFilterErrorResponse(c, err)
+}
+
+func exportContentType(format string) string {
+	if format == "xlsx" {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Fungsi untuk mengekspor laporan, pengguna atau poin sebagai CSV/XLSX.
+// Ekspor kecil langsung di-stream ke respons; ekspor di atas EXPORT_ASYNC_ROWS baris
+// (atau jika async=true) dijalankan sebagai job di background dan hasilnya diunduh nanti.
+func ExportTable(c echo.Context) error {
+	resource := c.Param("resource")
+	export, ok := tableExports[resource]
+	if !ok {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Unknown export resource", http.StatusNotFound, "error", nil))
+	}
+	options, err := parseExportOptions(c, export)
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	countQuery, err := export.query(c, options.Location)
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+	var total int64
+	if err := config.DB.Table("(?) AS export_rows", countQuery).Count(&total).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to count export rows", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Query dibuat ulang karena statement GORM yang sudah dipakai untuk Count tidak bisa dipakai lagi
+	query, err := export.query(c, options.Location)
+	if err != nil {
+		return exportErrorResponse(c, err)
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", resource, time.Now().In(options.Location).Format("20060102-150405"), options.Format)
+	if c.QueryParam("async") == "true" || total > int64(config.GetEnvInt("EXPORT_ASYNC_ROWS", 5000)) {
+		userID, _ := c.Get("userID").(uint)
+		job := models.ExportJob{
+			Resource:    resource,
+			Format:      options.Format,
+			Query:       c.QueryString(),
+			Status:      models.ExportJobPending,
+			FileName:    filename,
+			RequestedBy: userID,
+		}
+		if err := config.DB.Create(&job).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create export job", http.StatusInternalServerError, "error", nil))
+		}
+		go runExportJob(job, export, query, options)
+
+		return c.JSON(http.StatusAccepted, helper.APIResponse("Export job started", http.StatusAccepted, "success", toExportJobResponse(job)))
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to export data", http.StatusInternalServerError, "error", nil))
+	}
+	defer rows.Close()
+
+	// Setelah header dikirim, error hanya bisa dicatat di log
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	response.Header().Set(echo.HeaderContentType, exportContentType(options.Format))
+	response.WriteHeader(http.StatusOK)
+
+	table, err := helper.NewTableWriter(options.Format, response)
+	if err == nil {
+		var count int
+		count, err = export.write(rows, options, table, func() error {
+			response.Flush()
+			return nil
+		})
+		if err == nil {
+			err = table.Close()
+		}
+		if err != nil {
+			log.Printf("Export %s (%s) aborted after %d rows: %v", resource, options.Format, count, err)
+		}
+	} else {
+		log.Printf("Export %s (%s) failed: %v", resource, options.Format, err)
+	}
+	return nil
+}
+
+// runExportJob menulis hasil ekspor ke file di EXPORT_DIR dan memperbarui status job
+func runExportJob(job models.ExportJob, export tableExport, query *gorm.DB, options exportOptions) {
+	config.DB.Model(&job).Update("status", models.ExportJobRunning)
+
+	count, path, err := writeExportFile(job, export, query, options)
+	now := time.Now()
+	updates := map[string]interface{}{"completed_at": &now, "row_count": count}
+	if err != nil {
+		log.Printf("Export job %d failed: %v", job.ID, err)
+		if path != "" {
+			os.Remove(path)
+		}
+		updates["status"] = models.ExportJobFailed
+		updates["error"] = err.Error()
+	} else {
+		updates["status"] = models.ExportJobCompleted
+		updates["file_path"] = path
+	}
+	if err := config.DB.Model(&job).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update export job %d: %v", job.ID, err)
+	}
+}
+
+func writeExportFile(job models.ExportJob, export tableExport, query *gorm.DB, options exportOptions) (int, string, error) {
+	dir := os.Getenv("EXPORT_DIR")
+	if dir == "" {
+		dir = "exports"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("export-%d.%s", job.ID, job.Format))
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	rows, err := query.Rows()
+	if err != nil {
+		return 0, path, err
+	}
+	defer rows.Close()
+
+	table, err := helper.NewTableWriter(options.Format, file)
+	if err != nil {
+		return 0, path, err
+	}
+	count, err := export.write(rows, options, table, nil)
+	if err != nil {
+		return count, path, err
+	}
+	if err := table.Close(); err != nil {
+		return count, path, err
+	}
+	return count, path, file.Close()
+}
+
+// FailInterruptedExportJobs menandai job yang masih berjalan saat server berhenti sebagai gagal
+func FailInterruptedExportJobs() error {
+	return config.DB.Model(&models.ExportJob{}).
+		Where("status IN ?", []string{models.ExportJobPending, models.ExportJobRunning}).
+		Updates(map[string]interface{}{"status": models.ExportJobFailed, "error": "interrupted by server restart"}).Error
+}
+
+// Fungsi untuk menampilkan daftar job ekspor terbaru
+func GetExportJobs(c echo.Context) error {
+	var jobs []models.ExportJob
+	if err := config.DB.Order("created_at DESC, id DESC").Limit(50).Find(&jobs).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve export jobs", http.StatusInternalServerError, "error", nil))
+	}
+
+	responses := []ExportJobResponse{}
+	for _, job := range jobs {
+		responses = append(responses, toExportJobResponse(job))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Export jobs retrieved successfully", http.StatusOK, "success", responses))
+}
+
+var errInvalidExportJobID = errors.New("invalid export job ID")
+
+func findExportJob(c echo.Context) (models.ExportJob, error) {
+	var job models.ExportJob
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return job, errInvalidExportJobID
+	}
+	err = config.DB.First(&job, id).Error
+	return job, err
+}
+
+// exportJobErrorResponse mengubah error dari findExportJob menjadi respons HTTP
+func exportJobErrorResponse(c echo.Context, err error) error {
+	if errors.Is(err, errInvalidExportJobID) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid export job ID", http.StatusBadRequest, "error", nil))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Export job not found", http.StatusNotFound, "error", nil))
+	}
+	return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve export job", http.StatusInternalServerError, "error", nil))
+}
+
+// Fungsi untuk melihat status job ekspor
+func GetExportJob(c echo.Context) error {
+	job, err := findExportJob(c)
+	if err != nil {
+		return exportJobErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Export job retrieved successfully", http.StatusOK, "success", toExportJobResponse(job)))
+}
+
+// Fungsi untuk mengunduh hasil job ekspor yang sudah selesai
+func DownloadExportJob(c echo.Context) error {
+	job, err := findExportJob(c)
+	if err != nil {
+		return exportJobErrorResponse(c, err)
+	}
+	if job.Status != models.ExportJobCompleted {
+		return c.JSON(http.StatusConflict, helper.APIResponse("Export job is not completed", http.StatusConflict, "error", toExportJobResponse(job)))
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return c.JSON(http.StatusGone, helper.APIResponse("Export file is no longer available", http.StatusGone, "error", nil))
+	}
+	c.Response().Header().Set(echo.HeaderContentType, exportContentType(job.Format))
+	return c.Attachment(job.FilePath, job.FileName)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Lama hasil geocoding disimpan di cache, bisa diubah lewat GEOCODE_CACHE_TTL_HOURS
+const defaultGeocodeCacheTTLHours = 24 * 30
+
+var (
+	geocoder     helper.Geocoder
+	geocoderOnce sync.Once
+)
+
+// getGeocoder mengembalikan geocoder aplikasi (provider dari environment + cache database)
+func getGeocoder() helper.Geocoder {
+	geocoderOnce.Do(func() {
+		ttl := time.Duration(config.GetEnvInt("GEOCODE_CACHE_TTL_HOURS", defaultGeocodeCacheTTLHours)) * time.Hour
+		geocoder = helper.NewGeocoderFromEnv(dbGeocodeCache{ttl: ttl})
+	})
+	return geocoder
+}
+
+// dbGeocodeCache menyimpan cache geocoding di tabel geocode_caches
+type dbGeocodeCache struct {
+	ttl time.Duration
+}
+
+// cacheKey memendekkan key yang melebihi panjang kolom query_key
+func (d dbGeocodeCache) cacheKey(key string) string {
+	if len(key) <= 255 {
+		return key
+	}
+	sum := sha1.Sum([]byte(key))
+	return key[:4] + "sha1:" + hex.EncodeToString(sum[:])
+}
+
+func (d dbGeocodeCache) Get(key string) (helper.GeocodeResult, bool) {
+	var entry models.GeocodeCache
+	if err := config.DB.Where("query_key = ? AND updated_at >= ?", d.cacheKey(key), time.Now().Add(-d.ttl)).First(&entry).Error; err != nil {
+		return helper.GeocodeResult{}, false
+	}
+	return helper.GeocodeResult{
+		Latitude:    entry.Latitude,
+		Longitude:   entry.Longitude,
+		DisplayName: entry.DisplayName,
+		Street:      entry.Street,
+		Kelurahan:   entry.Kelurahan,
+		Kecamatan:   entry.Kecamatan,
+		City:        entry.City,
+		Province:    entry.Province,
+		Provider:    entry.Provider,
+	}, true
+}
+
+// Set menyimpan atau memperbarui hasil; kegagalan cache tidak menggagalkan geocoding
+func (d dbGeocodeCache) Set(key string, result helper.GeocodeResult) {
+	entry := models.GeocodeCache{
+		QueryKey:    d.cacheKey(key),
+		Latitude:    result.Latitude,
+		Longitude:   result.Longitude,
+		DisplayName: result.DisplayName,
+		Street:      result.Street,
+		Kelurahan:   result.Kelurahan,
+		Kecamatan:   result.Kecamatan,
+		City:        result.City,
+		Province:    result.Province,
+		Provider:    result.Provider,
+	}
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "query_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"latitude", "longitude", "display_name", "street", "kelurahan", "kecamatan", "city", "province", "provider", "updated_at"}),
+	}).Create(&entry).Error
+	if err != nil {
+		log.Printf("Failed to cache geocoding result for %q: %v", key, err)
+	}
+}
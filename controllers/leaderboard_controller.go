@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Struct untuk satu baris leaderboard
+type LeaderboardEntry struct {
+	Rank        int    `json:"rank"`
+	UserID      *uint  `json:"user_id"` // Kosong untuk user yang memilih anonim
+	NamaLengkap string `json:"nama_lengkap"`
+	Photo       string `json:"photo"`
+	Points      int    `json:"points"`
+	Anonymous   bool   `json:"anonymous"`
+}
+
+// Struct untuk posisi user yang sedang login
+type LeaderboardPosition struct {
+	Rank   int  `json:"rank"` // 0 jika belum memperoleh poin pada periode ini
+	Points int  `json:"points"`
+	InTop  bool `json:"in_top"`
+}
+
+// leaderboardStart mengembalikan awal periode leaderboard, atau nil untuk sepanjang waktu
+func leaderboardStart(period string, now time.Time) (*time.Time, bool) {
+	switch period {
+	case "week":
+		// Minggu dimulai hari Senin pukul 00:00
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		start := time.Date(now.Year(), now.Month(), now.Day()-daysSinceMonday, 0, 0, 0, 0, now.Location())
+		return &start, true
+	case "month":
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return &start, true
+	case "all", "":
+		return nil, true
+	default:
+		return nil, false
+	}
+}
+
+// leaderboardScores membangun query skor per user dari poin yang diperoleh (bukan saldo bersih)
+func leaderboardScores(start *time.Time, city string) *gorm.DB {
+	reasons := models.EarningPointReasons
+	if start == nil {
+		// Saldo awal hasil migrasi hanya dihitung untuk leaderboard sepanjang waktu
+		reasons = append(append([]string{}, reasons...), models.PointReasonOpeningBalance)
+	}
+
+	db := config.DB.Table("point_transactions AS pt").
+		Select("pt.user_id, SUM(pt.delta) AS points").
+		Where("pt.reason IN ?", reasons).
+		Group("pt.user_id").
+		Having("SUM(pt.delta) > 0")
+
+	if start != nil {
+		db = db.Where("pt.created_at >= ?", *start)
+	}
+
+	// Filter wilayah berdasarkan kota laporan yang menghasilkan poin
+	if city != "" {
+		db = db.Joins("JOIN report_rubbishes r ON r.id = pt.report_id").Where("r.city = ?", city)
+	}
+
+	return db
+}
+
+// Fungsi untuk menampilkan leaderboard kontributor teratas
+func GetLeaderboard(c echo.Context) error {
+	period := c.QueryParam("period")
+	start, ok := leaderboardStart(period, time.Now())
+	if !ok {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid period. Use 'week', 'month' or 'all'.", http.StatusBadRequest, "error", nil))
+	}
+	if period == "" {
+		period = "all"
+	}
+	city := c.QueryParam("city")
+
+	limit := 10
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	// Ambil N teratas, seri diurutkan berdasarkan ID user agar hasil stabil
+	var scores []struct {
+		UserID uint
+		Points int
+	}
+	if err := leaderboardScores(start, city).Order("points DESC, pt.user_id ASC").Limit(limit).Scan(&scores).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve leaderboard", http.StatusInternalServerError, "error", nil))
+	}
+
+	userIDs := make([]uint, 0, len(scores))
+	for _, score := range scores {
+		userIDs = append(userIDs, score.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := config.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve leaderboard", http.StatusInternalServerError, "error", nil))
+		}
+	}
+	usersByID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	callerID, _ := c.Get("userID").(uint)
+	var me LeaderboardPosition
+
+	// Peringkat kompetisi: nilai yang sama mendapat peringkat yang sama
+	entries := []LeaderboardEntry{}
+	for i, score := range scores {
+		rank := i + 1
+		if i > 0 && score.Points == scores[i-1].Points {
+			rank = entries[i-1].Rank
+		}
+
+		user := usersByID[score.UserID]
+		entry := LeaderboardEntry{Rank: rank, Points: score.Points}
+		if user.LeaderboardOptOut && user.ID != callerID {
+			entry.NamaLengkap = "Anonymous"
+			entry.Anonymous = true
+		} else {
+			userID := user.ID
+			entry.UserID = &userID
+			entry.NamaLengkap = user.NamaLengkap
+			entry.Photo = user.Photo
+		}
+		entries = append(entries, entry)
+
+		if score.UserID == callerID {
+			me = LeaderboardPosition{Rank: rank, Points: score.Points, InTop: true}
+		}
+	}
+
+	// Hitung posisi user yang login meskipun berada di luar N teratas
+	if callerID != 0 && !me.InTop {
+		var callerPoints int
+		if err := config.DB.Table("(?) AS s", leaderboardScores(start, city).Where("pt.user_id = ?", callerID)).
+			Select("COALESCE(MAX(s.points), 0)").Scan(&callerPoints).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve leaderboard", http.StatusInternalServerError, "error", nil))
+		}
+
+		me.Points = callerPoints
+		if callerPoints > 0 {
+			var ahead int64
+			if err := config.DB.Table("(?) AS s", leaderboardScores(start, city)).
+				Where("s.points > ?", callerPoints).Count(&ahead).Error; err != nil {
+				return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve leaderboard", http.StatusInternalServerError, "error", nil))
+			}
+			me.Rank = int(ahead) + 1
+		}
+	}
+
+	responseData := map[string]interface{}{
+		"period":  period,
+		"city":    city,
+		"entries": entries,
+		"me":      me,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Leaderboard retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi untuk mengatur apakah user tampil dengan nama atau anonim di leaderboard
+func UpdateLeaderboardVisibility(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	input := struct {
+		OptOut *bool `json:"opt_out" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Update("leaderboard_opt_out", *input.OptOut).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update leaderboard visibility", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := map[string]interface{}{
+		"user_id": userID,
+		"opt_out": *input.OptOut,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Leaderboard visibility updated successfully", http.StatusOK, "success", responseData))
+}
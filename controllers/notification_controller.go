@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Struct untuk respons notifikasi
+type NotificationResponse struct {
+	ID        uint   `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Read      bool   `json:"read"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Fungsi untuk mendapatkan notifikasi milik user yang sedang login
+func GetNotifications(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	db := config.DB.Where("user_id = ?", userID)
+	if c.QueryParam("unread") == "true" {
+		db = db.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := db.Order("created_at DESC").Limit(50).Find(&notifications).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve notifications", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := []NotificationResponse{}
+	for _, notification := range notifications {
+		responseData = append(responseData, NotificationResponse{
+			ID:        notification.ID,
+			Type:      notification.Type,
+			Title:     notification.Title,
+			Message:   notification.Message,
+			Read:      notification.ReadAt != nil,
+			CreatedAt: notification.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Notifications retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi untuk menandai notifikasi sudah dibaca
+func MarkNotificationRead(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid notification ID", http.StatusBadRequest, "error", nil))
+	}
+
+	result := config.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update notification", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Notification marked as read", http.StatusOK, "success", nil))
+}
@@ -5,10 +5,40 @@ import (
 	"Backend-Recything/helper"
 	"Backend-Recything/models"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
 
+// Struct ringkasan saldo poin user yang dihitung dari ledger
+type pointSummary struct {
+	TotalEarned int `json:"total_earned"`
+	TotalSpent  int `json:"total_spent"`
+}
+
+// Struct untuk respons riwayat poin
+type PointTransactionResponse struct {
+	ID           uint   `json:"id"`
+	Delta        int    `json:"delta"`
+	BalanceAfter uint   `json:"balance_after"`
+	Reason       string `json:"reason"`
+	ReportID     *uint  `json:"report_id"`
+	RedemptionID *uint  `json:"redemption_id"`
+	Note         string `json:"note"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Fungsi untuk menghitung total poin masuk dan keluar milik user dari ledger
+func getPointSummary(userID uint) (pointSummary, error) {
+	var summary pointSummary
+	err := config.DB.Model(&models.PointTransaction{}).
+		Select("COALESCE(SUM(CASE WHEN delta > 0 THEN delta ELSE 0 END), 0) AS total_earned, "+
+			"COALESCE(SUM(CASE WHEN delta < 0 THEN -delta ELSE 0 END), 0) AS total_spent").
+		Where("user_id = ?", userID).
+		Scan(&summary).Error
+	return summary, err
+}
+
 // Fungsi untuk mendapatkan poin dari pengguna berdasarkan userID
 func GetUserPoints(c echo.Context) error {
 	// Mendapatkan userID dari token JWT
@@ -17,30 +47,32 @@ func GetUserPoints(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, "Invalid user ID from token")
 	}
 
-	// Mengambil data poin dari tabel Points dan preload data User
-	var userPoints models.Points
-	if err := config.DB.Preload("User").Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
-		// Jika tidak ditemukan, kembalikan poin 0
-		if err.Error() == "record not found" {
-			userPoints.Points = 0
-		} else {
-			return c.JSON(http.StatusInternalServerError, "Failed to retrieve points")
-		}
+	// Saldo user dimaterialisasi di users.points dan selalu sinkron dengan ledger
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
+	}
+
+	summary, err := getPointSummary(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve points", http.StatusInternalServerError, "error", nil))
 	}
 
 	// Menyiapkan respons dengan menambahkan data pengguna (User)
 	response := struct {
-		ID          uint   `json:"id"`
 		UserID      uint   `json:"user_id"`
 		Points      uint   `json:"points"`
+		TotalEarned int    `json:"total_earned"`
+		TotalSpent  int    `json:"total_spent"`
 		NamaLengkap string `json:"nama_lengkap"`
 		Email       string `json:"email"`
 	}{
-		ID:          userPoints.ID,
-		UserID:      userPoints.UserID,
-		Points:      userPoints.Points,
-		NamaLengkap: userPoints.User.NamaLengkap, // Akses data User
-		Email:       userPoints.User.Email,       // Akses data User
+		UserID:      user.ID,
+		Points:      user.Points,
+		TotalEarned: summary.TotalEarned,
+		TotalSpent:  summary.TotalSpent,
+		NamaLengkap: user.NamaLengkap,
+		Email:       user.Email,
 	}
 
 	// Mengembalikan response sukses dengan data yang diinginkan
@@ -48,46 +80,133 @@ func GetUserPoints(c echo.Context) error {
 }
 
 func GetAllUserPoints(c echo.Context) error {
-	// Ambil semua data poin pengguna dari tabel Points dan preload data User
-	var userPoints []models.Points
-	if err := config.DB.Preload("User").Find(&userPoints).Error; err != nil {
+	// Ambil saldo poin semua pengguna beserta total masuk/keluar dari ledger, termasuk yang belum punya transaksi
+	var rows []struct {
+		UserID      uint
+		Points      uint
+		TotalEarned int
+		TotalSpent  int
+		NamaLengkap string
+		Email       string
+		NoTelepon   string
+	}
+	if err := config.DB.Table("users").
+		Select("users.id AS user_id, users.points, users.nama_lengkap, users.email, users.no_telepon, " +
+			"COALESCE(SUM(CASE WHEN pt.delta > 0 THEN pt.delta ELSE 0 END), 0) AS total_earned, " +
+			"COALESCE(SUM(CASE WHEN pt.delta < 0 THEN -pt.delta ELSE 0 END), 0) AS total_spent").
+		Joins("LEFT JOIN point_transactions pt ON pt.user_id = users.id").
+		Group("users.id, users.points, users.nama_lengkap, users.email, users.no_telepon").
+		Order("users.id").
+		Scan(&rows).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve user points", http.StatusInternalServerError, "error", nil))
 	}
 
 	// Jika tidak ada data poin ditemukan
-	if len(userPoints) == 0 {
+	if len(rows) == 0 {
 		return c.JSON(http.StatusNotFound, helper.APIResponse("No user points found", http.StatusNotFound, "error", nil))
 	}
 
 	// Menyiapkan respons dengan menambahkan data pengguna (User)
-	var responseData []struct {
-		ID          uint   `json:"id"`
+	type userPointsResponse struct {
 		UserID      uint   `json:"user_id"`
 		Points      uint   `json:"points"`
+		TotalEarned int    `json:"total_earned"`
+		TotalSpent  int    `json:"total_spent"`
 		NamaLengkap string `json:"nama_lengkap"`
 		Email       string `json:"email"`
 		NoTelepon   string `json:"no_telepon"`
 	}
 
 	// Mengisi response data
-	for _, point := range userPoints {
-		responseData = append(responseData, struct {
-			ID          uint   `json:"id"`
-			UserID      uint   `json:"user_id"`
-			Points      uint   `json:"points"`
-			NamaLengkap string `json:"nama_lengkap"`
-			Email       string `json:"email"`
-			NoTelepon   string `json:"no_telepon"`
-		}{
-			ID:          point.ID,
-			UserID:      point.UserID,
-			Points:      point.Points,
-			NamaLengkap: point.User.NamaLengkap, // Mengakses data User
-			Email:       point.User.Email,       // Mengakses data User
-			NoTelepon:   point.User.NoTelepon,
+	var responseData []userPointsResponse
+	for _, row := range rows {
+		responseData = append(responseData, userPointsResponse{
+			UserID:      row.UserID,
+			Points:      row.Points,
+			TotalEarned: row.TotalEarned,
+			TotalSpent:  row.TotalSpent,
+			NamaLengkap: row.NamaLengkap,
+			Email:       row.Email,
+			NoTelepon:   row.NoTelepon,
 		})
 	}
 
 	// Mengembalikan response sukses dengan data yang diinginkan
 	return c.JSON(http.StatusOK, helper.APIResponse("All user points retrieved successfully", http.StatusOK, "success", responseData))
 }
+
+// Fungsi untuk mendapatkan riwayat (mutasi) poin milik user yang sedang login
+func GetUserPointHistory(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	// Ambil parameter query untuk paginasi
+	pageParam := c.QueryParam("page")
+	limitParam := c.QueryParam("limit")
+
+	// Default nilai untuk paginasi
+	page := 1
+	limit := 10
+
+	// Parse parameter jika ada
+	if pageParam != "" {
+		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
+			limit = min(l, 100) // Halaman dibatasi 100 entri
+		}
+	}
+
+	// Hitung offset berdasarkan page dan limit
+	offset := (page - 1) * limit
+
+	db := config.DB.Model(&models.PointTransaction{}).Where("user_id = ?", userID)
+
+	var totalItems int64
+	if err := db.Count(&totalItems).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to count point history", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Entri terbaru ditampilkan lebih dulu
+	var transactions []models.PointTransaction
+	if err := db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&transactions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve point history", http.StatusInternalServerError, "error", nil))
+	}
+
+	var items []PointTransactionResponse
+	for _, tx := range transactions {
+		items = append(items, PointTransactionResponse{
+			ID:           tx.ID,
+			Delta:        tx.Delta,
+			BalanceAfter: tx.BalanceAfter,
+			Reason:       tx.Reason,
+			ReportID:     tx.ReportID,
+			RedemptionID: tx.RedemptionID,
+			Note:         tx.Note,
+			CreatedAt:    tx.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	// Hitung total halaman
+	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": items,
+			"pagination": map[string]interface{}{
+				"current_page":       page,
+				"per_page":           limit,
+				"total_transactions": totalItems,
+				"total_pages":        totalPages,
+			},
+		},
+		"error": nil,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Point history retrieved successfully", http.StatusOK, "success", response))
+}
@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"Backend-Recything/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientPoints dikembalikan jika transaksi akan membuat saldo poin menjadi negatif
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// postPointTransaction mencatat satu entri ledger dan memperbarui saldo user (User.Points).
+// Harus dipanggil di dalam transaksi DB agar entri dan saldo selalu konsisten.
+func postPointTransaction(tx *gorm.DB, entry *models.PointTransaction) (uint, error) {
+	// Kunci baris user supaya transaksi paralel tidak saling menimpa saldo
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, entry.UserID).Error; err != nil {
+		return 0, err
+	}
+
+	balance := int64(user.Points) + int64(entry.Delta)
+	if balance < 0 {
+		return 0, ErrInsufficientPoints
+	}
+
+	entry.BalanceAfter = uint(balance)
+	if err := tx.Create(entry).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("points", entry.BalanceAfter).Error; err != nil {
+		return 0, err
+	}
+
+	return entry.BalanceAfter, nil
+}
+
+// Aksi poin yang dihasilkan dari perubahan status laporan
+const (
+	PointActionGranted   = "granted"
+	PointActionReversed  = "reversed"
+	PointActionUnchanged = "unchanged"
+)
+
+// reportPointBalance menghitung poin bersih yang saat ini dipegang user dari satu laporan
+func reportPointBalance(tx *gorm.DB, reportID uint) (int, error) {
+	var net int
+	err := tx.Model(&models.PointTransaction{}).
+		Select("COALESCE(SUM(delta), 0)").
+		Where("report_id = ? AND reason IN ?", reportID, []string{models.PointReasonReportAward, models.PointReasonReportReversal}).
+		Scan(&net).Error
+	return net, err
+}
+
+// grantReportPoints memberikan poin untuk laporan yang disetujui, maksimal satu kali per laporan.
+// Jika poin laporan masih dipegang user, tidak ada entri baru yang dibuat.
+func grantReportPoints(tx *gorm.DB, report models.ReportRubbish, actorID uint, eval PointEvaluation) (string, int, error) {
+	points := eval.Total
+	net, err := reportPointBalance(tx, report.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	if net > 0 || points <= 0 {
+		return PointActionUnchanged, 0, nil
+	}
+
+	reportID := report.ID
+	if _, err := postPointTransaction(tx, &models.PointTransaction{
+		UserID:   report.UserID,
+		Delta:    points,
+		Reason:   models.PointReasonReportAward,
+		ReportID: &reportID,
+		ActorID:  &actorID,
+		Note:     eval.Note(),
+	}); err != nil {
+		return "", 0, err
+	}
+	return PointActionGranted, points, nil
+}
+
+// reverseReportPoints membatalkan poin yang pernah diberikan untuk sebuah laporan.
+// Jika sebagian poin sudah dipakai, hanya sisa saldo user yang ditarik agar saldo tidak negatif.
+func reverseReportPoints(tx *gorm.DB, report models.ReportRubbish, actorID uint, note string) (string, int, error) {
+	net, err := reportPointBalance(tx, report.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	if net <= 0 {
+		return PointActionUnchanged, 0, nil
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, report.UserID).Error; err != nil {
+		return "", 0, err
+	}
+	amount := net
+	if int(user.Points) < amount {
+		amount = int(user.Points)
+		note = strings.TrimSpace(fmt.Sprintf("%s (partial reversal, %d points already spent)", note, net-amount))
+	}
+	if amount == 0 {
+		return PointActionUnchanged, 0, nil
+	}
+
+	reportID := report.ID
+	if _, err := postPointTransaction(tx, &models.PointTransaction{
+		UserID:   report.UserID,
+		Delta:    -amount,
+		Reason:   models.PointReasonReportReversal,
+		ReportID: &reportID,
+		ActorID:  &actorID,
+		Note:     note,
+	}); err != nil {
+		return "", 0, err
+	}
+	return PointActionReversed, -amount, nil
+}
+
+// confirmationPointBalances menghitung poin konfirmasi bersih per user untuk satu laporan
+func confirmationPointBalances(tx *gorm.DB, reportID uint) (map[uint]int, error) {
+	var rows []struct {
+		UserID uint
+		Net    int
+	}
+	err := tx.Model(&models.PointTransaction{}).
+		Select("user_id, COALESCE(SUM(delta), 0) AS net").
+		Where("report_id = ? AND reason IN ?", reportID, []string{models.PointReasonConfirmationAward, models.PointReasonConfirmationReversal}).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		balances[row.UserID] = row.Net
+	}
+	return balances, nil
+}
+
+// userReportPointBalance menghitung poin bersih yang dipegang seorang user dari satu laporan,
+// baik sebagai pelapor maupun sebagai pelapor tambahan
+func userReportPointBalance(tx *gorm.DB, userID uint, reportID uint) (int, error) {
+	var net int
+	err := tx.Model(&models.PointTransaction{}).
+		Select("COALESCE(SUM(delta), 0)").
+		Where("user_id = ? AND report_id = ? AND reason IN ?", userID, reportID, []string{
+			models.PointReasonReportAward, models.PointReasonReportReversal,
+			models.PointReasonConfirmationAward, models.PointReasonConfirmationReversal,
+		}).
+		Scan(&net).Error
+	return net, err
+}
+
+// grantConfirmationPoints memberikan poin kepada pelapor tambahan dari laporan yang disetujui.
+// Setiap user hanya dikreditkan sekali per laporan, pemilik laporan tidak ikut dikreditkan,
+// dan pelapor dari laporan duplikat yang poinnya masih dipegang tidak dibayar dua kali.
+func grantConfirmationPoints(tx *gorm.DB, report models.ReportRubbish, actorID *uint) (int, error) {
+	var confirmations []models.ReportConfirmation
+	if err := tx.Where("report_id = ?", report.ID).Order("id ASC").Find(&confirmations).Error; err != nil {
+		return 0, err
+	}
+	if len(confirmations) == 0 {
+		return 0, nil
+	}
+
+	balances, err := confirmationPointBalances(tx, report.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	credited := 0
+	for _, confirmation := range confirmations {
+		if confirmation.UserID == report.UserID || balances[confirmation.UserID] > 0 {
+			continue
+		}
+		if confirmation.MergedFromReportID != nil {
+			held, err := userReportPointBalance(tx, confirmation.UserID, *confirmation.MergedFromReportID)
+			if err != nil {
+				return 0, err
+			}
+			if held > 0 {
+				continue
+			}
+		}
+
+		eval, err := evaluatePoints(tx, confirmation.UserID, report.Category, models.PointOutcomeReportConfirmed, 0, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		if eval.Total <= 0 {
+			continue
+		}
+
+		reportID := report.ID
+		if _, err := postPointTransaction(tx, &models.PointTransaction{
+			UserID:   confirmation.UserID,
+			Delta:    eval.Total,
+			Reason:   models.PointReasonConfirmationAward,
+			ReportID: &reportID,
+			ActorID:  actorID,
+			Note:     eval.Note(),
+		}); err != nil {
+			return 0, err
+		}
+		balances[confirmation.UserID] = eval.Total
+		credited++
+	}
+	return credited, nil
+}
+
+// reverseConfirmationPoints membatalkan poin konfirmasi yang pernah diberikan untuk sebuah laporan.
+// Sama seperti reverseReportPoints, penarikan dibatasi sisa saldo user.
+func reverseConfirmationPoints(tx *gorm.DB, report models.ReportRubbish, actorID uint, note string) error {
+	balances, err := confirmationPointBalances(tx, report.ID)
+	if err != nil {
+		return err
+	}
+
+	for userID, net := range balances {
+		if net <= 0 {
+			continue
+		}
+
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		amount := net
+		entryNote := note
+		if int(user.Points) < amount {
+			amount = int(user.Points)
+			entryNote = strings.TrimSpace(fmt.Sprintf("%s (partial reversal, %d points already spent)", note, net-amount))
+		}
+		if amount == 0 {
+			continue
+		}
+
+		reportID := report.ID
+		if _, err := postPointTransaction(tx, &models.PointTransaction{
+			UserID:   userID,
+			Delta:    -amount,
+			Reason:   models.PointReasonConfirmationReversal,
+			ReportID: &reportID,
+			ActorID:  &actorID,
+			Note:     entryNote,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grantCleanupPoints memberikan bonus kepada user yang membersihkan sampah laporan.
+// Bonus hanya diberikan sekali per laporan, siapa pun yang membersihkannya.
+func grantCleanupPoints(tx *gorm.DB, report models.ReportRubbish, cleanerID uint, actorID uint) (int, error) {
+	var existing int64
+	if err := tx.Model(&models.PointTransaction{}).
+		Where("report_id = ? AND reason = ?", report.ID, models.PointReasonCleanupAward).
+		Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, nil
+	}
+
+	eval, err := evaluatePoints(tx, cleanerID, report.Category, models.PointOutcomeReportCleaned, 0, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if eval.Total <= 0 {
+		return 0, nil
+	}
+
+	reportID := report.ID
+	if _, err := postPointTransaction(tx, &models.PointTransaction{
+		UserID:   cleanerID,
+		Delta:    eval.Total,
+		Reason:   models.PointReasonCleanupAward,
+		ReportID: &reportID,
+		ActorID:  &actorID,
+		Note:     eval.Note(),
+	}); err != nil {
+		return 0, err
+	}
+	return eval.Total, nil
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"Backend-Recything/internal/testdb"
+	"Backend-Recything/models"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func createTestUser(t *testing.T, db *gorm.DB, points uint) models.User {
+	t.Helper()
+	user := models.User{NamaLengkap: "Test User", Email: t.Name() + "@example.com", Role: models.RoleUser, Points: points}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func userPoints(t *testing.T, db *gorm.DB, userID uint) uint {
+	t.Helper()
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	return user.Points
+}
+
+func TestPostPointTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     uint
+		delta       int
+		wantErr     error
+		wantBalance uint
+	}{
+		{name: "credit", balance: 10, delta: 5, wantBalance: 15},
+		{name: "debit to zero", balance: 10, delta: -10, wantBalance: 0},
+		{name: "overdraft is rejected", balance: 10, delta: -11, wantErr: ErrInsufficientPoints, wantBalance: 10},
+		{name: "zero balance debit is rejected", balance: 0, delta: -1, wantErr: ErrInsufficientPoints, wantBalance: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.User{}, &models.PointTransaction{})
+			user := createTestUser(t, db, tt.balance)
+
+			entry := models.PointTransaction{UserID: user.ID, Delta: tt.delta, Reason: models.PointReasonManualAward}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				_, err := postPointTransaction(tx, &entry)
+				return err
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("postPointTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := userPoints(t, db, user.ID); got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+
+			var entries []models.PointTransaction
+			db.Where("user_id = ?", user.ID).Find(&entries)
+			if tt.wantErr != nil {
+				if len(entries) != 0 {
+					t.Errorf("rejected transaction left %d ledger entries", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].BalanceAfter != tt.wantBalance {
+				t.Errorf("ledger = %+v, want one entry with balance_after %d", entries, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestReportPointsGrantAndReverse(t *testing.T) {
+	// Langkah: "grant" memberi 10 poin laporan, "reverse" membatalkannya, "spend:N" memakai N poin
+	tests := []struct {
+		name        string
+		steps       []string
+		wantActions []string
+		wantBalance uint
+		wantNet     int
+		wantPartial bool
+	}{
+		{
+			name:        "grant once",
+			steps:       []string{"grant"},
+			wantActions: []string{PointActionGranted},
+			wantBalance: 10, wantNet: 10,
+		},
+		{
+			name:        "second grant is ignored",
+			steps:       []string{"grant", "grant"},
+			wantActions: []string{PointActionGranted, PointActionUnchanged},
+			wantBalance: 10, wantNet: 10,
+		},
+		{
+			name:        "reverse without grant",
+			steps:       []string{"reverse"},
+			wantActions: []string{PointActionUnchanged},
+			wantBalance: 0, wantNet: 0,
+		},
+		{
+			name:        "grant then reverse",
+			steps:       []string{"grant", "reverse", "reverse"},
+			wantActions: []string{PointActionGranted, PointActionReversed, PointActionUnchanged},
+			wantBalance: 0, wantNet: 0,
+		},
+		{
+			name:        "reapproval after reversal grants again",
+			steps:       []string{"grant", "reverse", "grant"},
+			wantActions: []string{PointActionGranted, PointActionReversed, PointActionGranted},
+			wantBalance: 10, wantNet: 10,
+		},
+		{
+			name:        "partial reversal when points were spent",
+			steps:       []string{"grant", "spend:7", "reverse"},
+			wantActions: []string{PointActionGranted, "", PointActionReversed},
+			wantBalance: 0, wantNet: 7, wantPartial: true,
+		},
+		{
+			name:        "nothing to reverse when everything was spent",
+			steps:       []string{"grant", "spend:10", "reverse"},
+			wantActions: []string{PointActionGranted, "", PointActionUnchanged},
+			wantBalance: 0, wantNet: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.User{}, &models.PointTransaction{})
+			user := createTestUser(t, db, 0)
+			report := models.ReportRubbish{ID: 42, UserID: user.ID}
+			eval := PointEvaluation{Total: 10}
+
+			for i, step := range tt.steps {
+				var action string
+				err := db.Transaction(func(tx *gorm.DB) error {
+					var err error
+					switch {
+					case step == "grant":
+						action, _, err = grantReportPoints(tx, report, 1, eval)
+					case step == "reverse":
+						action, _, err = reverseReportPoints(tx, report, 1, "rejected")
+					case strings.HasPrefix(step, "spend:"):
+						amount, _ := strconv.Atoi(strings.TrimPrefix(step, "spend:"))
+						_, err = postPointTransaction(tx, &models.PointTransaction{UserID: user.ID, Delta: -amount, Reason: models.PointReasonAdminDeduction})
+					}
+					return err
+				})
+				if err != nil {
+					t.Fatalf("step %d (%s) error = %v", i, step, err)
+				}
+				if action != tt.wantActions[i] {
+					t.Errorf("step %d (%s) action = %q, want %q", i, step, action, tt.wantActions[i])
+				}
+			}
+
+			if got := userPoints(t, db, user.ID); got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+			net, err := reportPointBalance(db, report.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if net != tt.wantNet {
+				t.Errorf("report point balance = %d, want %d", net, tt.wantNet)
+			}
+
+			var reversal models.PointTransaction
+			err = db.Where("reason = ?", models.PointReasonReportReversal).Last(&reversal).Error
+			partial := err == nil && strings.Contains(reversal.Note, "partial reversal")
+			if partial != tt.wantPartial {
+				t.Errorf("partial reversal note = %v (%q), want %v", partial, reversal.Note, tt.wantPartial)
+			}
+		})
+	}
+}
+
+// Saldo user harus selalu sama dengan jumlah delta di ledger-nya
+func TestPointBalanceMatchesLedger(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.PointTransaction{})
+	user := createTestUser(t, db, 0)
+
+	deltas := []int{25, -5, 40, -60, 3, -100}
+	for _, delta := range deltas {
+		db.Transaction(func(tx *gorm.DB) error {
+			_, err := postPointTransaction(tx, &models.PointTransaction{UserID: user.ID, Delta: delta, Reason: models.PointReasonManualAward})
+			return err
+		})
+	}
+
+	var sum int
+	db.Model(&models.PointTransaction{}).Select("COALESCE(SUM(delta), 0)").Where("user_id = ?", user.ID).Scan(&sum)
+	if got := userPoints(t, db, user.ID); int(got) != sum || sum != 3 {
+		t.Errorf("balance = %d, ledger sum = %d, want both 3", got, sum)
+	}
+}
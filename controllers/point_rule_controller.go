@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Struct untuk input aturan poin
+type PointRuleInput struct {
+	Name       string  `json:"name" validate:"required,max=100"` // Beberapa nama aturan digabung ke note ledger
+	Kind       string  `json:"kind" validate:"required,oneof=base first_of_month_bonus campaign"`
+	Category   string  `json:"category" validate:"omitempty,oneof=report_rubbish report_littering"`
+	Outcome    string  `json:"outcome" validate:"required,oneof=report_approved report_confirmed report_cleaned manual_award"`
+	Points     int     `json:"points" validate:"min=0"`
+	Multiplier float64 `json:"multiplier" validate:"omitempty,gt=0"`
+	StartsAt   string  `json:"starts_at"` // Format YYYY-MM-DD, opsional
+	EndsAt     string  `json:"ends_at"`   // Format YYYY-MM-DD, opsional (inklusif)
+	Active     *bool   `json:"active"`
+}
+
+// Fungsi untuk mengubah input menjadi model aturan poin
+func (input PointRuleInput) toModel(rule *models.PointRule) error {
+	rule.Name = input.Name
+	rule.Kind = input.Kind
+	rule.Category = input.Category
+	rule.Outcome = input.Outcome
+	rule.Points = input.Points
+	rule.Multiplier = 1
+	if input.Kind == models.PointRuleKindCampaign {
+		rule.Multiplier = input.Multiplier
+	}
+	rule.Active = input.Active == nil || *input.Active
+
+	rule.StartsAt = nil
+	if input.StartsAt != "" {
+		startsAt, err := time.ParseInLocation("2006-01-02", input.StartsAt, time.Local)
+		if err != nil {
+			return errors.New("invalid starts_at format. Please use YYYY-MM-DD")
+		}
+		rule.StartsAt = &startsAt
+	}
+
+	rule.EndsAt = nil
+	if input.EndsAt != "" {
+		endsAt, err := time.ParseInLocation("2006-01-02", input.EndsAt, time.Local)
+		if err != nil {
+			return errors.New("invalid ends_at format. Please use YYYY-MM-DD")
+		}
+		// Tanggal akhir berlaku sampai akhir hari
+		endsAt = endsAt.Add(24*time.Hour - time.Second)
+		rule.EndsAt = &endsAt
+	}
+
+	// Kampanye wajib memiliki periode dan pengali
+	if rule.Kind == models.PointRuleKindCampaign {
+		if rule.StartsAt == nil || rule.EndsAt == nil {
+			return errors.New("campaign rules require starts_at and ends_at")
+		}
+		if rule.Multiplier <= 0 {
+			return errors.New("campaign rules require a multiplier greater than 0")
+		}
+	}
+	if rule.StartsAt != nil && rule.EndsAt != nil && rule.EndsAt.Before(*rule.StartsAt) {
+		return errors.New("ends_at must not be before starts_at")
+	}
+
+	return nil
+}
+
+// Fungsi untuk mendapatkan semua aturan poin
+func GetPointRules(c echo.Context) error {
+	db := config.DB.Model(&models.PointRule{})
+	if kind := c.QueryParam("kind"); kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	if outcome := c.QueryParam("outcome"); outcome != "" {
+		db = db.Where("outcome = ?", outcome)
+	}
+
+	var rules []models.PointRule
+	if err := db.Order("id ASC").Find(&rules).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve point rules", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Point rules retrieved successfully", http.StatusOK, "success", rules))
+}
+
+// Fungsi untuk membuat aturan poin baru
+func CreatePointRule(c echo.Context) error {
+	var input PointRuleInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var rule models.PointRule
+	if err := input.toModel(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+
+	if err := config.DB.Create(&rule).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create point rule", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Point rule created successfully", http.StatusOK, "success", rule))
+}
+
+// Fungsi untuk memperbarui aturan poin
+func UpdatePointRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid point rule ID", http.StatusBadRequest, "error", nil))
+	}
+
+	var input PointRuleInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var rule models.PointRule
+	if err := config.DB.First(&rule, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Point rule not found", http.StatusNotFound, "error", nil))
+	}
+
+	if err := input.toModel(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+
+	if err := config.DB.Save(&rule).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update point rule", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Point rule updated successfully", http.StatusOK, "success", rule))
+}
+
+// Fungsi untuk menghapus aturan poin
+func DeletePointRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid point rule ID", http.StatusBadRequest, "error", nil))
+	}
+
+	result := config.DB.Delete(&models.PointRule{}, id)
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to delete point rule", http.StatusInternalServerError, "error", nil))
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Point rule not found", http.StatusNotFound, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Point rule deleted successfully", http.StatusOK, "success", nil))
+}
+
+// Fungsi dry-run untuk melihat poin yang akan didapat sebuah laporan jika disetujui
+func PreviewReportPoints(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
+	}
+
+	var report models.ReportRubbish
+	if err := config.DB.First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+		}
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Waktu evaluasi bisa diatur lewat query "at" (YYYY-MM-DD) untuk mensimulasikan kampanye
+	at := time.Now()
+	if atParam := c.QueryParam("at"); atParam != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", atParam, time.Local)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid date format. Please use YYYY-MM-DD.", http.StatusBadRequest, "error", nil))
+		}
+		at = parsed.Add(12 * time.Hour)
+	}
+
+	eval, err := evaluatePoints(config.DB, report.UserID, report.Category, models.PointOutcomeReportApproved, report.ID, at)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to evaluate point rules", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Laporan yang poinnya sudah diberikan tidak akan dibayar lagi
+	alreadyAwarded, err := reportPointBalance(config.DB, report.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to evaluate point rules", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := struct {
+		ReportID       uint            `json:"report_id"`
+		Status         string          `json:"status"`
+		EvaluatedAt    string          `json:"evaluated_at"`
+		AlreadyAwarded int             `json:"already_awarded"`
+		Evaluation     PointEvaluation `json:"evaluation"`
+	}{
+		ReportID:       report.ID,
+		Status:         report.Status,
+		EvaluatedAt:    at.Format("2006-01-02 15:04:05"),
+		AlreadyAwarded: alreadyAwarded,
+		Evaluation:     eval,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Report points preview generated successfully", http.StatusOK, "success", responseData))
+}
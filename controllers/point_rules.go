@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"Backend-Recything/models"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Poin bawaan jika admin belum membuat aturan dasar untuk suatu hasil
+var defaultOutcomePoints = map[string]int{
+	models.PointOutcomeReportApproved:  1000,
+	models.PointOutcomeReportConfirmed: 250,
+	models.PointOutcomeReportCleaned:   500,
+	models.PointOutcomeManualAward:     1000,
+}
+
+// Struct untuk rincian satu aturan yang ikut dihitung
+type PointRuleApplied struct {
+	RuleID     *uint   `json:"rule_id"`
+	Name       string  `json:"name"`
+	Kind       string  `json:"kind"`
+	Points     int     `json:"points"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Struct hasil evaluasi aturan poin
+type PointEvaluation struct {
+	Outcome    string             `json:"outcome"`
+	Category   string             `json:"category"`
+	BasePoints int                `json:"base_points"`
+	Bonus      int                `json:"bonus"`
+	Multiplier float64            `json:"multiplier"`
+	Total      int                `json:"total"`
+	Applied    []PointRuleApplied `json:"applied"`
+}
+
+// Note meringkas evaluasi untuk disimpan di ledger, dipotong sesuai panjang kolom note
+func (e PointEvaluation) Note() string {
+	parts := make([]string, 0, len(e.Applied))
+	for _, rule := range e.Applied {
+		if rule.Kind == models.PointRuleKindCampaign {
+			parts = append(parts, fmt.Sprintf("%s x%g", rule.Name, rule.Multiplier))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s +%d", rule.Name, rule.Points))
+		}
+	}
+	return truncateRunes(strings.Join(parts, ", "), 255)
+}
+
+// evaluatePoints menghitung poin untuk sebuah hasil berdasarkan aturan yang aktif pada waktu "at".
+// excludeReportID dipakai agar laporan yang sedang dievaluasi tidak dihitung sebagai laporan sebelumnya.
+func evaluatePoints(tx *gorm.DB, userID uint, category string, outcome string, excludeReportID uint, at time.Time) (PointEvaluation, error) {
+	eval := PointEvaluation{Outcome: outcome, Category: category, Multiplier: 1}
+
+	var rules []models.PointRule
+	if err := tx.Where("active = ? AND outcome = ? AND (category = '' OR category IS NULL OR category = ?)", true, outcome, category).
+		Order("id ASC").
+		Find(&rules).Error; err != nil {
+		return eval, err
+	}
+
+	// Aturan dasar: aturan khusus kategori lebih diutamakan daripada aturan umum, yang terbaru menang
+	var base *models.PointRule
+	for i := range rules {
+		rule := rules[i]
+		if rule.Kind != models.PointRuleKindBase || !rule.AppliesAt(at) {
+			continue
+		}
+		if base == nil || rule.Category != "" || base.Category == "" {
+			base = &rules[i]
+		}
+	}
+	if base != nil {
+		eval.BasePoints = base.Points
+		eval.Applied = append(eval.Applied, PointRuleApplied{RuleID: &base.ID, Name: base.Name, Kind: base.Kind, Points: base.Points, Multiplier: 1})
+	} else {
+		eval.BasePoints = defaultOutcomePoints[outcome]
+		eval.Applied = append(eval.Applied, PointRuleApplied{Name: "default", Kind: models.PointRuleKindBase, Points: eval.BasePoints, Multiplier: 1})
+	}
+
+	for i := range rules {
+		rule := rules[i]
+		if !rule.AppliesAt(at) {
+			continue
+		}
+
+		switch rule.Kind {
+		case models.PointRuleKindFirstOfMonthBonus:
+			first, err := isFirstAwardOfMonth(tx, userID, excludeReportID, at)
+			if err != nil {
+				return eval, err
+			}
+			if first {
+				eval.Bonus += rule.Points
+				eval.Applied = append(eval.Applied, PointRuleApplied{RuleID: &rules[i].ID, Name: rule.Name, Kind: rule.Kind, Points: rule.Points, Multiplier: 1})
+			}
+		case models.PointRuleKindCampaign:
+			eval.Multiplier *= rule.Multiplier
+			eval.Applied = append(eval.Applied, PointRuleApplied{RuleID: &rules[i].ID, Name: rule.Name, Kind: rule.Kind, Multiplier: rule.Multiplier})
+		}
+	}
+
+	eval.Total = int(math.Round(float64(eval.BasePoints+eval.Bonus) * eval.Multiplier))
+	return eval, nil
+}
+
+// isFirstAwardOfMonth mengecek apakah user belum pernah menerima poin laporan pada bulan "at"
+func isFirstAwardOfMonth(tx *gorm.DB, userID uint, excludeReportID uint, at time.Time) (bool, error) {
+	monthStart := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	var count int64
+	err := tx.Model(&models.PointTransaction{}).
+		Where("user_id = ? AND reason = ? AND created_at >= ? AND created_at < ?", userID, models.PointReasonReportAward, monthStart, monthEnd).
+		Where("report_id IS NULL OR report_id <> ?", excludeReportID).
+		Count(&count).Error
+	return count == 0, err
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Fungsi untuk mendapatkan profil user yang sedang login beserta poin dan badge
+func GetMyProfile(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
+	}
+
+	var totalReports int64
+	if err := config.DB.Model(&models.ReportRubbish{}).Where("user_id = ?", user.ID).Count(&totalReports).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to count reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	badges, err := loadUserBadges(user.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve user badges", http.StatusInternalServerError, "error", nil))
+	}
+
+	profile := struct {
+		UserResponse
+		Points            uint       `json:"points"`
+		TotalReports      int64      `json:"total_reports"`
+		LeaderboardOptOut bool       `json:"leaderboard_opt_out"`
+		Badges            UserBadges `json:"badges"`
+	}{
+		UserResponse: UserResponse{
+			IDUser:       user.ID,
+			NamaLengkap:  user.NamaLengkap,
+			TanggalLahir: user.TanggalLahir.Format("2006-01-02"),
+			NoTelepon:    user.NoTelepon,
+			Email:        user.Email,
+			Role:         user.Role,
+			Photo:        user.Photo,
+		},
+		Points:            user.Points,
+		TotalReports:      totalReports,
+		LeaderboardOptOut: user.LeaderboardOptOut,
+		Badges:            badges,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Profile retrieved successfully", http.StatusOK, "success", profile))
+}
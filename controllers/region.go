@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RegionImportOptions mengatur cara membaca properti fitur GeoJSON saat impor wilayah
+type RegionImportOptions struct {
+	Level        string // Tingkat wilayah untuk semua fitur di file
+	NameProperty string // Properti berisi nama wilayah
+	CodeProperty string // Properti berisi kode unik wilayah (misalnya kode BPS)
+}
+
+type geoJSONFeatureCollection struct {
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   json.RawMessage        `json:"geometry"`
+	} `json:"features"`
+}
+
+// findRegionForPoint mencari wilayah terkecil yang memuat sebuah titik
+func findRegionForPoint(tx *gorm.DB, lat, lng float64) (*models.Region, error) {
+	var candidates []models.Region
+	if err := tx.Where("min_lat <= ? AND max_lat >= ? AND min_lng <= ? AND max_lng >= ?", lat, lat, lng, lng).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	// Cek wilayah dari tingkat terkecil dan kotak pembatas tersempit lebih dulu
+	sort.Slice(candidates, func(i, j int) bool {
+		ri, rj := models.RegionLevelRank(candidates[i].Level), models.RegionLevelRank(candidates[j].Level)
+		if ri != rj {
+			return ri < rj
+		}
+		return regionBoxArea(candidates[i]) < regionBoxArea(candidates[j])
+	})
+
+	for i := range candidates {
+		polygons, _, err := helper.ParseGeoJSONPolygons([]byte(candidates[i].Geometry))
+		if err != nil {
+			log.Printf("Skipping region %d with invalid geometry: %v", candidates[i].ID, err)
+			continue
+		}
+		if helper.PolygonsContain(polygons, lat, lng) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func regionBoxArea(region models.Region) float64 {
+	return (region.MaxLat - region.MinLat) * (region.MaxLng - region.MinLng)
+}
+
+// assignReportRegion mengisi RegionID laporan berdasarkan koordinatnya
+func assignReportRegion(tx *gorm.DB, report *models.ReportRubbish) error {
+	report.RegionID = nil
+	if report.Latitude == 0 && report.Longitude == 0 {
+		return nil
+	}
+	region, err := findRegionForPoint(tx, report.Latitude, report.Longitude)
+	if err != nil || region == nil {
+		return err
+	}
+	report.RegionID = &region.ID
+	return nil
+}
+
+// regionAncestorIDs mengembalikan ID wilayah beserta semua wilayah induknya
+func regionAncestorIDs(tx *gorm.DB, regionID uint) ([]uint, error) {
+	ids := []uint{}
+	current := &regionID
+	for current != nil && len(ids) < len(models.RegionLevels) {
+		var region models.Region
+		if err := tx.Select("id, parent_id").First(&region, *current).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, region.ID)
+		current = region.ParentID
+	}
+	return ids, nil
+}
+
+// regionAndDescendantIDs mengembalikan ID wilayah beserta semua wilayah di bawahnya
+func regionAndDescendantIDs(tx *gorm.DB, regionID uint) ([]uint, error) {
+	ids := []uint{regionID}
+	frontier := []uint{regionID}
+	for len(frontier) > 0 {
+		var children []uint
+		if err := tx.Model(&models.Region{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, children...)
+		frontier = children
+	}
+	return ids, nil
+}
+
+// ImportRegionsFromGeoJSON mengimpor (atau memperbarui berdasarkan kode) wilayah dari file
+// GeoJSON FeatureCollection, lalu menghubungkan setiap wilayah ke wilayah induknya.
+func ImportRegionsFromGeoJSON(path string, options RegionImportOptions) (int, error) {
+	if models.RegionLevelRank(options.Level) < 0 {
+		return 0, fmt.Errorf("invalid level %q, use one of: %s", options.Level, strings.Join(models.RegionLevels, ", "))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return 0, fmt.Errorf("invalid GeoJSON FeatureCollection: %w", err)
+	}
+
+	imported := 0
+	seen := make(map[string]int, len(collection.Features)) // Kode wilayah -> indeks fitur pertama
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		for i, feature := range collection.Features {
+			name := featureProperty(feature.Properties, options.NameProperty)
+			if name == "" {
+				return fmt.Errorf("feature %d has no %q property", i, options.NameProperty)
+			}
+			code := featureProperty(feature.Properties, options.CodeProperty)
+			if code == "" {
+				// Tanpa kode resmi, nama wilayah dijadikan kode per tingkat
+				code = options.Level + ":" + helper.NormalizeGeocodeQuery(name)
+			}
+			// Kode yang berulang akan saling menimpa geometri, jadi impor dibatalkan
+			if first, ok := seen[code]; ok {
+				return fmt.Errorf("features %d and %d share region code %q, use -code-prop with a property holding unique codes", first, i, code)
+			}
+			seen[code] = i
+
+			_, box, err := helper.ParseGeoJSONPolygons(feature.Geometry)
+			if err != nil {
+				return fmt.Errorf("feature %d (%s): %w", i, name, err)
+			}
+
+			region := models.Region{Code: code}
+			if err := tx.Where("code = ?", code).FirstOrInit(&region).Error; err != nil {
+				return err
+			}
+			region.Name = name
+			region.Level = options.Level
+			region.Geometry = string(feature.Geometry)
+			region.MinLat, region.MinLng, region.MaxLat, region.MaxLng = box.MinLat, box.MinLng, box.MaxLat, box.MaxLng
+			if err := tx.Save(&region).Error; err != nil {
+				return err
+			}
+			imported++
+		}
+		return linkRegionParents(tx)
+	})
+	return imported, err
+}
+
+// featureProperty membaca properti fitur GeoJSON sebagai string
+func featureProperty(properties map[string]interface{}, key string) string {
+	value, ok := properties[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// linkRegionParents menghubungkan setiap wilayah ke wilayah tingkat di atasnya yang memuatnya
+func linkRegionParents(tx *gorm.DB) error {
+	var regions []models.Region
+	if err := tx.Find(&regions).Error; err != nil {
+		return err
+	}
+
+	parsed := make(map[uint][]helper.Polygon, len(regions))
+	for _, region := range regions {
+		polygons, _, err := helper.ParseGeoJSONPolygons([]byte(region.Geometry))
+		if err != nil {
+			return fmt.Errorf("region %s: %w", region.Code, err)
+		}
+		parsed[region.ID] = polygons
+	}
+
+	for _, region := range regions {
+		lat, lng, ok := helper.InteriorPoint(parsed[region.ID])
+		if !ok {
+			continue
+		}
+
+		// Induk adalah wilayah dengan tingkat terdekat di atasnya yang memuat titik dalam wilayah ini
+		var parent *models.Region
+		rank := models.RegionLevelRank(region.Level)
+		for i := range regions {
+			candidate := &regions[i]
+			candidateRank := models.RegionLevelRank(candidate.Level)
+			if candidateRank <= rank || (parent != nil && candidateRank >= models.RegionLevelRank(parent.Level)) {
+				continue
+			}
+			box := helper.BoundingBox{MinLat: candidate.MinLat, MinLng: candidate.MinLng, MaxLat: candidate.MaxLat, MaxLng: candidate.MaxLng}
+			if box.Contains(lat, lng) && helper.PolygonsContain(parsed[candidate.ID], lat, lng) {
+				parent = candidate
+			}
+		}
+
+		var parentID *uint
+		if parent != nil {
+			parentID = &parent.ID
+		}
+		if err := tx.Model(&models.Region{}).Where("id = ?", region.ID).Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillReportRegions menentukan wilayah untuk laporan yang sudah ada.
+// Jika all bernilai false, hanya laporan yang belum punya wilayah yang diproses.
+func BackfillReportRegions(all bool) (int, error) {
+	db := config.DB.Model(&models.ReportRubbish{}).Where("(latitude <> 0 OR longitude <> 0)")
+	if !all {
+		db = db.Where("region_id IS NULL")
+	}
+
+	updated := 0
+	var reports []models.ReportRubbish
+	result := db.FindInBatches(&reports, 200, func(tx *gorm.DB, batch int) error {
+		for i := range reports {
+			previous := reports[i].RegionID
+			if err := assignReportRegion(config.DB, &reports[i]); err != nil {
+				return err
+			}
+			current := reports[i].RegionID
+			if (previous == nil && current == nil) || (previous != nil && current != nil && *previous == *current) {
+				continue
+			}
+			if err := config.DB.Model(&models.ReportRubbish{}).Where("id = ?", reports[i].ID).
+				Update("region_id", reports[i].RegionID).Error; err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, result.Error
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Struct untuk respons wilayah
+type RegionResponse struct {
+	ID       uint   `json:"id"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Level    string `json:"level"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// Struct untuk jumlah laporan per kelompok (status, kategori, atau sub-wilayah)
+type RegionCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+func toRegionResponse(region models.Region) RegionResponse {
+	return RegionResponse{
+		ID:       region.ID,
+		Code:     region.Code,
+		Name:     region.Name,
+		Level:    region.Level,
+		ParentID: region.ParentID,
+	}
+}
+
+var errInvalidRegionID = errors.New("invalid region_id")
+
+// regionFilterIDs membaca query parameter region_id dan mengembalikan ID wilayah beserta sub-wilayahnya.
+// Mengembalikan nil jika parameter tidak diisi.
+func regionFilterIDs(c echo.Context) ([]uint, error) {
+	param := c.QueryParam("region_id")
+	if param == "" {
+		return nil, nil
+	}
+	regionID, err := strconv.Atoi(param)
+	if err != nil || regionID <= 0 {
+		return nil, errInvalidRegionID
+	}
+
+	var region models.Region
+	if err := config.DB.Select("id").First(&region, regionID).Error; err != nil {
+		return nil, err
+	}
+	return regionAndDescendantIDs(config.DB, region.ID)
+}
+
+// regionFilterErrorResponse mengubah error dari regionFilterIDs menjadi respons HTTP
+func regionFilterErrorResponse(c echo.Context, err error) error {
+	if errors.Is(err, errInvalidRegionID) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid region_id", http.StatusBadRequest, "error", nil))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Region not found", http.StatusNotFound, "error", nil))
+	}
+	return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to load region", http.StatusInternalServerError, "error", nil))
+}
+
+// Fungsi untuk menampilkan daftar wilayah, bisa difilter berdasarkan tingkat dan induk
+func GetRegions(c echo.Context) error {
+	db := config.DB.Model(&models.Region{})
+	if level := c.QueryParam("level"); level != "" {
+		if models.RegionLevelRank(level) < 0 {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid level", http.StatusBadRequest, "error", nil))
+		}
+		db = db.Where("level = ?", level)
+	}
+	if parent := c.QueryParam("parent_id"); parent != "" {
+		parentID, err := strconv.Atoi(parent)
+		if err != nil || parentID <= 0 {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid parent_id", http.StatusBadRequest, "error", nil))
+		}
+		db = db.Where("parent_id = ?", parentID)
+	}
+
+	// Geometri tidak ikut diambil karena ukurannya besar
+	var regions []models.Region
+	if err := db.Select("id, code, name, level, parent_id").Order("name ASC").Find(&regions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve regions", http.StatusInternalServerError, "error", nil))
+	}
+
+	responses := []RegionResponse{}
+	for _, region := range regions {
+		responses = append(responses, toRegionResponse(region))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Regions retrieved successfully", http.StatusOK, "success", responses))
+}
+
+// Fungsi untuk menampilkan ringkasan laporan di sebuah wilayah beserta sub-wilayahnya
+func GetRegionSummary(c echo.Context) error {
+	regionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || regionID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid region ID", http.StatusBadRequest, "error", nil))
+	}
+
+	var region models.Region
+	if err := config.DB.Select("id, code, name, level, parent_id").First(&region, regionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, helper.APIResponse("Region not found", http.StatusNotFound, "error", nil))
+		}
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve region", http.StatusInternalServerError, "error", nil))
+	}
+
+	regionIDs, err := regionAndDescendantIDs(config.DB, region.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve region", http.StatusInternalServerError, "error", nil))
+	}
+	reports := func() *gorm.DB {
+		return config.DB.Model(&models.ReportRubbish{}).Where("region_id IN ? AND status <> ?", regionIDs, models.ReportStatusMerged)
+	}
+
+	var totalReports, totalReporters, last30Days int64
+	byStatus := []RegionCount{}
+	byCategory := []RegionCount{}
+	if err := reports().Count(&totalReports).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+	if err := reports().Distinct("user_id").Count(&totalReporters).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+	if err := reports().Where("tanggal_laporan >= ?", time.Now().AddDate(0, 0, -30)).Count(&last30Days).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+	if err := reports().Select("status AS `key`, COUNT(*) AS count").Group("status").Order("count DESC").Scan(&byStatus).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+	if err := reports().Select("category AS `key`, COUNT(*) AS count").Group("category").Order("count DESC").Scan(&byCategory).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Jumlah laporan per sub-wilayah langsung (misalnya kelurahan di dalam kecamatan)
+	var children []models.Region
+	if err := config.DB.Select("id, code, name, level, parent_id").Where("parent_id = ?", region.ID).Order("name ASC").Find(&children).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+	}
+	childSummaries := []map[string]interface{}{}
+	for _, child := range children {
+		childIDs, err := regionAndDescendantIDs(config.DB, child.ID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+		}
+		var count int64
+		if err := config.DB.Model(&models.ReportRubbish{}).Where("region_id IN ? AND status <> ?", childIDs, models.ReportStatusMerged).
+			Count(&count).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to summarize region", http.StatusInternalServerError, "error", nil))
+		}
+		childSummaries = append(childSummaries, map[string]interface{}{
+			"region":        toRegionResponse(child),
+			"total_reports": count,
+		})
+	}
+
+	responseData := map[string]interface{}{
+		"region":          toRegionResponse(region),
+		"total_reports":   totalReports,
+		"total_reporters": totalReporters,
+		"last_30_days":    last30Days,
+		"by_status":       byStatus,
+		"by_category":     byCategory,
+		"sub_regions":     childSummaries,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Region summary retrieved successfully", http.StatusOK, "success", responseData))
+}
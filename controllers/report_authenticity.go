@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Batas bawaan cek keaslian foto, bisa diubah lewat PHOTO_MAX_DISTANCE_M dan PHOTO_MAX_AGE_HOURS
+const (
+	defaultPhotoMaxDistanceMeters = 500
+	defaultPhotoMaxAgeHours       = 24 // Toleransi foto diambil sebelum tanggal laporan
+)
+
+// Struct untuk metadata dan hasil cek keaslian satu foto (admin)
+type PhotoAuthenticityResponse struct {
+	PhotoID   uint     `json:"photo_id"`
+	URL       string   `json:"url"`
+	TakenAt   string   `json:"taken_at,omitempty"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	DistanceM *float64 `json:"distance_m"`
+	Camera    string   `json:"camera,omitempty"`
+	Flags     []string `json:"flags"`
+}
+
+// Struct untuk ringkasan keaslian foto laporan (admin)
+type ReportAuthenticityResponse struct {
+	Flagged bool                        `json:"flagged"`
+	Photos  []PhotoAuthenticityResponse `json:"photos"`
+}
+
+func truncateRunes(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return string([]rune(value)[:max])
+}
+
+// checkPhotoAuthenticity mengisi Flags foto berdasarkan EXIF: waktu atau GPS tidak ada,
+// koordinat jauh dari lokasi laporan, atau foto diambil sebelum tanggal laporan.
+// Mengembalikan true jika foto ditandai.
+func checkPhotoAuthenticity(photo *models.ReportPhoto, report models.ReportRubbish) bool {
+	var flags []string
+	if photo.ExifTakenAt == nil {
+		flags = append(flags, models.PhotoFlagExifMissing)
+	} else {
+		maxAge := time.Duration(config.GetEnvInt("PHOTO_MAX_AGE_HOURS", defaultPhotoMaxAgeHours)) * time.Hour
+		reportDay := time.Date(report.TanggalLaporan.Year(), report.TanggalLaporan.Month(), report.TanggalLaporan.Day(), 0, 0, 0, 0, time.Local)
+		if photo.ExifTakenAt.Before(reportDay.Add(-maxAge)) {
+			flags = append(flags, models.PhotoFlagTakenBeforeReport)
+		}
+	}
+
+	if photo.ExifLatitude == nil || photo.ExifLongitude == nil {
+		flags = append(flags, models.PhotoFlagGPSMissing)
+	} else if report.Latitude != 0 || report.Longitude != 0 {
+		distance := math.Round(helper.HaversineMeters(report.Latitude, report.Longitude, *photo.ExifLatitude, *photo.ExifLongitude))
+		photo.ExifDistanceM = &distance
+		if distance > config.GetEnvFloat("PHOTO_MAX_DISTANCE_M", defaultPhotoMaxDistanceMeters) {
+			flags = append(flags, models.PhotoFlagLocationMismatch)
+		}
+	}
+
+	photo.Flags = strings.Join(flags, ",")
+	return len(flags) > 0
+}
+
+func toReportAuthenticityResponse(report models.ReportRubbish) *ReportAuthenticityResponse {
+	response := &ReportAuthenticityResponse{Flagged: report.PhotoFlagged, Photos: []PhotoAuthenticityResponse{}}
+	for _, photo := range report.Photos {
+		item := PhotoAuthenticityResponse{
+			PhotoID:   photo.ID,
+			URL:       photo.URL,
+			Latitude:  photo.ExifLatitude,
+			Longitude: photo.ExifLongitude,
+			DistanceM: photo.ExifDistanceM,
+			Camera:    photo.ExifCamera,
+			Flags:     photo.FlagList(),
+		}
+		if photo.ExifTakenAt != nil {
+			item.TakenAt = photo.ExifTakenAt.Format(time.RFC3339)
+		}
+		response.Photos = append(response.Photos, item)
+	}
+	return response
+}
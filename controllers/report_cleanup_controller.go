@@ -0,0 +1,378 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Jarak maksimal bawaan foto "after" dari koordinat laporan, bisa diubah lewat CLEANUP_MAX_DISTANCE_M
+const defaultCleanupMaxDistanceMeters = 100
+
+// Error untuk alur verifikasi pembersihan
+var (
+	ErrReportNotApproved      = errors.New("only approved reports can be marked as cleaned")
+	ErrNotCleanupSubmitter    = errors.New("you are not allowed to submit a cleanup for this report")
+	ErrCleanupAlreadyPending  = errors.New("a cleanup for this report is already waiting for verification")
+	ErrCleanupTooFar          = errors.New("cleanup photo location is too far from the report")
+	ErrCleanupAlreadyReviewed = errors.New("cleanup has already been reviewed")
+)
+
+// Struct untuk respons bukti pembersihan
+type ReportCleanupResponse struct {
+	ID            uint    `json:"id"`
+	ReportID      uint    `json:"report_id"`
+	SubmittedByID uint    `json:"submitted_by_id"`
+	NamaLengkap   string  `json:"nama_lengkap"`
+	Photo         string  `json:"photo"`
+	Note          string  `json:"note"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	DistanceM     float64 `json:"distance_m"`
+	LocationFrom  string  `json:"location_from"` // exif, atau form jika foto tanpa GPS
+	Status        string  `json:"status"`
+	ReviewedByID  *uint   `json:"reviewed_by_id"`
+	ReviewNote    string  `json:"review_note"`
+	ReviewedAt    string  `json:"reviewed_at,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+func toReportCleanupResponse(cleanup models.ReportCleanup) ReportCleanupResponse {
+	response := ReportCleanupResponse{
+		ID:            cleanup.ID,
+		ReportID:      cleanup.ReportID,
+		SubmittedByID: cleanup.SubmittedByID,
+		NamaLengkap:   cleanup.SubmittedBy.NamaLengkap,
+		Photo:         cleanup.Photo,
+		Note:          cleanup.Note,
+		Latitude:      cleanup.Latitude,
+		Longitude:     cleanup.Longitude,
+		DistanceM:     cleanup.DistanceM,
+		LocationFrom:  cleanup.LocationFrom,
+		Status:        cleanup.Status,
+		ReviewedByID:  cleanup.ReviewedByID,
+		ReviewNote:    cleanup.ReviewNote,
+		CreatedAt:     cleanup.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if cleanup.ReviewedAt != nil {
+		response.ReviewedAt = cleanup.ReviewedAt.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+// loadReportCleanups mengambil semua bukti pembersihan sebuah laporan, yang terbaru lebih dulu
+func loadReportCleanups(reportID uint) ([]ReportCleanupResponse, error) {
+	var cleanups []models.ReportCleanup
+	if err := config.DB.Preload("SubmittedBy").Where("report_id = ?", reportID).
+		Order("created_at DESC, id DESC").Find(&cleanups).Error; err != nil {
+		return nil, err
+	}
+
+	responses := []ReportCleanupResponse{}
+	for _, cleanup := range cleanups {
+		responses = append(responses, toReportCleanupResponse(cleanup))
+	}
+	return responses, nil
+}
+
+// canSubmitCleanup mengecek apakah user boleh mengirim bukti pembersihan untuk laporan:
+// pelapor sendiri atau anggota tim yang sedang mengerjakan laporan tersebut
+func canSubmitCleanup(tx *gorm.DB, report models.ReportRubbish, userID uint) (bool, error) {
+	if report.UserID == userID {
+		return true, nil
+	}
+	assignment, err := memberAssignment(tx, report.ID, userID)
+	return assignment != nil, err
+}
+
+// checkCleanupSubmittable memastikan laporan sudah disetujui, user boleh mengirim bukti,
+// dan belum ada bukti pembersihan lain yang menunggu verifikasi
+func checkCleanupSubmittable(tx *gorm.DB, report models.ReportRubbish, userID uint) error {
+	if report.Status != models.ReportStatusApproved {
+		return ErrReportNotApproved
+	}
+	allowed, err := canSubmitCleanup(tx, report, userID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrNotCleanupSubmitter
+	}
+
+	var pending int64
+	if err := tx.Model(&models.ReportCleanup{}).
+		Where("report_id = ? AND status = ?", report.ID, models.CleanupStatusPending).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrCleanupAlreadyPending
+	}
+	return nil
+}
+
+// cleanupSubmitErrorResponse memetakan error pengajuan bukti pembersihan ke respons HTTP
+func cleanupSubmitErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrNotCleanupSubmitter):
+		return c.JSON(http.StatusForbidden, helper.APIResponse(err.Error(), http.StatusForbidden, "error", nil))
+	case errors.Is(err, ErrReportNotApproved), errors.Is(err, ErrCleanupAlreadyPending):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	default:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to submit cleanup", http.StatusInternalServerError, "error", nil))
+	}
+}
+
+// Fungsi untuk mengirim foto "after" sebagai bukti bahwa sampah laporan sudah dibersihkan.
+// Lokasi foto (GPS di EXIF, atau koordinat form jika foto tanpa GPS) harus dekat dengan lokasi laporan.
+func SubmitReportCleanup(c echo.Context) error {
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reportID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
+	}
+	return submitReportCleanup(c, uint(reportID))
+}
+
+// submitReportCleanup dipakai bersama oleh pelapor dan tim kebersihan. Jika pengirim adalah anggota
+// tim yang sedang mengerjakan laporan, penugasannya ikut ditandai selesai.
+func submitReportCleanup(c echo.Context, reportID uint) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	file, _ := c.FormFile("photo")
+	if file == nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("An after photo is required", http.StatusBadRequest, "error", nil))
+	}
+	data, exif, err := readReportPhoto(file)
+	if errors.Is(err, ErrInvalidPhotoType) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid file type, only JPEG and PNG photos are allowed", http.StatusBadRequest, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to read photo", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Lokasi diambil dari GPS di EXIF foto; koordinat dari form hanya dipakai jika foto tidak memiliki GPS
+	var lat, lng float64
+	locationFrom := models.CleanupLocationExif
+	if exif != nil && exif.Latitude != nil && exif.Longitude != nil {
+		lat, lng = *exif.Latitude, *exif.Longitude
+	} else {
+		var errLat, errLng error
+		lat, errLat = strconv.ParseFloat(c.FormValue("latitude"), 64)
+		lng, errLng = strconv.ParseFloat(c.FormValue("longitude"), 64)
+		if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("Valid latitude and longitude are required when the photo has no GPS data", http.StatusBadRequest, "error", nil))
+		}
+		locationFrom = models.CleanupLocationForm
+	}
+
+	// Cek laporan, status, dan pengirim sebelum foto diunggah agar pengajuan yang pasti ditolak tidak menyisakan file
+	var report models.ReportRubbish
+	err = config.DB.First(&report, reportID).Error
+	if err == nil {
+		err = checkCleanupSubmittable(config.DB, report, userID)
+	}
+	if err != nil {
+		return cleanupSubmitErrorResponse(c, err)
+	}
+	maxDistance := config.GetEnvFloat("CLEANUP_MAX_DISTANCE_M", defaultCleanupMaxDistanceMeters)
+	distance := helper.HaversineMeters(report.Latitude, report.Longitude, lat, lng)
+	if report.Latitude == 0 && report.Longitude == 0 {
+		distance = 0 // Laporan tanpa koordinat tidak bisa dibandingkan, admin memeriksa secara manual
+	}
+	if distance > maxDistance {
+		return c.JSON(http.StatusUnprocessableEntity, helper.APIResponse(ErrCleanupTooFar.Error(), http.StatusUnprocessableEntity, "error", map[string]interface{}{
+			"distance_m":     math.Round(distance),
+			"max_distance_m": maxDistance,
+			"location_from":  locationFrom,
+		}))
+	}
+
+	photoURL, err := uploadReportPhotoData(c.Request().Context(), data)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to upload photo", http.StatusInternalServerError, "error", nil))
+	}
+
+	cleanup := models.ReportCleanup{
+		ReportID:      reportID,
+		SubmittedByID: userID,
+		Photo:         photoURL,
+		Note:          strings.TrimSpace(c.FormValue("note")),
+		Latitude:      lat,
+		Longitude:     lng,
+		DistanceM:     math.Round(distance*10) / 10,
+		LocationFrom:  locationFrom,
+		Status:        models.CleanupStatusPending,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Cek ulang dengan baris terkunci karena laporan bisa berubah selama foto diunggah
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return err
+		}
+		if err := checkCleanupSubmittable(tx, report, userID); err != nil {
+			return err
+		}
+		if err := tx.Create(&cleanup).Error; err != nil {
+			return err
+		}
+
+		assignment, err := memberAssignment(tx, report.ID, userID)
+		if err != nil || assignment == nil {
+			return err
+		}
+		return advanceAssignment(tx, assignment, models.AssignmentEventCompleted, userID, fmt.Sprintf("cleanup #%d submitted", cleanup.ID))
+	})
+	if err != nil {
+		return cleanupSubmitErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, helper.APIResponse("Cleanup submitted for verification", http.StatusCreated, "success", toReportCleanupResponse(cleanup)))
+}
+
+// Fungsi untuk menampilkan antrean bukti pembersihan (admin), bawaan hanya yang menunggu verifikasi
+func GetReportCleanups(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = models.CleanupStatusPending
+	}
+	if status != models.CleanupStatusPending && status != models.CleanupStatusVerified && status != models.CleanupStatusRejected {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid status. Use 'pending', 'verified' or 'rejected'.", http.StatusBadRequest, "error", nil))
+	}
+
+	var cleanups []models.ReportCleanup
+	if err := config.DB.Preload("SubmittedBy").Where("status = ?", status).
+		Order("created_at ASC, id ASC").Limit(100).Find(&cleanups).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve cleanups", http.StatusInternalServerError, "error", nil))
+	}
+
+	responses := []ReportCleanupResponse{}
+	for _, cleanup := range cleanups {
+		responses = append(responses, toReportCleanupResponse(cleanup))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Cleanups retrieved successfully", http.StatusOK, "success", responses))
+}
+
+// Fungsi untuk memverifikasi bukti pembersihan (admin). Laporan pindah ke cleaned_up
+// dan user yang membersihkan mendapat bonus poin.
+func VerifyReportCleanup(c echo.Context) error {
+	return reviewReportCleanup(c, true)
+}
+
+// Fungsi untuk menolak bukti pembersihan (admin). Laporan tetap approved dan bisa diajukan ulang.
+func RejectReportCleanup(c echo.Context) error {
+	return reviewReportCleanup(c, false)
+}
+
+func reviewReportCleanup(c echo.Context, approve bool) error {
+	cleanupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || cleanupID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid cleanup ID", http.StatusBadRequest, "error", nil))
+	}
+
+	input := struct {
+		Note string `json:"note"` // Wajib saat menolak, ditampilkan ke pengirim
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if !approve && input.Note == "" {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("A note is required when rejecting a cleanup", http.StatusBadRequest, "error", nil))
+	}
+
+	adminID, _ := c.Get("userID").(uint)
+	permissions, _ := c.Get("userPermissions").([]string)
+
+	var cleanup models.ReportCleanup
+	pointsAwarded := 0
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cleanup, cleanupID).Error; err != nil {
+			return err
+		}
+		if cleanup.Status != models.CleanupStatusPending {
+			return ErrCleanupAlreadyReviewed
+		}
+
+		now := time.Now()
+		cleanup.ReviewedByID = &adminID
+		cleanup.ReviewedAt = &now
+		cleanup.ReviewNote = input.Note
+		if !approve {
+			cleanup.Status = models.CleanupStatusRejected
+			if err := tx.Save(&cleanup).Error; err != nil {
+				return err
+			}
+			return reopenCompletedAssignment(tx, cleanup.ReportID, adminID, fmt.Sprintf("cleanup #%d rejected", cleanup.ID))
+		}
+
+		var report models.ReportRubbish
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, cleanup.ReportID).Error; err != nil {
+			return err
+		}
+
+		// Foto "after" yang terverifikasi memenuhi syarat transisi approved -> cleaned_up
+		fromStatus := report.Status
+		report.AfterPhoto = cleanup.Photo
+		report.CleanedByID = &cleanup.SubmittedByID
+		report.CleanedAt = &now
+		if err := models.CheckReportTransition(report, models.ReportStatusCleanedUp, permissions, ""); err != nil {
+			return err
+		}
+		report.Status = models.ReportStatusCleanedUp
+		if err := tx.Save(&report).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, report.ID, fromStatus, report.Status, adminID, "", fmt.Sprintf("cleanup #%d verified", cleanup.ID)); err != nil {
+			return err
+		}
+
+		cleanup.Status = models.CleanupStatusVerified
+		if err := tx.Save(&cleanup).Error; err != nil {
+			return err
+		}
+		if err := cancelActiveAssignments(tx, report.ID, &adminID, "report cleaned up"); err != nil {
+			return err
+		}
+
+		var err error
+		pointsAwarded, err = grantCleanupPoints(tx, report, cleanup.SubmittedByID, adminID)
+		return err
+	})
+
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		return transitionErrorResponse(c, transitionErr)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Cleanup not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrCleanupAlreadyReviewed):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to review cleanup", http.StatusInternalServerError, "error", nil))
+	}
+
+	message := "Cleanup rejected"
+	if approve {
+		message = "Cleanup verified successfully"
+	}
+	responseData := map[string]interface{}{
+		"cleanup":        toReportCleanupResponse(cleanup),
+		"points_awarded": pointsAwarded,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse(message, http.StatusOK, "success", responseData))
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Batas jumlah sel agregasi peta agar respons tetap kecil
+const maxClusterCells = 5000
+
+// Struct untuk satu kelompok laporan di peta admin
+type ReportCluster struct {
+	Geohash    string           `json:"geohash"`
+	Count      int64            `json:"count"`
+	Latitude   float64          `json:"latitude"`  // Titik tengah rata-rata laporan di sel
+	Longitude  float64          `json:"longitude"` // Titik tengah rata-rata laporan di sel
+	Bounds     [4]float64       `json:"bounds"`    // min_lat, min_lng, max_lat, max_lng sel
+	ByStatus   map[string]int64 `json:"by_status"`
+	ByCategory map[string]int64 `json:"by_category"`
+}
+
+// Struct untuk satu titik heatmap
+type HeatmapPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int64   `json:"count"`
+	Weight    float64 `json:"weight"`
+	Intensity float64 `json:"intensity"` // Bobot dinormalisasi 0..1 terhadap sel terberat
+}
+
+// clusterPrecisionForZoom memetakan level zoom peta web (0-22) ke presisi geohash,
+// sehingga satu sel kira-kira selebar beberapa puluh piksel di layar
+func clusterPrecisionForZoom(zoom int) int {
+	switch {
+	case zoom <= 2:
+		return 1
+	case zoom <= 4:
+		return 2
+	case zoom <= 7:
+		return 3
+	case zoom <= 9:
+		return 4
+	case zoom <= 12:
+		return 5
+	case zoom <= 14:
+		return 6
+	case zoom <= 16:
+		return 7
+	case zoom <= 18:
+		return 8
+	default:
+		return 9
+	}
+}
+
+// aggregationQuery menyusun query laporan untuk agregasi peta dari filter yang sama dengan daftar laporan
+func aggregationQuery(c echo.Context) (*gorm.DB, *time.Time, *time.Time, error) {
+	box, ok := parseBoundingBoxParams(c)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid bounding box")
+	}
+	start, end, ok := parseDateRangeParams(c)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("invalid date range, use start_date/end_date in YYYY-MM-DD")
+	}
+
+	db := config.DB.Model(&models.ReportRubbish{}).
+		Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", box.MinLat, box.MaxLat, box.MinLng, box.MaxLng).
+		Where("geohash <> '' AND status <> ?", models.ReportStatusMerged)
+	db = applyStatusCategoryFilters(db, c)
+	db = applyAddressFilters(db, c)
+	db = applyDateRange(db, start, end)
+	return db, start, end, nil
+}
+
+// Fungsi untuk mengelompokkan laporan per sel geohash untuk peta admin (mode cluster atau heatmap)
+func GetReportClusters(c echo.Context) error {
+	zoom, err := strconv.Atoi(c.QueryParam("zoom"))
+	if err != nil || zoom < 0 || zoom > 22 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("zoom must be between 0 and 22", http.StatusBadRequest, "error", nil))
+	}
+
+	mode := c.QueryParam("mode")
+	if mode == "" {
+		mode = "clusters"
+	}
+	if mode != "clusters" && mode != "heatmap" {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid mode. Use 'clusters' or 'heatmap'.", http.StatusBadRequest, "error", nil))
+	}
+
+	db, start, end, err := aggregationQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+
+	regionIDs, err := regionFilterIDs(c)
+	if err != nil {
+		return regionFilterErrorResponse(c, err)
+	}
+	if regionIDs != nil {
+		db = db.Where("region_id IN ?", regionIDs)
+	}
+
+	if mode == "heatmap" {
+		return reportHeatmap(c, db, zoom, start, end)
+	}
+
+	precision := clusterPrecisionForZoom(zoom)
+	var rows []struct {
+		Cell     string
+		Status   string
+		Category string
+		Count    int64
+		SumLat   float64
+		SumLng   float64
+	}
+	cell := fmt.Sprintf("LEFT(geohash, %d)", precision)
+	if err := db.Select(cell + " AS cell, status, category, COUNT(*) AS count, SUM(latitude) AS sum_lat, SUM(longitude) AS sum_lng").
+		Group("cell, status, category").
+		Scan(&rows).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to aggregate reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Gabungkan baris per status/kategori menjadi satu kelompok per sel
+	clustersByCell := make(map[string]*ReportCluster)
+	sums := make(map[string][2]float64)
+	var total int64
+	for _, row := range rows {
+		cluster, ok := clustersByCell[row.Cell]
+		if !ok {
+			minLat, minLng, maxLat, maxLng := helper.DecodeGeohashBounds(row.Cell)
+			cluster = &ReportCluster{
+				Geohash:    row.Cell,
+				Bounds:     [4]float64{minLat, minLng, maxLat, maxLng},
+				ByStatus:   map[string]int64{},
+				ByCategory: map[string]int64{},
+			}
+			clustersByCell[row.Cell] = cluster
+		}
+		cluster.Count += row.Count
+		cluster.ByStatus[row.Status] += row.Count
+		cluster.ByCategory[row.Category] += row.Count
+		sum := sums[row.Cell]
+		sums[row.Cell] = [2]float64{sum[0] + row.SumLat, sum[1] + row.SumLng}
+		total += row.Count
+	}
+
+	clusters := make([]ReportCluster, 0, len(clustersByCell))
+	for cellKey, cluster := range clustersByCell {
+		cluster.Latitude = math.Round(sums[cellKey][0]/float64(cluster.Count)*1e6) / 1e6
+		cluster.Longitude = math.Round(sums[cellKey][1]/float64(cluster.Count)*1e6) / 1e6
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		return clusters[i].Geohash < clusters[j].Geohash
+	})
+	truncated := len(clusters) > maxClusterCells
+	if truncated {
+		clusters = clusters[:maxClusterCells]
+	}
+
+	responseData := map[string]interface{}{
+		"mode":          mode,
+		"zoom":          zoom,
+		"precision":     precision,
+		"total_reports": total,
+		"clusters":      clusters,
+		"truncated":     truncated,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Report clusters retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// reportHeatmap menghitung bobot laporan per sel yang lebih halus dari mode cluster.
+// weight=count memberi bobot 1 per laporan; weight=recency memberi bobot lebih besar
+// untuk laporan yang lebih baru di dalam rentang tanggal (minimal 0.1).
+func reportHeatmap(c echo.Context, db *gorm.DB, zoom int, start, end *time.Time) error {
+	weighting := c.QueryParam("weight")
+	if weighting == "" {
+		weighting = "count"
+	}
+	if weighting != "count" && weighting != "recency" {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid weight. Use 'count' or 'recency'.", http.StatusBadRequest, "error", nil))
+	}
+
+	precision := int(math.Min(float64(clusterPrecisionForZoom(zoom)+2), 9))
+	cell := fmt.Sprintf("LEFT(geohash, %d)", precision)
+	weightExpr := "COUNT(*)"
+	var weightArgs []interface{}
+	if weighting == "recency" {
+		// Tanpa rentang tanggal, bobot dihitung untuk 90 hari terakhir
+		rangeEnd := time.Now()
+		if end != nil {
+			rangeEnd = *end
+		}
+		rangeStart := rangeEnd.AddDate(0, 0, -90)
+		if start != nil {
+			rangeStart = *start
+		}
+		span := math.Max(rangeEnd.Sub(rangeStart).Seconds(), 1)
+		weightExpr = "SUM(GREATEST(0.1, LEAST(1, 1 - TIMESTAMPDIFF(SECOND, tanggal_laporan, ?) / ?)))"
+		weightArgs = []interface{}{rangeEnd, span}
+	}
+
+	var rows []struct {
+		Cell   string
+		Count  int64
+		Weight float64
+		AvgLat float64
+		AvgLng float64
+	}
+	if err := db.Select(cell+" AS cell, COUNT(*) AS count, "+weightExpr+" AS weight, AVG(latitude) AS avg_lat, AVG(longitude) AS avg_lng", weightArgs...).
+		Group("cell").
+		Order("weight DESC").
+		Limit(maxClusterCells + 1).
+		Scan(&rows).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to aggregate reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	truncated := len(rows) > maxClusterCells
+	if truncated {
+		rows = rows[:maxClusterCells]
+	}
+
+	maxWeight := 0.0
+	for _, row := range rows {
+		maxWeight = math.Max(maxWeight, row.Weight)
+	}
+	points := make([]HeatmapPoint, 0, len(rows))
+	for _, row := range rows {
+		point := HeatmapPoint{
+			Latitude:  math.Round(row.AvgLat*1e6) / 1e6,
+			Longitude: math.Round(row.AvgLng*1e6) / 1e6,
+			Count:     row.Count,
+			Weight:    math.Round(row.Weight*1000) / 1000,
+		}
+		if maxWeight > 0 {
+			point.Intensity = math.Round(row.Weight/maxWeight*1000) / 1000
+		}
+		points = append(points, point)
+	}
+
+	responseData := map[string]interface{}{
+		"mode":       "heatmap",
+		"zoom":       zoom,
+		"precision":  precision,
+		"weight":     weighting,
+		"max_weight": maxWeight,
+		"points":     points,
+		"truncated":  truncated,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Report heatmap retrieved successfully", http.StatusOK, "success", responseData))
+}
@@ -0,0 +1,488 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/api/uploader"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Nilai bawaan deteksi duplikat, bisa diubah lewat DUPLICATE_RADIUS_M dan DUPLICATE_WINDOW_HOURS
+const (
+	defaultDuplicateRadiusMeters = 50
+	defaultDuplicateWindowHours  = 72
+	maxDuplicateCandidates       = 5
+)
+
+// Status laporan yang masih dianggap terbuka untuk deteksi duplikat dan "me too"
+var openReportStatuses = []string{models.ReportStatusSubmitted, models.ReportStatusInReview, models.ReportStatusApproved}
+
+// Error untuk alur duplikat dan "me too"
+var (
+	ErrInvalidPhotoType        = errors.New("invalid file type, only JPEG and PNG photos are allowed")
+	ErrReportNotOpen           = errors.New("report is no longer open")
+	ErrOwnReportConfirmation   = errors.New("you cannot confirm your own report")
+	ErrAlreadyConfirmed        = errors.New("you have already confirmed this report")
+	ErrInvalidMergeTarget      = errors.New("reports cannot be merged into this report")
+	ErrReportHasMergedChildren = errors.New("report has merged duplicates")
+)
+
+// Struct untuk kandidat laporan duplikat yang ditampilkan ke pelapor
+type DuplicateCandidate struct {
+	ID                uint    `json:"id"`
+	Category          string  `json:"category"`
+	Location          string  `json:"location"`
+	Description       string  `json:"description"`
+	Photo             string  `json:"photo"`
+	Status            string  `json:"status"`
+	TanggalLaporan    string  `json:"tanggal_laporan"`
+	DistanceM         float64 `json:"distance_m"`
+	ConfirmationCount int64   `json:"confirmation_count"`
+}
+
+// Struct untuk respons pelapor tambahan pada sebuah laporan
+type ReportConfirmationResponse struct {
+	ID                 uint    `json:"id"`
+	UserID             uint    `json:"user_id"`
+	NamaLengkap        string  `json:"nama_lengkap"`
+	Source             string  `json:"source"`
+	Photo              string  `json:"photo"`
+	Description        string  `json:"description"`
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	MergedFromReportID *uint   `json:"merged_from_report_id"`
+	CreatedAt          string  `json:"created_at"`
+}
+
+// uploadReportPhoto mengunggah foto laporan ke Cloudinary dan mengembalikan URL-nya
+func uploadReportPhoto(ctx context.Context, file *multipart.FileHeader) (string, error) {
+	url, _, err := uploadReportPhotoWithExif(ctx, file)
+	return url, err
+}
+
+// uploadReportPhotoWithExif membaca metadata EXIF foto lalu mengunggah salinan tanpa GPS,
+// sehingga lokasi rumah pelapor tidak ikut tersebar lewat foto publik
+func uploadReportPhotoWithExif(ctx context.Context, file *multipart.FileHeader) (string, *helper.ExifData, error) {
+	data, exif, err := readReportPhoto(file)
+	if err != nil {
+		return "", nil, err
+	}
+	url, err := uploadReportPhotoData(ctx, data)
+	return url, exif, err
+}
+
+// readReportPhoto memvalidasi tipe file, membaca metadata EXIF foto, lalu mengembalikan salinan
+// tanpa metadata lokasi yang siap diunggah. Format yang tidak bisa dibersihkan ditolak.
+func readReportPhoto(file *multipart.FileHeader) ([]byte, *helper.ExifData, error) {
+	if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
+		return nil, nil, ErrInvalidPhotoType
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open photo file: %w", err)
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read photo file: %w", err)
+	}
+
+	var exif *helper.ExifData
+	if metadata, err := helper.ReadExif(data); err == nil {
+		exif = &metadata
+	}
+	sanitized, err := helper.SanitizeImage(data)
+	if errors.Is(err, helper.ErrUnsupportedImage) {
+		return nil, nil, ErrInvalidPhotoType
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to strip photo metadata: %w", err)
+	}
+	return sanitized, exif, nil
+}
+
+// uploadReportPhotoData mengunggah foto yang sudah dibersihkan readReportPhoto ke Cloudinary
+func uploadReportPhotoData(ctx context.Context, data []byte) (string, error) {
+	cld, err := config.InitCloudinary()
+	if err != nil {
+		return "", fmt.Errorf("cloudinary initialization failed: %w", err)
+	}
+
+	uploadResult, err := cld.Upload.Upload(ctx, bytes.NewReader(data), uploader.UploadParams{
+		Folder: "report_rubbish",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload photo: %w", err)
+	}
+	return uploadResult.SecureURL, nil
+}
+
+// findDuplicateCandidates mencari laporan terbuka dengan kategori sama di sekitar titik
+// dalam rentang waktu deteksi duplikat, diurutkan dari yang terdekat
+func findDuplicateCandidates(lat, lng float64, category string) ([]DuplicateCandidate, error) {
+	radius := config.GetEnvFloat("DUPLICATE_RADIUS_M", defaultDuplicateRadiusMeters)
+	window := config.GetEnvInt("DUPLICATE_WINDOW_HOURS", defaultDuplicateWindowHours)
+	if radius <= 0 || window <= 0 {
+		return nil, nil // Deteksi duplikat dimatikan
+	}
+
+	db := whereWithinRadius(config.DB.Model(&models.ReportRubbish{}), lat, lng, radius).
+		Where("category = ? AND status IN ? AND merged_into_id IS NULL", category, openReportStatuses).
+		Where("created_at >= ?", time.Now().Add(-time.Duration(window)*time.Hour))
+
+	var reports []models.ReportRubbish
+	if err := orderByDistance(db, lat, lng).Limit(maxNearbyCandidates).Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	candidates := []DuplicateCandidate{}
+	for _, report := range reports {
+		distance := helper.HaversineMeters(lat, lng, report.Latitude, report.Longitude)
+		if distance > radius {
+			continue
+		}
+		candidates = append(candidates, DuplicateCandidate{
+			ID:             report.ID,
+			Category:       report.Category,
+			Location:       report.Location,
+			Description:    report.Description,
+			Photo:          report.Photo,
+			Status:         report.Status,
+			TanggalLaporan: report.TanggalLaporan.Format("2006-01-02"),
+			DistanceM:      math.Round(distance*10) / 10,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].DistanceM < candidates[j].DistanceM })
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+
+	for i := range candidates {
+		if err := config.DB.Model(&models.ReportConfirmation{}).
+			Where("report_id = ?", candidates[i].ID).
+			Count(&candidates[i].ConfirmationCount).Error; err != nil {
+			return nil, err
+		}
+	}
+	return candidates, nil
+}
+
+// loadReportConfirmations mengambil pelapor tambahan sebuah laporan beserta fotonya
+func loadReportConfirmations(reportID uint) ([]ReportConfirmationResponse, error) {
+	var confirmations []models.ReportConfirmation
+	if err := config.DB.Preload("User").Where("report_id = ?", reportID).Order("created_at ASC, id ASC").Find(&confirmations).Error; err != nil {
+		return nil, err
+	}
+
+	responses := []ReportConfirmationResponse{}
+	for _, confirmation := range confirmations {
+		responses = append(responses, ReportConfirmationResponse{
+			ID:                 confirmation.ID,
+			UserID:             confirmation.UserID,
+			NamaLengkap:        confirmation.User.NamaLengkap,
+			Source:             confirmation.Source,
+			Photo:              confirmation.Photo,
+			Description:        confirmation.Description,
+			Latitude:           confirmation.Latitude,
+			Longitude:          confirmation.Longitude,
+			MergedFromReportID: confirmation.MergedFromReportID,
+			CreatedAt:          confirmation.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return responses, nil
+}
+
+// creditConfirmersIfApproved memberikan poin pelapor tambahan jika laporan utama sudah memberikan poin
+func creditConfirmersIfApproved(tx *gorm.DB, report models.ReportRubbish, actorID *uint) (int, error) {
+	net, err := reportPointBalance(tx, report.ID)
+	if err != nil || net <= 0 {
+		return 0, err
+	}
+	return grantConfirmationPoints(tx, report, actorID)
+}
+
+// checkReportConfirmable memastikan laporan masih terbuka, bukan milik user, dan belum dikonfirmasi user
+func checkReportConfirmable(tx *gorm.DB, report models.ReportRubbish, userID uint) error {
+	if report.MergedIntoID != nil || !containsStatus(openReportStatuses, report.Status) {
+		return ErrReportNotOpen
+	}
+	if report.UserID == userID {
+		return ErrOwnReportConfirmation
+	}
+
+	var existing int64
+	if err := tx.Model(&models.ReportConfirmation{}).
+		Where("report_id = ? AND user_id = ? AND source = ?", report.ID, userID, models.ConfirmationSourceMeToo).
+		Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return ErrAlreadyConfirmed
+	}
+	return nil
+}
+
+// confirmationErrorResponse memetakan error konfirmasi laporan ke respons HTTP
+func confirmationErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrReportNotOpen), errors.Is(err, ErrOwnReportConfirmation), errors.Is(err, ErrAlreadyConfirmed):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	default:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to confirm report", http.StatusInternalServerError, "error", nil))
+	}
+}
+
+// Fungsi untuk menandai laporan yang sudah ada sebagai tumpukan sampah yang sama ("me too")
+func ConfirmReportRubbish(c echo.Context) error {
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reportID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
+	}
+
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	// Cek laporan sebelum foto diunggah agar konfirmasi yang pasti ditolak tidak menyisakan file
+	var report models.ReportRubbish
+	err = config.DB.First(&report, reportID).Error
+	if err == nil {
+		err = checkReportConfirmable(config.DB, report, userID)
+	}
+	if err != nil {
+		return confirmationErrorResponse(c, err)
+	}
+
+	// Foto pelapor tambahan disimpan di laporan yang sudah ada
+	var photoURL string
+	if file, _ := c.FormFile("photo"); file != nil {
+		photoURL, err = uploadReportPhoto(c.Request().Context(), file)
+		if errors.Is(err, ErrInvalidPhotoType) {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid file type, only JPEG and PNG photos are allowed", http.StatusBadRequest, "error", nil))
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to upload photo", http.StatusInternalServerError, "error", nil))
+		}
+	}
+
+	confirmation := models.ReportConfirmation{
+		ReportID:    uint(reportID),
+		UserID:      userID,
+		Source:      models.ConfirmationSourceMeToo,
+		Photo:       photoURL,
+		Description: c.FormValue("description"),
+	}
+	if lat, err := strconv.ParseFloat(c.FormValue("latitude"), 64); err == nil && lat >= -90 && lat <= 90 {
+		confirmation.Latitude = lat
+	}
+	if lng, err := strconv.ParseFloat(c.FormValue("longitude"), 64); err == nil && lng >= -180 && lng <= 180 {
+		confirmation.Longitude = lng
+	}
+
+	credited := 0
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Cek ulang dengan baris terkunci karena laporan bisa berubah selama foto diunggah
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return err
+		}
+		if err := checkReportConfirmable(tx, report, userID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&confirmation).Error; err != nil {
+			return err
+		}
+
+		// Laporan yang sudah disetujui langsung memberikan poin kepada pelapor tambahan
+		var err error
+		credited, err = creditConfirmersIfApproved(tx, report, nil)
+		return err
+	})
+	if err != nil {
+		return confirmationErrorResponse(c, err)
+	}
+
+	responseData := map[string]interface{}{
+		"id":              confirmation.ID,
+		"report_id":       confirmation.ReportID,
+		"photo":           confirmation.Photo,
+		"description":     confirmation.Description,
+		"points_credited": credited > 0,
+	}
+	return c.JSON(http.StatusCreated, helper.APIResponse("Report confirmed successfully", http.StatusCreated, "success", responseData))
+}
+
+// Fungsi untuk menggabungkan laporan duplikat ke satu laporan utama (admin)
+func MergeReportRubbish(c echo.Context) error {
+	canonicalID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || canonicalID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
+	}
+
+	input := struct {
+		DuplicateIDs []uint `json:"duplicate_ids" validate:"required,min=1,dive,required"`
+		Reason       string `json:"reason"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	// Buang ID ganda dan laporan utama itu sendiri
+	seen := map[uint]bool{uint(canonicalID): true}
+	duplicateIDs := []uint{}
+	for _, id := range input.DuplicateIDs {
+		if !seen[id] {
+			seen[id] = true
+			duplicateIDs = append(duplicateIDs, id)
+		}
+	}
+	if len(duplicateIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("duplicate_ids must contain reports other than the target", http.StatusBadRequest, "error", nil))
+	}
+	// Urutkan agar penguncian baris selalu berurutan dan tidak saling menunggu
+	sort.Slice(duplicateIDs, func(i, j int) bool { return duplicateIDs[i] < duplicateIDs[j] })
+
+	adminID, _ := c.Get("userID").(uint)
+	permissions, _ := c.Get("userPermissions").([]string)
+
+	var canonical models.ReportRubbish
+	credited := 0
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&canonical, canonicalID).Error; err != nil {
+			return err
+		}
+		if canonical.MergedIntoID != nil || canonical.Status == models.ReportStatusMerged || canonical.Status == models.ReportStatusRejected {
+			return ErrInvalidMergeTarget
+		}
+
+		for _, duplicateID := range duplicateIDs {
+			var duplicate models.ReportRubbish
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&duplicate, duplicateID).Error; err != nil {
+				return err
+			}
+
+			reason := input.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("merged into report #%d", canonical.ID)
+			}
+			if err := models.CheckReportTransition(duplicate, models.ReportStatusMerged, permissions, reason); err != nil {
+				return err
+			}
+
+			// Foto dan deskripsi pelapor duplikat disimpan di laporan utama
+			mergedFrom := duplicate.ID
+			if err := tx.Create(&models.ReportConfirmation{
+				ReportID:           canonical.ID,
+				UserID:             duplicate.UserID,
+				Source:             models.ConfirmationSourceMerged,
+				Photo:              duplicate.Photo,
+				Description:        duplicate.Description,
+				Latitude:           duplicate.Latitude,
+				Longitude:          duplicate.Longitude,
+				MergedFromReportID: &mergedFrom,
+				CreatedAt:          duplicate.CreatedAt,
+			}).Error; err != nil {
+				return err
+			}
+
+			// Galeri foto duplikat disalin ke akhir galeri laporan utama
+			flagged, err := copyReportPhotos(tx, duplicate, canonical)
+			if err != nil {
+				return err
+			}
+			if flagged && !canonical.PhotoFlagged {
+				canonical.PhotoFlagged = true
+				if err := tx.Model(&canonical).Update("photo_flagged", true).Error; err != nil {
+					return err
+				}
+			}
+
+			// Pelapor tambahan dari laporan duplikat ikut dipindahkan
+			if err := tx.Model(&models.ReportConfirmation{}).
+				Where("report_id = ? AND merged_from_report_id IS NULL", duplicate.ID).
+				Update("merged_from_report_id", duplicate.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.ReportConfirmation{}).
+				Where("report_id = ?", duplicate.ID).
+				Update("report_id", canonical.ID).Error; err != nil {
+				return err
+			}
+
+			// Poin yang sudah diterima dari laporan duplikat tetap dipegang pelapornya
+			fromStatus := duplicate.Status
+			duplicate.Status = models.ReportStatusMerged
+			duplicate.MergedIntoID = &canonical.ID
+			if err := tx.Save(&duplicate).Error; err != nil {
+				return err
+			}
+			if err := recordStatusChange(tx, duplicate.ID, fromStatus, duplicate.Status, adminID, reason, ""); err != nil {
+				return err
+			}
+			if err := cancelActiveAssignments(tx, duplicate.ID, &adminID, fmt.Sprintf("merged into report #%d", canonical.ID)); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		credited, err = creditConfirmersIfApproved(tx, canonical, &adminID)
+		return err
+	})
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrInvalidMergeTarget):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case errors.As(err, &transitionErr):
+		return transitionErrorResponse(c, transitionErr)
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to merge reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	confirmations, err := loadReportConfirmations(canonical.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report confirmations", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := map[string]interface{}{
+		"id":                canonical.ID,
+		"merged_report_ids": duplicateIDs,
+		"confirmations":     confirmations,
+		"users_credited":    credited,
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Reports merged successfully", http.StatusOK, "success", responseData))
+}
+
+// containsStatus memeriksa apakah status ada di dalam daftar
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
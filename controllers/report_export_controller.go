@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Jumlah fitur yang ditulis sebelum buffer dikirim ke client
+const exportFlushEvery = 500
+
+// Properti laporan yang disertakan di setiap fitur ekspor GIS
+type reportExportProperties struct {
+	ID             uint     `json:"id"`
+	Status         string   `json:"status"`
+	Category       string   `json:"category"`
+	Description    string   `json:"description"`
+	Location       string   `json:"location"`
+	Street         string   `json:"street"`
+	Kelurahan      string   `json:"kelurahan"`
+	Kecamatan      string   `json:"kecamatan"`
+	City           string   `json:"city"`
+	Province       string   `json:"province"`
+	RegionID       *uint    `json:"region_id"`
+	TanggalLaporan string   `json:"tanggal_laporan"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	PhotoURL       string   `json:"photo_url"`
+	AccuracyM      *float64 `json:"accuracy_m"`
+
+	PhotoURLs []string `json:"photo_urls"` // Semua foto galeri sesuai urutan
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         uint                   `json:"id"`
+	Geometry   *geoJSONPoint          `json:"geometry"` // null untuk laporan tanpa koordinat
+	Properties reportExportProperties `json:"properties"`
+}
+
+func toReportExportProperties(report models.ReportRubbish) reportExportProperties {
+	return reportExportProperties{
+		ID:             report.ID,
+		Status:         report.Status,
+		Category:       report.Category,
+		Description:    report.Description,
+		Location:       report.Location,
+		Street:         report.Street,
+		Kelurahan:      report.Kelurahan,
+		Kecamatan:      report.Kecamatan,
+		City:           report.City,
+		Province:       report.Province,
+		RegionID:       report.RegionID,
+		TanggalLaporan: report.TanggalLaporan.Format("2006-01-02"),
+		CreatedAt:      report.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      report.UpdatedAt.Format(time.RFC3339),
+		PhotoURL:       report.Photo,
+		AccuracyM:      report.AccuracyM,
+		PhotoURLs:      reportPhotoURLs(report),
+	}
+}
+
+// reportPhotoURLs mengembalikan URL semua foto galeri. Laporan lama tanpa galeri memakai foto sampulnya.
+func reportPhotoURLs(report models.ReportRubbish) []string {
+	urls := []string{}
+	for _, photo := range report.Photos {
+		urls = append(urls, photo.URL)
+	}
+	if len(urls) == 0 && report.Photo != "" {
+		urls = append(urls, report.Photo)
+	}
+	return urls
+}
+
+// loadExportPhotos memuat galeri untuk satu batch laporan dengan satu query
+func loadExportPhotos(reports []models.ReportRubbish) error {
+	ids := make([]uint, len(reports))
+	for i, report := range reports {
+		ids[i] = report.ID
+	}
+	var photos []models.ReportPhoto
+	if err := config.DB.Where("report_id IN ?", ids).Order("report_id ASC, position ASC, id ASC").Find(&photos).Error; err != nil {
+		return err
+	}
+	byReport := make(map[uint][]models.ReportPhoto, len(reports))
+	for _, photo := range photos {
+		byReport[photo.ReportID] = append(byReport[photo.ReportID], photo)
+	}
+	for i := range reports {
+		reports[i].Photos = byReport[reports[i].ID]
+	}
+	return nil
+}
+
+// Fungsi untuk mengekspor laporan sebagai GeoJSON FeatureCollection atau KML.
+// Laporan dibaca baris per baris dari database dan langsung ditulis ke respons,
+// sehingga ekspor besar tidak dimuat seluruhnya ke memori.
+func ExportReportsGIS(c echo.Context) error {
+	format := c.Param("format")
+	if format != "geojson" && format != "kml" {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid format. Use 'geojson' or 'kml'.", http.StatusBadRequest, "error", nil))
+	}
+
+	start, end, ok := parseDateRangeParams(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid date range, use start_date/end_date in YYYY-MM-DD", http.StatusBadRequest, "error", nil))
+	}
+
+	db, err := applyReportListFilters(config.DB.Model(&models.ReportRubbish{}), c)
+	if err != nil {
+		return regionFilterErrorResponse(c, err)
+	}
+	db = applyDateRange(db, start, end).Where("status <> ?", models.ReportStatusMerged)
+
+	rows, err := db.Order("tanggal_laporan ASC, id ASC").Rows()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to export reports", http.StatusInternalServerError, "error", nil))
+	}
+	defer rows.Close()
+
+	// Setelah header dikirim, error hanya bisa dicatat di log
+	filename := fmt.Sprintf("reports-%s.%s", time.Now().Format("20060102-150405"), format)
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	if format == "kml" {
+		response.Header().Set(echo.HeaderContentType, "application/vnd.google-earth.kml+xml")
+	} else {
+		response.Header().Set(echo.HeaderContentType, "application/geo+json")
+	}
+	response.WriteHeader(http.StatusOK)
+
+	writer := bufio.NewWriter(response)
+	var writeErr error
+	if format == "kml" {
+		writeErr = writeKMLHeader(writer)
+	} else {
+		_, writeErr = writer.WriteString(`{"type":"FeatureCollection","features":[`)
+	}
+
+	// Laporan ditulis per batch agar galeri foto bisa dimuat sekaligus untuk setiap batch
+	count := 0
+	batch := make([]models.ReportRubbish, 0, exportFlushEvery)
+	writeBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := loadExportPhotos(batch); err != nil {
+			return err
+		}
+		for _, report := range batch {
+			var err error
+			if format == "kml" {
+				err = writeKMLPlacemark(writer, report)
+			} else {
+				err = writeGeoJSONFeature(writer, report, count == 0)
+			}
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		batch = batch[:0]
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	}
+
+	for writeErr == nil && rows.Next() {
+		var report models.ReportRubbish
+		if err := config.DB.ScanRows(rows, &report); err != nil {
+			writeErr = err
+			break
+		}
+		batch = append(batch, report)
+		if len(batch) == exportFlushEvery {
+			writeErr = writeBatch()
+		}
+	}
+	if writeErr == nil {
+		writeErr = rows.Err()
+	}
+	if writeErr == nil {
+		writeErr = writeBatch()
+	}
+
+	if writeErr == nil {
+		if format == "kml" {
+			_, writeErr = writer.WriteString("</Document>\n</kml>\n")
+		} else {
+			_, writeErr = writer.WriteString("]}\n")
+		}
+	}
+	if writeErr == nil {
+		writeErr = writer.Flush()
+	}
+	if writeErr != nil {
+		log.Printf("Report %s export aborted after %d reports: %v", format, count, writeErr)
+	}
+	return nil
+}
+
+// writeGeoJSONFeature menulis satu laporan sebagai fitur GeoJSON Point
+func writeGeoJSONFeature(w *bufio.Writer, report models.ReportRubbish, first bool) error {
+	feature := geoJSONFeature{
+		Type:       "Feature",
+		ID:         report.ID,
+		Properties: toReportExportProperties(report),
+	}
+	if report.Latitude != 0 || report.Longitude != 0 {
+		feature.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{report.Longitude, report.Latitude}}
+	}
+
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+	if !first {
+		if err := w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeKMLHeader(w *bufio.Writer) error {
+	_, err := w.WriteString(xml.Header + `<kml xmlns="http://www.opengis.net/kml/2.2">` + "\n<Document>\n<name>Recything reports</name>\n")
+	return err
+}
+
+// writeKMLPlacemark menulis satu laporan sebagai Placemark KML dengan ExtendedData
+func writeKMLPlacemark(w *bufio.Writer, report models.ReportRubbish) error {
+	properties := toReportExportProperties(report)
+	fields := [][2]string{
+		{"id", strconv.FormatUint(uint64(properties.ID), 10)},
+		{"status", properties.Status},
+		{"category", properties.Category},
+		{"location", properties.Location},
+		{"street", properties.Street},
+		{"kelurahan", properties.Kelurahan},
+		{"kecamatan", properties.Kecamatan},
+		{"city", properties.City},
+		{"province", properties.Province},
+		{"tanggal_laporan", properties.TanggalLaporan},
+		{"created_at", properties.CreatedAt},
+		{"updated_at", properties.UpdatedAt},
+		{"photo_url", properties.PhotoURL},
+		{"photo_urls", strings.Join(properties.PhotoURLs, "\n")},
+	}
+	if properties.RegionID != nil {
+		fields = append(fields, [2]string{"region_id", strconv.FormatUint(uint64(*properties.RegionID), 10)})
+	}
+
+	if _, err := fmt.Fprintf(w, "<Placemark id=\"report-%d\">\n<name>Report #%d</name>\n<description>", report.ID, report.ID); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(w, []byte(report.Description)); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("</description>\n<ExtendedData>\n"); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := writeKMLData(w, field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	if _, err := w.WriteString("</ExtendedData>\n"); err != nil {
+		return err
+	}
+	if report.Latitude != 0 || report.Longitude != 0 {
+		if _, err := fmt.Fprintf(w, "<Point><coordinates>%s,%s</coordinates></Point>\n",
+			strconv.FormatFloat(report.Longitude, 'f', -1, 64), strconv.FormatFloat(report.Latitude, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString("</Placemark>\n")
+	return err
+}
+
+func writeKMLData(w io.Writer, name string, value string) error {
+	if _, err := fmt.Fprintf(w, "<Data name=%q><value>", name); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(w, []byte(value)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</value></Data>\n")
+	return err
+}
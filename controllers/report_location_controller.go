@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Batas pencarian lokasi agar query tetap ringan
+const (
+	defaultNearbyRadiusMeters = 1000
+	maxNearbyRadiusMeters     = 50000
+	maxNearbyCandidates       = 5000
+	defaultBBoxLimit          = 500
+	maxBBoxLimit              = 2000
+	metersPerDegreeLat        = 111320.0
+)
+
+// Struct untuk respons laporan beserta jaraknya dari titik pencarian.
+// Field User menimpa ReportResponse.User agar kontak dan tanggal lahir pelapor
+// tidak terlihat oleh user lain yang menjelajah peta.
+type NearbyReportResponse struct {
+	ReportResponse
+	User      ReporterResponse `json:"user"`
+	DistanceM *float64         `json:"distance_m,omitempty"`
+}
+
+// Struct untuk identitas publik pelapor tanpa email, nomor telepon, dan tanggal lahir
+type ReporterResponse struct {
+	IDUser      uint   `json:"id_user"`
+	NamaLengkap string `json:"nama_lengkap"`
+	Photo       string `json:"photo"`
+}
+
+// Fungsi untuk memetakan laporan ke respons peta yang hanya memuat identitas publik pelapor
+func toNearbyReportResponse(report models.ReportRubbish) NearbyReportResponse {
+	response := toReportResponse(report)
+	response.User = UserResponse{}
+	return NearbyReportResponse{
+		ReportResponse: response,
+		User: ReporterResponse{
+			IDUser:      report.User.ID,
+			NamaLengkap: report.User.NamaLengkap,
+			Photo:       report.User.Photo,
+		},
+	}
+}
+
+// Fungsi untuk menerapkan filter status dan kategori (boleh dipisah koma)
+func applyStatusCategoryFilters(db *gorm.DB, c echo.Context) *gorm.DB {
+	if status := c.QueryParam("status"); status != "" {
+		db = db.Where("status IN ?", strings.Split(status, ","))
+	}
+	if category := c.QueryParam("category"); category != "" {
+		db = db.Where("category IN ?", strings.Split(category, ","))
+	}
+	return db
+}
+
+// Fungsi untuk menerapkan filter wilayah administratif hasil reverse geocoding
+func applyAddressFilters(db *gorm.DB, c echo.Context) *gorm.DB {
+	for _, column := range []string{"kelurahan", "kecamatan", "city", "province"} {
+		if value := c.QueryParam(column); value != "" {
+			db = db.Where(column+" = ?", value)
+		}
+	}
+	return db
+}
+
+// Fungsi untuk membaca parameter koordinat dari query dan memvalidasi rentangnya
+func parseCoordinateParam(c echo.Context, name string, min float64, max float64) (float64, bool) {
+	value, err := strconv.ParseFloat(c.QueryParam(name), 64)
+	if err != nil || math.IsNaN(value) || value < min || value > max {
+		return 0, false
+	}
+	return value, true
+}
+
+// whereWithinRadius membatasi query laporan ke kandidat di sekitar titik.
+// Sel geohash pusat dan 8 tetangganya mencakup seluruh radius pencarian,
+// sehingga query memakai index geohash alih-alih memindai seluruh tabel.
+// Jarak sebenarnya tetap harus dicek dengan helper.HaversineMeters.
+func whereWithinRadius(db *gorm.DB, lat, lng, radius float64) *gorm.DB {
+	precision := helper.GeohashPrecisionForRadius(lat, radius)
+	cells := helper.GeohashNeighbors(helper.EncodeGeohash(lat, lng, precision))
+
+	cellQuery := config.DB
+	for i, cell := range cells {
+		if i == 0 {
+			cellQuery = cellQuery.Where("geohash LIKE ?", cell+"%")
+		} else {
+			cellQuery = cellQuery.Or("geohash LIKE ?", cell+"%")
+		}
+	}
+	db = db.Where(cellQuery)
+
+	// Persempit dengan kotak pembatas radius
+	dLat := radius / metersPerDegreeLat
+	dLng := radius / (metersPerDegreeLat * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	return db.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", lat-dLat, lat+dLat, lng-dLng, lng+dLng)
+}
+
+// orderByDistance mengurutkan laporan dari yang terdekat ke titik memakai jarak kuadrat equirectangular,
+// sehingga LIMIT di SQL memotong kandidat terjauh dan bukan kandidat acak
+func orderByDistance(db *gorm.DB, lat, lng float64) *gorm.DB {
+	scale := math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "POW(latitude - ?, 2) + POW((longitude - ?) * ?, 2)",
+		Vars: []interface{}{lat, lng, scale},
+	}})
+}
+
+// Fungsi untuk membaca kotak pembatas min_lat/min_lng/max_lat/max_lng dari query
+func parseBoundingBoxParams(c echo.Context) (helper.BoundingBox, bool) {
+	minLat, ok1 := parseCoordinateParam(c, "min_lat", -90, 90)
+	minLng, ok2 := parseCoordinateParam(c, "min_lng", -180, 180)
+	maxLat, ok3 := parseCoordinateParam(c, "max_lat", -90, 90)
+	maxLng, ok4 := parseCoordinateParam(c, "max_lng", -180, 180)
+	if !ok1 || !ok2 || !ok3 || !ok4 || minLat > maxLat || minLng > maxLng {
+		return helper.BoundingBox{}, false
+	}
+	return helper.BoundingBox{MinLat: minLat, MinLng: minLng, MaxLat: maxLat, MaxLng: maxLng}, true
+}
+
+// Fungsi untuk membaca rentang tanggal start_date/end_date (YYYY-MM-DD) dari query.
+// end_date bersifat inklusif sehingga batas atasnya adalah awal hari berikutnya.
+func parseDateRangeParams(c echo.Context) (*time.Time, *time.Time, bool) {
+	return parseDateRangeParamsIn(c, time.Local)
+}
+
+// parseDateRangeParamsIn sama seperti parseDateRangeParams, tetapi tanggal dibaca di zona waktu loc
+func parseDateRangeParamsIn(c echo.Context, loc *time.Location) (*time.Time, *time.Time, bool) {
+	var start, end *time.Time
+	if value := c.QueryParam("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			return nil, nil, false
+		}
+		start = &t
+	}
+	if value := c.QueryParam("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, loc)
+		if err != nil {
+			return nil, nil, false
+		}
+		t = t.AddDate(0, 0, 1)
+		end = &t
+	}
+	if start != nil && end != nil && !start.Before(*end) {
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+// Fungsi untuk menerapkan rentang tanggal laporan ke query
+func applyDateRange(db *gorm.DB, start, end *time.Time) *gorm.DB {
+	if start != nil {
+		db = db.Where("tanggal_laporan >= ?", *start)
+	}
+	if end != nil {
+		db = db.Where("tanggal_laporan < ?", *end)
+	}
+	return db
+}
+
+// Fungsi untuk mencari laporan dalam radius tertentu dari sebuah titik
+func GetNearbyReports(c echo.Context) error {
+	lat, okLat := parseCoordinateParam(c, "lat", -90, 90)
+	lng, okLng := parseCoordinateParam(c, "lng", -180, 180)
+	if !okLat || !okLng {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid or missing lat/lng", http.StatusBadRequest, "error", nil))
+	}
+
+	radius := float64(defaultNearbyRadiusMeters)
+	if radiusParam := c.QueryParam("radius_m"); radiusParam != "" {
+		r, err := strconv.ParseFloat(radiusParam, 64)
+		if err != nil || r <= 0 || r > maxNearbyRadiusMeters {
+			return c.JSON(http.StatusBadRequest, helper.APIResponse("radius_m must be between 1 and 50000", http.StatusBadRequest, "error", nil))
+		}
+		radius = r
+	}
+
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+
+	db := whereWithinRadius(config.DB.Model(&models.ReportRubbish{}), lat, lng, radius)
+	db = applyStatusCategoryFilters(db, c)
+	db = applyAddressFilters(db, c)
+
+	var reports []models.ReportRubbish
+	if err := orderByDistance(db, lat, lng).Preload("User").Preload("Photos", orderedReportPhotos).Limit(maxNearbyCandidates).Find(&reports).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to search nearby reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Hitung jarak sebenarnya lalu urutkan dari yang terdekat
+	results := []NearbyReportResponse{}
+	for _, report := range reports {
+		distance := helper.HaversineMeters(lat, lng, report.Latitude, report.Longitude)
+		if distance > radius {
+			continue
+		}
+		distance = math.Round(distance*10) / 10
+		item := toNearbyReportResponse(report)
+		item.DistanceM = &distance
+		results = append(results, item)
+	}
+	sort.Slice(results, func(i, j int) bool { return *results[i].DistanceM < *results[j].DistanceM })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	responseData := map[string]interface{}{
+		"center":   map[string]float64{"lat": lat, "lng": lng},
+		"radius_m": radius,
+		"items":    results,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Nearby reports retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi untuk mengambil laporan di dalam kotak peta (viewport)
+func GetReportsInBoundingBox(c echo.Context) error {
+	box, ok := parseBoundingBoxParams(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid bounding box", http.StatusBadRequest, "error", nil))
+	}
+	minLat, minLng, maxLat, maxLng := box.MinLat, box.MinLng, box.MaxLat, box.MaxLng
+
+	limit := defaultBBoxLimit
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 && l <= maxBBoxLimit {
+		limit = l
+	}
+
+	// Titik acuan jarak bersifat opsional
+	refLat, okRefLat := parseCoordinateParam(c, "lat", -90, 90)
+	refLng, okRefLng := parseCoordinateParam(c, "lng", -180, 180)
+	hasRef := okRefLat && okRefLng
+
+	// Query memakai index gabungan (latitude, longitude)
+	db := config.DB.Model(&models.ReportRubbish{}).
+		Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng)
+	db = applyStatusCategoryFilters(db, c)
+	db = applyAddressFilters(db, c)
+
+	// Ambil satu baris lebih banyak untuk mengetahui apakah hasil terpotong
+	var reports []models.ReportRubbish
+	if err := db.Preload("User").Preload("Photos", orderedReportPhotos).Order("tanggal_laporan DESC").Limit(limit + 1).Find(&reports).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to search reports", http.StatusInternalServerError, "error", nil))
+	}
+
+	truncated := len(reports) > limit
+	if truncated {
+		reports = reports[:limit]
+	}
+
+	results := []NearbyReportResponse{}
+	for _, report := range reports {
+		item := toNearbyReportResponse(report)
+		if hasRef {
+			distance := math.Round(helper.HaversineMeters(refLat, refLng, report.Latitude, report.Longitude)*10) / 10
+			item.DistanceM = &distance
+		}
+		results = append(results, item)
+	}
+
+	responseData := map[string]interface{}{
+		"bbox":      []float64{minLat, minLng, maxLat, maxLng},
+		"items":     results,
+		"truncated": truncated,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Reports retrieved successfully", http.StatusOK, "success", responseData))
+}
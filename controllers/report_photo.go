@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Jumlah foto bawaan per laporan, bisa diubah lewat REPORT_MAX_PHOTOS
+const defaultMaxReportPhotos = 5
+
+// Error untuk galeri foto laporan
+var (
+	ErrTooManyPhotos       = errors.New("too many photos")
+	ErrPhotoCaptionTooLong = errors.New("photo caption is too long")
+)
+
+// Struct untuk respons satu foto di galeri laporan
+type ReportPhotoResponse struct {
+	ID       uint   `json:"id"`
+	URL      string `json:"url"`
+	Caption  string `json:"caption"`
+	Position int    `json:"position"`
+}
+
+// maxReportPhotos mengembalikan batas jumlah foto per laporan
+func maxReportPhotos() int {
+	limit := config.GetEnvInt("REPORT_MAX_PHOTOS", defaultMaxReportPhotos)
+	if limit < 1 {
+		return defaultMaxReportPhotos
+	}
+	return limit
+}
+
+// orderedReportPhotos dipakai untuk Preload("Photos", ...) agar galeri selalu urut
+func orderedReportPhotos(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+func toReportPhotoResponses(photos []models.ReportPhoto) []ReportPhotoResponse {
+	responses := []ReportPhotoResponse{}
+	for _, photo := range photos {
+		responses = append(responses, ReportPhotoResponse{
+			ID:       photo.ID,
+			URL:      photo.URL,
+			Caption:  photo.Caption,
+			Position: photo.Position,
+		})
+	}
+	return responses
+}
+
+// uploadReportPhotos mengunggah semua bagian "photo" dari form multipart sesuai urutan kirim.
+// Keterangan foto dibaca dari bagian "caption" dengan urutan yang sama dan boleh dikosongkan.
+// Semua file divalidasi dulu sebelum ada yang diunggah.
+func uploadReportPhotos(c echo.Context) ([]models.ReportPhoto, error) {
+	form, err := c.MultipartForm()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart form: %w", err)
+	}
+
+	files := form.File["photo"]
+	captions := form.Value["caption"]
+	if len(files) > maxReportPhotos() {
+		return nil, ErrTooManyPhotos
+	}
+	for _, caption := range captions {
+		if utf8.RuneCountInString(strings.TrimSpace(caption)) > 255 {
+			return nil, ErrPhotoCaptionTooLong
+		}
+	}
+	contents := make([][]byte, len(files))
+	exifs := make([]*helper.ExifData, len(files))
+	for i, file := range files {
+		if contents[i], exifs[i], err = readReportPhoto(file); err != nil {
+			return nil, err
+		}
+	}
+
+	photos := make([]models.ReportPhoto, 0, len(files))
+	for i := range files {
+		url, err := uploadReportPhotoData(c.Request().Context(), contents[i])
+		if err != nil {
+			return nil, err
+		}
+		exif := exifs[i]
+		photo := models.ReportPhoto{URL: url, Position: i}
+		if exif != nil {
+			photo.ExifTakenAt = exif.TakenAt
+			photo.ExifLatitude = exif.Latitude
+			photo.ExifLongitude = exif.Longitude
+			photo.ExifCamera = truncateRunes(exif.Camera, 100)
+		}
+		if i < len(captions) {
+			photo.Caption = strings.TrimSpace(captions[i])
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+// copyReportPhotos menyalin galeri foto laporan "from" ke akhir galeri laporan "to".
+// Keaslian salinan dicek ulang terhadap lokasi dan tanggal laporan tujuan;
+// mengembalikan true jika ada salinan yang ditandai.
+func copyReportPhotos(tx *gorm.DB, from models.ReportRubbish, to models.ReportRubbish) (bool, error) {
+	var photos []models.ReportPhoto
+	if err := orderedReportPhotos(tx.Where("report_id = ?", from.ID)).Find(&photos).Error; err != nil || len(photos) == 0 {
+		return false, err
+	}
+
+	var last int
+	if err := tx.Model(&models.ReportPhoto{}).Where("report_id = ?", to.ID).Select("COALESCE(MAX(position), -1)").Scan(&last).Error; err != nil {
+		return false, err
+	}
+
+	flagged := false
+	for i := range photos {
+		photos[i].ID = 0
+		photos[i].ReportID = to.ID
+		photos[i].Position = last + 1 + i
+		photos[i].ExifDistanceM = nil
+		if checkPhotoAuthenticity(&photos[i], to) {
+			flagged = true
+		}
+	}
+	return flagged, tx.Create(&photos).Error
+}
@@ -5,27 +5,43 @@ import (
 	"Backend-Recything/helper"
 	"Backend-Recything/models"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"encoding/json"
-	"net/url"
+	"errors"
 
-	"github.com/cloudinary/cloudinary-go/api/uploader"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Struct untuk input laporan
 type ReportInput struct {
-	Category       string `form:"category" validate:"required,oneof=report_rubbish report_littering"`
-	Location       string `form:"location" validate:"required"` // Menggunakan alamat untuk mendapatkan latitude dan longitude
-	Description    string `form:"description" validate:"required"`
-	Photo          string `form:"photo"`
-	Status         string `form:"status"`
-	TanggalLaporan string `form:"tanggal_laporan" validate:"required"`
+	Category       string   `form:"category" validate:"required,oneof=report_rubbish report_littering"`
+	Location       string   `form:"location" validate:"required_without=Latitude"` // Alamat, dipakai untuk geocoding jika GPS tidak dikirim
+	Latitude       *float64 `form:"latitude" validate:"omitempty,gte=-90,lte=90"`  // Koordinat GPS perangkat
+	Longitude      *float64 `form:"longitude" validate:"omitempty,gte=-180,lte=180"`
+	Accuracy       *float64 `form:"accuracy" validate:"omitempty,gte=0"` // Akurasi GPS dalam meter
+	Description    string   `form:"description" validate:"required"`
+	Photo          string   `form:"photo"`
+	TanggalLaporan string   `form:"tanggal_laporan" validate:"required"`
+	ConfirmNew     bool     `form:"confirm_new"` // Tetap buat laporan baru meskipun ada laporan serupa di dekatnya
+}
+
+// Struct untuk alamat terstruktur laporan
+type ReportAddressResponse struct {
+	Street         string   `json:"street"`
+	Kelurahan      string   `json:"kelurahan"`
+	Kecamatan      string   `json:"kecamatan"`
+	City           string   `json:"city"`
+	Province       string   `json:"province"`
+	LocationSource string   `json:"location_source"`
+	AccuracyM      *float64 `json:"accuracy_m"`
+	RegionID       *uint    `json:"region_id"`
 }
 
 // Struct untuk respons laporan
@@ -36,65 +52,30 @@ type ReportResponse struct {
 	TanggalLaporan string       `json:"tanggal_laporan"`
 	Location       string       `json:"location"`
 	Description    string       `json:"description"`
-	Photo          string       `json:"photo"`
+	Photo          string       `json:"photo"` // Foto sampul (foto pertama di galeri)
 	Status         string       `json:"status"`
 	Longitude      float64      `json:"longitude"`
 	Latitude       float64      `json:"latitude"`
 	User           UserResponse `json:"user"`
-}
 
-type DurationData struct {
-	Duration     int   `json:"duration"`      // Duration in months
-	ReportCounts []int `json:"report_counts"` // Count of reports per month
-	UserCounts   []int `json:"user_counts"`   // Count of users per month
+	Photos        []ReportPhotoResponse        `json:"photos"`                   // Galeri foto laporan sesuai urutan
+	Timeline      []ReportStatusChangeResponse `json:"timeline,omitempty"`       // Riwayat perubahan status
+	MergedIntoID  *uint                        `json:"merged_into_id,omitempty"` // Laporan utama jika laporan ini duplikat
+	Confirmations []ReportConfirmationResponse `json:"confirmations,omitempty"`  // Pelapor tambahan beserta fotonya
+	Address       *ReportAddressResponse       `json:"address,omitempty"`        // Alamat hasil reverse geocoding
+	AfterPhoto    string                       `json:"after_photo,omitempty"`    // Foto setelah dibersihkan yang sudah diverifikasi
+	CleanedByID   *uint                        `json:"cleaned_by_id,omitempty"`
+	Cleanups      []ReportCleanupResponse      `json:"cleanups,omitempty"`     // Bukti pembersihan yang pernah diajukan
+	Assignments   []ReportAssignmentResponse   `json:"assignments,omitempty"`  // Penugasan tim kebersihan beserta langkah-langkahnya
+	Authenticity  *ReportAuthenticityResponse  `json:"authenticity,omitempty"` // Metadata EXIF dan hasil cek keaslian foto (admin)
 }
 
-// Struktur untuk respons dari HERE API
-type HereGeocodeResponse struct {
-	Items []struct {
-		Position struct {
-			Latitude  float64 `json:"lat"`
-			Longitude float64 `json:"lng"`
-		} `json:"position"`
-	} `json:"items"`
-}
-
-// Fungsi untuk mendapatkan koordinat (latitude, longitude) dari alamat menggunakan HERE API
-func getCoordinatesFromAddress(address string) (float64, float64, error) {
-	apiKey := os.Getenv("HERE_API_KEY")
-	baseURL := os.Getenv("HERE_BASE_URL")
-
-	// Membuat URL request dengan parameter alamat dan API key
-	requestURL := fmt.Sprintf("%s?q=%s&apiKey=%s", baseURL, url.QueryEscape(address), apiKey)
-
-	// Mengirimkan request ke HERE API
-	resp, err := http.Get(requestURL)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to make request to HERE API: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Mengecek jika status kode bukan 200 OK
-	if resp.StatusCode != http.StatusOK {
-		return 0, 0, fmt.Errorf("error: received status code %d from HERE API", resp.StatusCode)
-	}
-
-	// Parsing JSON response dari HERE API
-	var geocodeResponse HereGeocodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geocodeResponse); err != nil {
-		return 0, 0, fmt.Errorf("failed to parse JSON response: %v", err)
-	}
-
-	// Memeriksa apakah ada hasil yang ditemukan
-	if len(geocodeResponse.Items) == 0 {
-		return 0, 0, fmt.Errorf("no results found for the address")
-	}
-
-	// Mendapatkan latitude dan longitude dari hasil pertama
-	latitude := geocodeResponse.Items[0].Position.Latitude
-	longitude := geocodeResponse.Items[0].Position.Longitude
-
-	return latitude, longitude, nil
+type DurationData struct {
+	Duration       int     `json:"duration"`        // Duration in months
+	ReportCounts   []int   `json:"report_counts"`   // Count of reports per month
+	UserCounts     []int   `json:"user_counts"`     // Count of users per month
+	ResolvedCounts []int   `json:"resolved_counts"` // Count of reports with a verified cleanup per month
+	ResolutionRate float64 `json:"resolution_rate"` // Verified cleanups / (approved + cleaned) reports in the duration
 }
 
 // Fungsi untuk membuat laporan baru
@@ -106,76 +87,148 @@ func CreateReportRubbish(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input", http.StatusBadRequest, "error", nil))
 	}
 
+	// Validate input
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
 	// Get user ID from context
 	userID, ok := c.Get("userID").(uint)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
 	}
 
-	// Handle photo upload if provided
-	var photoURL string
-	file, err := c.FormFile("photo")
-	if file != nil {
-		src, err := file.Open()
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to open photo file", http.StatusInternalServerError, "error", nil))
-		}
-		defer src.Close()
+	// GPS coordinates must come as a pair
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("latitude and longitude must be provided together", http.StatusBadRequest, "error", nil))
+	}
 
-		if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
-			return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid file type, only images are allowed", http.StatusBadRequest, "error", nil))
-		}
+	// Device GPS is preferred; otherwise the typed address is geocoded.
+	// A provider outage must not block the report, so failures are only logged
+	// and the report is stored without coordinates.
+	ctx := c.Request().Context()
+	var address helper.GeocodeResult
+	locationSource := models.LocationSourceAddress
+	if input.Latitude != nil {
+		locationSource = models.LocationSourceGPS
+		address.Latitude, address.Longitude = *input.Latitude, *input.Longitude
+	} else if result, err := getGeocoder().Geocode(ctx, input.Location); err != nil {
+		log.Printf("Geocoding failed for report location %q: %v", input.Location, err)
+	} else {
+		address = result
+	}
 
-		cld, err := config.InitCloudinary()
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Cloudinary initialization failed", http.StatusInternalServerError, "error", nil))
+	// Reverse-geocode into street/kelurahan/kecamatan/city/province; failures are not fatal
+	var geohash string
+	if address.Latitude != 0 || address.Longitude != 0 {
+		geohash = helper.EncodeGeohash(address.Latitude, address.Longitude, models.ReportGeohashPrecision)
+		if !address.HasAdminAreas() {
+			if result, err := getGeocoder().ReverseGeocode(ctx, address.Latitude, address.Longitude); err != nil {
+				log.Printf("Reverse geocoding failed for %f,%f: %v", address.Latitude, address.Longitude, err)
+			} else {
+				// Keep the reported coordinates, only take the address components
+				result.Latitude, result.Longitude = address.Latitude, address.Longitude
+				address = result
+			}
 		}
+	}
 
-		uploadResult, err := cld.Upload.Upload(c.Request().Context(), src, uploader.UploadParams{
-			Folder: "report_rubbish",
-		})
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to upload photo", http.StatusInternalServerError, "error", nil))
-		}
-		photoURL = uploadResult.SecureURL
+	location := input.Location
+	if location == "" {
+		location = address.DisplayName
+	}
+	var accuracy *float64
+	if locationSource == models.LocationSourceGPS {
+		accuracy = input.Accuracy
 	}
 
-	// Set status to "process" if required fields are provided
-	status := "rejected"
-	if input.Location != "" && input.Description != "" && photoURL != "" && input.TanggalLaporan != "" {
-		status = "process"
+	// Parse TanggalLaporan into time.Time
+	tanggalLaporan, err := time.Parse("2006-01-02", input.TanggalLaporan)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid date format. Please use YYYY-MM-DD.", http.StatusBadRequest, "error", nil))
 	}
 
-	// Get coordinates if location is provided
-	var latitude, longitude float64
-	if input.Location != "" {
-		latitude, longitude, err = getCoordinatesFromAddress(input.Location)
+	// Check for open reports of the same pile before uploading the photo.
+	// The client can attach to one of them via "me too" or resend with confirm_new=true.
+	if (address.Latitude != 0 || address.Longitude != 0) && !input.ConfirmNew {
+		candidates, err := findDuplicateCandidates(address.Latitude, address.Longitude, input.Category)
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to get coordinates from address", http.StatusInternalServerError, "error", err.Error()))
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to check for duplicate reports", http.StatusInternalServerError, "error", nil))
+		}
+		if len(candidates) > 0 {
+			return c.JSON(http.StatusConflict, helper.APIResponse("Similar reports already exist nearby", http.StatusConflict, "error", map[string]interface{}{
+				"duplicates": candidates,
+			}))
 		}
 	}
 
-	// Parse TanggalLaporan into time.Time
-	tanggalLaporan, err := time.Parse("2006-01-02", input.TanggalLaporan)
+	// Upload every "photo" part in order; the first one becomes the cover photo
+	photos, err := uploadReportPhotos(c)
+	if errors.Is(err, ErrInvalidPhotoType) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid file type, only JPEG and PNG photos are allowed", http.StatusBadRequest, "error", nil))
+	}
+	if errors.Is(err, ErrTooManyPhotos) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(fmt.Sprintf("A report can have at most %d photos", maxReportPhotos()), http.StatusBadRequest, "error", nil))
+	}
+	if errors.Is(err, ErrPhotoCaptionTooLong) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Photo captions must be at most 255 characters", http.StatusBadRequest, "error", nil))
+	}
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid date format. Please use YYYY-MM-DD.", http.StatusBadRequest, "error", nil))
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to upload photo", http.StatusInternalServerError, "error", nil))
+	}
+	var photoURL string
+	if len(photos) > 0 {
+		photoURL = photos[0].URL
 	}
 
 	// Create the report
 	report := models.ReportRubbish{
 		UserID:         userID,
 		Category:       input.Category,
-		Location:       input.Location,
+		Location:       location,
 		Description:    input.Description,
 		Photo:          photoURL,
-		Status:         status,
-		Longitude:      longitude,
-		Latitude:       latitude,
+		Status:         models.ReportStatusSubmitted, // Semua laporan baru masuk antrean moderasi
+		Longitude:      address.Longitude,
+		Latitude:       address.Latitude,
+		Geohash:        geohash,
+		Street:         address.Street,
+		Kelurahan:      address.Kelurahan,
+		Kecamatan:      address.Kecamatan,
+		City:           address.City,
+		Province:       address.Province,
+		LocationSource: locationSource,
+		AccuracyM:      accuracy,
 		TanggalLaporan: tanggalLaporan, // Store as time.Time
 	}
 
-	// Save the report to the database
-	if err := config.DB.Create(&report).Error; err != nil {
+	// Check each photo's EXIF timestamp and GPS against the report; flagged reports are highlighted for admins
+	for i := range photos {
+		if checkPhotoAuthenticity(&photos[i], report) {
+			report.PhotoFlagged = true
+		}
+	}
+
+	// Save the report and its first timeline entry to the database
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Assign the smallest administrative region containing the report
+		if err := assignReportRegion(tx, &report); err != nil {
+			return err
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		for i := range photos {
+			photos[i].ReportID = report.ID
+		}
+		if len(photos) > 0 {
+			if err := tx.Create(&photos).Error; err != nil {
+				return err
+			}
+		}
+		return recordStatusChange(tx, report.ID, "", report.Status, userID, "", "")
+	})
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create report", http.StatusInternalServerError, "error", nil))
 	}
 
@@ -194,6 +247,7 @@ func CreateReportRubbish(c echo.Context) error {
 		Location:       report.Location,
 		Description:    report.Description,
 		Photo:          report.Photo,
+		Photos:         toReportPhotoResponses(photos),
 		Status:         report.Status,
 		Longitude:      report.Longitude,
 		Latitude:       report.Latitude,
@@ -206,8 +260,12 @@ func CreateReportRubbish(c echo.Context) error {
 			Role:         reportWithUser.User.Role,
 			Photo:        reportWithUser.User.Photo,
 		},
+		Address: toReportAddressResponse(report),
 	}
 
+	// Evaluate badges unlocked by this report
+	runAchievementEvaluation(userID, models.AchievementEventReportCreated)
+
 	// Return success response
 	return c.JSON(http.StatusOK, helper.APIResponse("Report created successfully", http.StatusOK, "success", response))
 }
@@ -219,71 +277,109 @@ func UpdateReportStatus(c echo.Context) error {
 
 	// Mendapatkan input status
 	input := struct {
-		Status string `json:"status" validate:"required,oneof=pending approved rejected"`
+		Status        string `json:"status" validate:"required,oneof=submitted in_review approved rejected cleaned_up closed"`
+		Reason        string `json:"reason"`         // Wajib untuk penolakan, ditampilkan ke pelapor
+		InternalNotes string `json:"internal_notes"` // Opsional, hanya terlihat oleh admin
 	}{}
 
 	// Bind dan validasi input
 	if err := c.Bind(&input); err != nil {
 		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
 	}
-
-	// Ambil laporan berdasarkan ID
-	var report models.ReportRubbish
-	if err := config.DB.First(&report, id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
 	}
 
-	// Update status laporan
-	report.Status = input.Status
-	if err := config.DB.Save(&report).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update report status", http.StatusInternalServerError, "error", nil))
-	}
+	adminID, _ := c.Get("userID").(uint)
+	permissions, _ := c.Get("userPermissions").([]string)
+
+	// Update status dan pemberian poin dilakukan dalam satu transaksi.
+	// Baris laporan dikunci agar klik ganda tidak memberikan poin dua kali.
+	var report models.ReportRubbish
+	pointsAction := PointActionUnchanged
+	pointsDelta := 0
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			return err
+		}
 
-	// Jika status laporan adalah "approved", beri poin ke user
-	if report.Status == "approved" {
-		var user models.User
-		if err := config.DB.First(&user, report.UserID).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to find user", http.StatusInternalServerError, "error", nil))
+		// Semua aturan transisi status dicek di models.CheckReportTransition
+		if err := models.CheckReportTransition(report, input.Status, permissions, input.Reason); err != nil {
+			return err
 		}
 
-		// Poin yang akan diberikan
-		points := uint(1000)
+		fromStatus := report.Status
+		report.Status = input.Status
+		if err := tx.Save(&report).Error; err != nil {
+			return err
+		}
 
-		// Tambahkan poin ke user
-		user.Points += points
-		if err := config.DB.Save(&user).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update user points", http.StatusInternalServerError, "error", nil))
+		// Catat perpindahan status beserta alasan dan catatan internal
+		if err := recordStatusChange(tx, report.ID, fromStatus, report.Status, adminID, input.Reason, input.InternalNotes); err != nil {
+			return err
 		}
 
-		// Update atau buat data poin di tabel Points
-		var userPoints models.Points
-		err := config.DB.Where("user_id = ?", user.ID).First(&userPoints).Error
-		if err != nil {
-			// Jika tidak ada data poin, buat data baru
-			if err := config.DB.Create(&models.Points{
-				UserID: user.ID,
-				Points: points,
-			}).Error; err != nil {
-				return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to add points", http.StatusInternalServerError, "error", nil))
+		var err error
+		switch report.Status {
+		case models.ReportStatusApproved:
+			// Poin dihitung dari aturan poin yang aktif dan hanya diberikan sekali per laporan
+			var eval PointEvaluation
+			eval, err = evaluatePoints(tx, report.UserID, report.Category, models.PointOutcomeReportApproved, report.ID, time.Now())
+			if err != nil {
+				return err
+			}
+			pointsAction, pointsDelta, err = grantReportPoints(tx, report, adminID, eval)
+			if err != nil {
+				return err
+			}
+			// Pelapor tambahan ("me too" dan duplikat yang digabung) ikut mendapat poin
+			if _, err = grantConfirmationPoints(tx, report, &adminID); err != nil {
+				return err
+			}
+			// Laporan langsung masuk antrean tim kebersihan jika AUTO_ASSIGN_CREWS aktif
+			err = autoAssignReport(tx, report)
+		case models.ReportStatusRejected:
+			// Laporan yang ditolak setelah disetujui kehilangan poinnya
+			pointsAction, pointsDelta, err = reverseReportPoints(tx, report, adminID, "report rejected")
+			if err != nil {
+				return err
 			}
-		} else {
-			// Jika data poin sudah ada, update
-			userPoints.Points += points
-			if err := config.DB.Save(&userPoints).Error; err != nil {
-				return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update points", http.StatusInternalServerError, "error", nil))
+			if err = reverseConfirmationPoints(tx, report, adminID, "report rejected"); err != nil {
+				return err
 			}
+			err = cancelActiveAssignments(tx, report.ID, &adminID, "report rejected")
 		}
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	}
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) {
+		return transitionErrorResponse(c, transitionErr)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update report status", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Evaluasi badge setiap kali laporan disetujui, termasuk persetujuan yang tidak memberikan poin
+	if input.Status == models.ReportStatusApproved {
+		runAchievementEvaluation(report.UserID, models.AchievementEventReportApproved)
 	}
 
 	// Siapkan respons dengan metadata dan data yang relevan
 	responseData := struct {
-		ID     uint   `json:"id"`
-		Status string `json:"status"`
-		UserID uint   `json:"user_id"`
+		ID           uint   `json:"id"`
+		Status       string `json:"status"`
+		UserID       uint   `json:"user_id"`
+		PointsAction string `json:"points_action"` // granted, reversed, atau unchanged
+		PointsDelta  int    `json:"points_delta"`
 	}{
-		ID:     report.ID,
-		Status: report.Status,
-		UserID: report.UserID,
+		ID:           report.ID,
+		Status:       report.Status,
+		UserID:       report.UserID,
+		PointsAction: pointsAction,
+		PointsDelta:  pointsDelta,
 	}
 
 	// Mengembalikan respons sukses dengan metadata dan data yang relevan
@@ -291,6 +387,93 @@ func UpdateReportStatus(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// Fungsi untuk memetakan model laporan (dengan relasi User) ke struktur respons
+func toReportResponse(report models.ReportRubbish) ReportResponse {
+	return ReportResponse{
+		ID:             report.ID,
+		UserID:         report.UserID,
+		Category:       report.Category,
+		TanggalLaporan: report.TanggalLaporan.Format("2006-01-02"),
+		Location:       report.Location,
+		Description:    report.Description,
+		Photo:          report.Photo,
+		Photos:         toReportPhotoResponses(report.Photos),
+		Status:         report.Status,
+		Longitude:      report.Longitude,
+		Latitude:       report.Latitude,
+		User: UserResponse{
+			IDUser:       report.User.ID,
+			NamaLengkap:  report.User.NamaLengkap,
+			TanggalLahir: report.User.TanggalLahir.Format("2006-01-02"),
+			NoTelepon:    report.User.NoTelepon,
+			Email:        report.User.Email,
+			Role:         report.User.Role,
+			Photo:        report.User.Photo,
+		},
+		MergedIntoID: report.MergedIntoID,
+		Address:      toReportAddressResponse(report),
+		AfterPhoto:   report.AfterPhoto,
+		CleanedByID:  report.CleanedByID,
+	}
+}
+
+// Fungsi untuk memetakan kolom alamat terstruktur laporan ke respons
+func toReportAddressResponse(report models.ReportRubbish) *ReportAddressResponse {
+	return &ReportAddressResponse{
+		Street:         report.Street,
+		Kelurahan:      report.Kelurahan,
+		Kecamatan:      report.Kecamatan,
+		City:           report.City,
+		Province:       report.Province,
+		LocationSource: report.LocationSource,
+		AccuracyM:      report.AccuracyM,
+		RegionID:       report.RegionID,
+	}
+}
+
+// Fungsi untuk mengubah error transisi status menjadi respons HTTP
+func transitionErrorResponse(c echo.Context, err *models.TransitionError) error {
+	data := map[string]interface{}{
+		"current_status":      err.From,
+		"requested_status":    err.To,
+		"allowed_next_states": err.Allowed,
+	}
+
+	switch {
+	case err.Forbidden:
+		return c.JSON(http.StatusForbidden, helper.APIResponse(err.Error(), http.StatusForbidden, "error", data))
+	case len(err.MissingFields) > 0:
+		data["missing_fields"] = err.MissingFields
+		return c.JSON(http.StatusUnprocessableEntity, helper.APIResponse(err.Error(), http.StatusUnprocessableEntity, "error", data))
+	default:
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", data))
+	}
+}
+
+// Fungsi untuk menerapkan filter daftar laporan admin: kategori, status,
+// kelurahan/kecamatan/kota/provinsi dan region_id (termasuk sub-wilayahnya)
+func applyReportListFilters(db *gorm.DB, c echo.Context) (*gorm.DB, error) {
+	if category := c.QueryParam("category"); category != "" {
+		db = db.Where("category = ?", category)
+	}
+	if status := c.QueryParam("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if flagged := c.QueryParam("flagged"); flagged != "" {
+		db = db.Where("photo_flagged = ?", flagged == "true")
+	}
+	db = applyAddressFilters(db, c)
+
+	regionIDs, err := regionFilterIDs(c)
+	if err != nil {
+		return nil, err
+	}
+	if regionIDs != nil {
+		db = db.Where("region_id IN ?", regionIDs)
+	}
+	return db, nil
+}
+
 func GetAllReportRubbish(c echo.Context) error {
 	// Ambil parameter query untuk paginasi
 	pageParam := c.QueryParam("page")
@@ -315,9 +498,7 @@ func GetAllReportRubbish(c echo.Context) error {
 	// Hitung offset berdasarkan page dan limit
 	offset := (page - 1) * limit
 
-	// Ambil kategori, status, dan sorting dari query parameter
-	category := c.QueryParam("category")
-	status := c.QueryParam("status") // New status filter
+	// Ambil sorting dari query parameter
 	sortOrder := c.QueryParam("sort")
 
 	// Validasi nilai sorting (default: "asc" jika tidak diisi)
@@ -328,18 +509,11 @@ func GetAllReportRubbish(c echo.Context) error {
 		sortOrder = "asc" // Default sorting: ascending
 	}
 
-	// Query database dengan filter kategori dan status
+	// Query database dengan filter kategori, status dan wilayah
 	var totalItems int64
-	db := config.DB.Model(&models.ReportRubbish{})
-
-	// Filter by category
-	if category != "" {
-		db = db.Where("category = ?", category)
-	}
-
-	// Filter by status if provided
-	if status != "" {
-		db = db.Where("status = ?", status)
+	db, err := applyReportListFilters(config.DB.Model(&models.ReportRubbish{}), c)
+	if err != nil {
+		return regionFilterErrorResponse(c, err)
 	}
 
 	// Hitung total data sebelum paginasi
@@ -350,7 +524,7 @@ func GetAllReportRubbish(c echo.Context) error {
 	// Ambil data dengan paginasi
 	var reports []models.ReportRubbish
 	if err := db.Order(fmt.Sprintf("tanggal_laporan %s", sortOrder)).
-		Offset(offset).Limit(limit).Preload("User").Find(&reports).Error; err != nil {
+		Offset(offset).Limit(limit).Preload("User").Preload("Photos", orderedReportPhotos).Find(&reports).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to load reports", http.StatusInternalServerError, "error", nil))
 	}
 
@@ -365,6 +539,7 @@ func GetAllReportRubbish(c echo.Context) error {
 			Location:       report.Location,
 			Description:    report.Description,
 			Photo:          report.Photo,
+			Photos:         toReportPhotoResponses(report.Photos),
 			Status:         report.Status,
 			Longitude:      report.Longitude,
 			Latitude:       report.Latitude,
@@ -409,10 +584,20 @@ func GetReportHistoryByUser(c echo.Context) error {
 
 	// Query laporan berdasarkan userID
 	var reports []models.ReportRubbish
-	if err := config.DB.Preload("User").Where("user_id = ?", userID).Find(&reports).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("Photos", orderedReportPhotos).Where("user_id = ?", userID).Find(&reports).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report history", http.StatusInternalServerError, "error", nil))
 	}
 
+	// Ambil riwayat status tanpa catatan internal admin
+	reportIDs := make([]uint, 0, len(reports))
+	for _, report := range reports {
+		reportIDs = append(reportIDs, report.ID)
+	}
+	timelines, err := loadReportTimelines(reportIDs, false)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report timeline", http.StatusInternalServerError, "error", nil))
+	}
+
 	// Mapping hasil query ke struktur response
 	var reportResponses []ReportResponse
 	for _, report := range reports {
@@ -424,6 +609,7 @@ func GetReportHistoryByUser(c echo.Context) error {
 			Location:       report.Location,
 			Description:    report.Description,
 			Photo:          report.Photo,
+			Photos:         toReportPhotoResponses(report.Photos),
 			Status:         report.Status,
 			Longitude:      report.Longitude,
 			Latitude:       report.Latitude,
@@ -436,6 +622,7 @@ func GetReportHistoryByUser(c echo.Context) error {
 				Role:         report.User.Role,
 				Photo:        report.User.Photo,
 			},
+			Timeline: timelines[report.ID],
 		})
 	}
 
@@ -450,40 +637,25 @@ func AddPointsToUser(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, "Invalid user ID from token")
 	}
 
-	// Ambil jumlah poin yang ingin ditambahkan (misalnya dari input request)
-	points := uint(1000) // Ganti dengan poin yang Anda inginkan
-
-	// Dapatkan user berdasarkan userID
-	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	// Jumlah poin mengikuti aturan poin untuk hasil "manual_award", lalu dicatat di ledger
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		eval, err := evaluatePoints(tx, userID, "", models.PointOutcomeManualAward, 0, time.Now())
+		if err != nil {
+			return err
+		}
+		_, err = postPointTransaction(tx, &models.PointTransaction{
+			UserID: userID,
+			Delta:  eval.Total,
+			Reason: models.PointReasonManualAward,
+			Note:   eval.Note(),
+		})
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.JSON(http.StatusNotFound, "User not found")
 	}
-
-	// Tambahkan poin ke user
-	user.Points += points
-
-	// Simpan perubahan pada user
-	if err := config.DB.Save(&user).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, "Failed to update user points")
-	}
-
-	// Cek apakah data poin untuk user sudah ada
-	var userPoints models.Points
-	err := config.DB.Where("user_id = ?", user.ID).First(&userPoints).Error
 	if err != nil {
-		// Jika tidak ada, buat data poin baru
-		if err := config.DB.Create(&models.Points{
-			UserID: user.ID,
-			Points: points,
-		}).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, "Failed to create points record")
-		}
-	} else {
-		// Jika sudah ada, update jumlah poin
-		userPoints.Points += points
-		if err := config.DB.Save(&userPoints).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, "Failed to update points")
-		}
+		return c.JSON(http.StatusInternalServerError, "Failed to update user points")
 	}
 
 	return c.JSON(http.StatusOK, "Points added successfully")
@@ -494,7 +666,7 @@ func GetLatestReports(c echo.Context) error {
 	var reports []models.ReportRubbish
 
 	// Query database: Ambil 10 laporan terbaru berdasarkan TanggalLaporan
-	if err := config.DB.Order("tanggal_laporan DESC").Limit(10).Preload("User").Find(&reports).Error; err != nil {
+	if err := config.DB.Order("tanggal_laporan DESC").Limit(10).Preload("User").Preload("Photos", orderedReportPhotos).Find(&reports).Error; err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve latest reports", http.StatusInternalServerError, "error", nil))
 	}
 
@@ -509,6 +681,7 @@ func GetLatestReports(c echo.Context) error {
 			Location:       report.Location,
 			Description:    report.Description,
 			Photo:          report.Photo,
+			Photos:         toReportPhotoResponses(report.Photos),
 			Status:         report.Status,
 			Longitude:      report.Longitude,
 			Latitude:       report.Latitude,
@@ -531,8 +704,9 @@ func GetLatestReports(c echo.Context) error {
 func DeductPointsFromUser(c echo.Context) error {
 	// Ambil input dari request
 	input := struct {
-		UserID uint `json:"user_id" validate:"required"`
-		Points int  `json:"points" validate:"required,min=1"` // Pastikan poin yang dikurangi minimal 1
+		UserID uint   `json:"user_id" validate:"required"`
+		Points int    `json:"points" validate:"required,min=1"` // Pastikan poin yang dikurangi minimal 1
+		Note   string `json:"note"`                             // Opsional: keterangan pengurangan
 	}{}
 
 	// Bind dan validasi input
@@ -540,40 +714,34 @@ func DeductPointsFromUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
 	}
 
-	// Cari pengguna berdasarkan ID
-	var user models.User
-	if err := config.DB.First(&user, input.UserID).Error; err != nil {
+	adminID, _ := c.Get("userID").(uint)
+
+	// Kurangi poin melalui ledger; saldo dicek di dalam transaksi agar tidak menjadi negatif
+	var remaining uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		remaining, err = postPointTransaction(tx, &models.PointTransaction{
+			UserID:  input.UserID,
+			Delta:   -input.Points,
+			Reason:  models.PointReasonAdminDeduction,
+			ActorID: &adminID,
+			Note:    input.Note,
+		})
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
 	}
-
-	// Pastikan poin tidak menjadi negatif
-	if int(user.Points) < input.Points {
+	if errors.Is(err, ErrInsufficientPoints) {
 		return c.JSON(http.StatusBadRequest, helper.APIResponse("Insufficient points", http.StatusBadRequest, "error", nil))
 	}
-
-	// Kurangi poin pengguna
-	user.Points -= uint(input.Points)
-	if err := config.DB.Save(&user).Error; err != nil {
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update user points", http.StatusInternalServerError, "error", nil))
 	}
 
-	// Update atau buat record di tabel Points
-	var userPoints models.Points
-	err := config.DB.Where("user_id = ?", user.ID).First(&userPoints).Error
-	if err != nil {
-		// Jika tidak ada record, buat baru
-		if err := config.DB.Create(&models.Points{
-			UserID: user.ID,
-			Points: uint(user.Points), // Simpan poin terbaru
-		}).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update points record", http.StatusInternalServerError, "error", nil))
-		}
-	} else {
-		// Jika ada, perbarui
-		userPoints.Points = uint(user.Points)
-		if err := config.DB.Save(&userPoints).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to save points record", http.StatusInternalServerError, "error", nil))
-		}
+	var user models.User
+	if err := config.DB.First(&user, input.UserID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
 	}
 
 	// Siapkan respons dengan tambahan nama dan email
@@ -588,7 +756,7 @@ func DeductPointsFromUser(c echo.Context) error {
 		NamaLengkap:     user.NamaLengkap,
 		Email:           user.Email,
 		NoTelepone:      user.NoTelepon,
-		RemainingPoints: user.Points,
+		RemainingPoints: remaining,
 	}
 
 	return c.JSON(http.StatusOK, helper.APIResponse("Points deducted successfully", http.StatusOK, "success", responseData))
@@ -604,22 +772,85 @@ func DeleteReportByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid report ID", http.StatusBadRequest, "error", nil))
 	}
 
-	// Ambil laporan berdasarkan ID
-	var report models.ReportRubbish
-	if err := config.DB.First(&report, reportID).Error; err != nil {
-		if err.Error() == "record not found" {
-			return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	adminID, _ := c.Get("userID").(uint)
+
+	// Poin laporan dibatalkan dan laporan dihapus dalam satu transaksi
+	pointsAction := PointActionUnchanged
+	pointsDelta := 0
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Ambil laporan berdasarkan ID
+		var report models.ReportRubbish
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return err
+		}
+
+		// Laporan utama dari duplikat yang sudah digabung tidak boleh dihapus
+		var children int64
+		if err := tx.Model(&models.ReportRubbish{}).Where("merged_into_id = ?", report.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrReportHasMergedChildren
+		}
+
+		var err error
+		pointsAction, pointsDelta, err = reverseReportPoints(tx, report, adminID, "report deleted")
+		if err != nil {
+			return err
+		}
+		if err := reverseConfirmationPoints(tx, report, adminID, "report deleted"); err != nil {
+			return err
 		}
-		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report", http.StatusInternalServerError, "error", nil))
-	}
 
-	// Hapus laporan dari database
-	if err := config.DB.Delete(&report).Error; err != nil {
+		// Hapus riwayat status dan pelapor tambahan milik laporan
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportStatusChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportConfirmation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportPhoto{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportCleanup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportAssignmentEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.ReportAssignment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReportConfirmation{}).Where("merged_from_report_id = ?", report.ID).
+			Update("merged_from_report_id", nil).Error; err != nil {
+			return err
+		}
+
+		// Hapus laporan dari database
+		return tx.Delete(&report).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
+	}
+	if errors.Is(err, ErrReportHasMergedChildren) {
+		return c.JSON(http.StatusConflict, helper.APIResponse("Report has merged duplicates and cannot be deleted", http.StatusConflict, "error", nil))
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to delete report", http.StatusInternalServerError, "error", nil))
 	}
 
+	responseData := struct {
+		ID           uint   `json:"id"`
+		PointsAction string `json:"points_action"`
+		PointsDelta  int    `json:"points_delta"`
+	}{
+		ID:           uint(reportID),
+		PointsAction: pointsAction,
+		PointsDelta:  pointsDelta,
+	}
+
 	// Kembalikan respons sukses
-	return c.JSON(http.StatusOK, helper.APIResponse("Report deleted successfully", http.StatusOK, "success", nil))
+	return c.JSON(http.StatusOK, helper.APIResponse("Report deleted successfully", http.StatusOK, "success", responseData))
 }
 func GetReportByID(c echo.Context) error {
 	// Mendapatkan ID laporan dari parameter URL
@@ -633,13 +864,37 @@ func GetReportByID(c echo.Context) error {
 
 	// Ambil laporan berdasarkan ID
 	var report models.ReportRubbish
-	if err := config.DB.Preload("User").First(&report, reportID).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("Photos", orderedReportPhotos).First(&report, reportID).Error; err != nil {
 		if strings.Contains(err.Error(), "record not found") {
 			return c.JSON(http.StatusNotFound, helper.APIResponse("Report not found", http.StatusNotFound, "error", nil))
 		}
 		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report", http.StatusInternalServerError, "error", nil))
 	}
 
+	// Admin melihat riwayat status lengkap termasuk catatan internal
+	timelines, err := loadReportTimelines([]uint{report.ID}, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report timeline", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Pelapor tambahan beserta foto masing-masing
+	confirmations, err := loadReportConfirmations(report.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report confirmations", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Bukti pembersihan beserta status verifikasinya
+	cleanups, err := loadReportCleanups(report.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report cleanups", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Penugasan tim kebersihan, yang terbaru lebih dulu
+	assignments, err := loadReportAssignments(report.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve report assignments", http.StatusInternalServerError, "error", nil))
+	}
+
 	// Mapping hasil ke response
 	reportResponse := ReportResponse{
 		ID:             report.ID,
@@ -649,6 +904,7 @@ func GetReportByID(c echo.Context) error {
 		Location:       report.Location,
 		Description:    report.Description,
 		Photo:          report.Photo,
+		Photos:         toReportPhotoResponses(report.Photos),
 		Status:         report.Status,
 		Longitude:      report.Longitude,
 		Latitude:       report.Latitude,
@@ -661,6 +917,15 @@ func GetReportByID(c echo.Context) error {
 			Role:         report.User.Role,
 			Photo:        report.User.Photo,
 		},
+		Timeline:      timelines[report.ID],
+		MergedIntoID:  report.MergedIntoID,
+		Confirmations: confirmations,
+		Address:       toReportAddressResponse(report),
+		AfterPhoto:    report.AfterPhoto,
+		CleanedByID:   report.CleanedByID,
+		Cleanups:      cleanups,
+		Assignments:   assignments,
+		Authenticity:  toReportAuthenticityResponse(report),
 	}
 
 	// Kembalikan respons sukses
@@ -671,6 +936,12 @@ func FetchStatistics(c echo.Context) error {
 	// Define durations in months
 	durations := []int{1, 3, 6, 9, 12}
 
+	// Optional region filter, including its sub-regions
+	regionIDs, err := regionFilterIDs(c)
+	if err != nil {
+		return regionFilterErrorResponse(c, err)
+	}
+
 	var result []DurationData
 
 	for _, duration := range durations {
@@ -680,7 +951,11 @@ func FetchStatistics(c echo.Context) error {
 
 		// Query reports within the duration range
 		var reports []models.ReportRubbish
-		if err := config.DB.Where("tanggal_laporan BETWEEN ? AND ?", startDate, endDate).Find(&reports).Error; err != nil {
+		db := config.DB.Where("tanggal_laporan BETWEEN ? AND ?", startDate, endDate)
+		if regionIDs != nil {
+			db = db.Where("region_id IN ?", regionIDs)
+		}
+		if err := db.Find(&reports).Error; err != nil {
 			// Return error with helper APIResponse
 			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to fetch reports", http.StatusInternalServerError, "error", nil))
 		}
@@ -688,6 +963,8 @@ func FetchStatistics(c echo.Context) error {
 		// Initialize arrays to store counts for each month in the duration
 		reportCounts := make([]int, duration)
 		userCounts := make([]int, duration)
+		resolvedCounts := make([]int, duration)
+		actionable, resolved := 0, 0
 
 		// Initialize maps to track users per month
 		userMap := make([]map[uint]bool, duration)
@@ -708,6 +985,15 @@ func FetchStatistics(c echo.Context) error {
 
 			// Add user to the map of distinct users for this month
 			userMap[monthDiff][report.UserID] = true
+
+			// Approved reports are waiting for cleanup; a verified cleanup resolves them
+			if report.CleanedAt != nil {
+				resolvedCounts[monthDiff]++
+				resolved++
+				actionable++
+			} else if report.Status == models.ReportStatusApproved {
+				actionable++
+			}
 		}
 
 		// Update userCounts with the number of distinct users for each month
@@ -717,9 +1003,13 @@ func FetchStatistics(c echo.Context) error {
 
 		// Add the data for this duration to the result
 		durationData := DurationData{
-			Duration:     duration,
-			ReportCounts: reportCounts,
-			UserCounts:   userCounts,
+			Duration:       duration,
+			ReportCounts:   reportCounts,
+			UserCounts:     userCounts,
+			ResolvedCounts: resolvedCounts,
+		}
+		if actionable > 0 {
+			durationData.ResolutionRate = math.Round(float64(resolved)/float64(actionable)*1000) / 1000
 		}
 
 		result = append(result, durationData)
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/models"
+
+	"gorm.io/gorm"
+)
+
+// Struct untuk respons riwayat status laporan
+type ReportStatusChangeResponse struct {
+	FromStatus    string `json:"from_status"`
+	ToStatus      string `json:"to_status"`
+	Reason        string `json:"reason"`
+	ChangedBy     string `json:"changed_by,omitempty"`     // Hanya untuk admin
+	InternalNotes string `json:"internal_notes,omitempty"` // Hanya untuk admin
+	CreatedAt     string `json:"created_at"`
+}
+
+// recordStatusChange menyimpan satu entri riwayat status laporan
+func recordStatusChange(tx *gorm.DB, reportID uint, from string, to string, changedByID uint, reason string, notes string) error {
+	return tx.Create(&models.ReportStatusChange{
+		ReportID:      reportID,
+		FromStatus:    from,
+		ToStatus:      to,
+		ChangedByID:   changedByID,
+		Reason:        reason,
+		InternalNotes: notes,
+	}).Error
+}
+
+// loadReportTimelines mengambil riwayat status untuk beberapa laporan sekaligus.
+// Catatan internal dan nama admin hanya disertakan jika includeInternal bernilai true.
+func loadReportTimelines(reportIDs []uint, includeInternal bool) (map[uint][]ReportStatusChangeResponse, error) {
+	timelines := make(map[uint][]ReportStatusChangeResponse)
+	if len(reportIDs) == 0 {
+		return timelines, nil
+	}
+
+	var changes []models.ReportStatusChange
+	if err := config.DB.Preload("ChangedBy").
+		Where("report_id IN ?", reportIDs).
+		Order("created_at ASC, id ASC").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		entry := ReportStatusChangeResponse{
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			Reason:     change.Reason,
+			CreatedAt:  change.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if includeInternal {
+			entry.ChangedBy = change.ChangedBy.NamaLengkap
+			entry.InternalNotes = change.InternalNotes
+		}
+		timelines[change.ReportID] = append(timelines[change.ReportID], entry)
+	}
+
+	return timelines, nil
+}
@@ -0,0 +1,510 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRewardUnavailable dikembalikan jika hadiah tidak aktif atau di luar masa berlaku
+	ErrRewardUnavailable = errors.New("reward is not available")
+	// ErrRewardOutOfStock dikembalikan jika stok hadiah sudah habis
+	ErrRewardOutOfStock = errors.New("reward is out of stock")
+	// ErrRedemptionNotPending dikembalikan jika penukaran sudah diproses sebelumnya
+	ErrRedemptionNotPending = errors.New("redemption has already been processed")
+)
+
+// Struct untuk input hadiah
+type RewardInput struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url" validate:"omitempty,url"`
+	Cost        uint   `json:"cost" validate:"required,min=1"`
+	Stock       uint   `json:"stock"`
+	ValidFrom   string `json:"valid_from"`  // Format YYYY-MM-DD, opsional
+	ValidUntil  string `json:"valid_until"` // Format YYYY-MM-DD, opsional (inklusif)
+	Active      *bool  `json:"active"`
+}
+
+// Struct untuk respons hadiah
+type RewardResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	Cost        uint   `json:"cost"`
+	Stock       uint   `json:"stock"`
+	ValidFrom   string `json:"valid_from"`
+	ValidUntil  string `json:"valid_until"`
+	Active      bool   `json:"active"`
+}
+
+// Struct untuk respons penukaran hadiah
+type RedemptionResponse struct {
+	ID           uint   `json:"id"`
+	UserID       uint   `json:"user_id"`
+	NamaLengkap  string `json:"nama_lengkap,omitempty"`
+	RewardID     uint   `json:"reward_id"`
+	RewardName   string `json:"reward_name"`
+	Cost         uint   `json:"cost"`
+	VoucherCode  string `json:"voucher_code"`
+	Status       string `json:"status"`
+	CancelReason string `json:"cancel_reason,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	FulfilledAt  string `json:"fulfilled_at,omitempty"`
+	CancelledAt  string `json:"cancelled_at,omitempty"`
+}
+
+func formatOptionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatOptionalDateTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func toRewardResponse(reward models.Reward) RewardResponse {
+	return RewardResponse{
+		ID:          reward.ID,
+		Name:        reward.Name,
+		Description: reward.Description,
+		ImageURL:    reward.ImageURL,
+		Cost:        reward.Cost,
+		Stock:       reward.Stock,
+		ValidFrom:   formatOptionalDate(reward.ValidFrom),
+		ValidUntil:  formatOptionalDate(reward.ValidUntil),
+		Active:      reward.Active,
+	}
+}
+
+func toRedemptionResponse(redemption models.Redemption) RedemptionResponse {
+	return RedemptionResponse{
+		ID:           redemption.ID,
+		UserID:       redemption.UserID,
+		NamaLengkap:  redemption.User.NamaLengkap,
+		RewardID:     redemption.RewardID,
+		RewardName:   redemption.Reward.Name,
+		Cost:         redemption.Cost,
+		VoucherCode:  redemption.VoucherCode,
+		Status:       redemption.Status,
+		CancelReason: redemption.CancelReason,
+		CreatedAt:    redemption.CreatedAt.Format("2006-01-02 15:04:05"),
+		FulfilledAt:  formatOptionalDateTime(redemption.FulfilledAt),
+		CancelledAt:  formatOptionalDateTime(redemption.CancelledAt),
+	}
+}
+
+// Fungsi untuk mengubah input menjadi model hadiah
+func (input RewardInput) toModel(reward *models.Reward) error {
+	reward.Name = input.Name
+	reward.Description = input.Description
+	reward.ImageURL = input.ImageURL
+	reward.Cost = input.Cost
+	reward.Stock = input.Stock
+	reward.Active = input.Active == nil || *input.Active
+
+	reward.ValidFrom = nil
+	if input.ValidFrom != "" {
+		validFrom, err := time.ParseInLocation("2006-01-02", input.ValidFrom, time.Local)
+		if err != nil {
+			return errors.New("invalid valid_from format. Please use YYYY-MM-DD")
+		}
+		reward.ValidFrom = &validFrom
+	}
+
+	reward.ValidUntil = nil
+	if input.ValidUntil != "" {
+		validUntil, err := time.ParseInLocation("2006-01-02", input.ValidUntil, time.Local)
+		if err != nil {
+			return errors.New("invalid valid_until format. Please use YYYY-MM-DD")
+		}
+		// Tanggal akhir berlaku sampai akhir hari
+		validUntil = validUntil.Add(24*time.Hour - time.Second)
+		reward.ValidUntil = &validUntil
+	}
+
+	if reward.ValidFrom != nil && reward.ValidUntil != nil && reward.ValidUntil.Before(*reward.ValidFrom) {
+		return errors.New("valid_until must not be before valid_from")
+	}
+	return nil
+}
+
+// Fungsi untuk menampilkan katalog hadiah yang bisa ditukar
+func GetRewards(c echo.Context) error {
+	now := time.Now()
+
+	var rewards []models.Reward
+	if err := config.DB.
+		Where("active = ? AND stock > 0", true).
+		Where("valid_from IS NULL OR valid_from <= ?", now).
+		Where("valid_until IS NULL OR valid_until >= ?", now).
+		Order("cost ASC").
+		Find(&rewards).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve rewards", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := []RewardResponse{}
+	for _, reward := range rewards {
+		responseData = append(responseData, toRewardResponse(reward))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Rewards retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi untuk mendapatkan detail hadiah berdasarkan ID
+func GetRewardByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid reward ID", http.StatusBadRequest, "error", nil))
+	}
+
+	var reward models.Reward
+	if err := config.DB.First(&reward, id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Reward not found", http.StatusNotFound, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Reward retrieved successfully", http.StatusOK, "success", toRewardResponse(reward)))
+}
+
+// Fungsi untuk menukar poin user dengan hadiah
+func RedeemReward(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid reward ID", http.StatusBadRequest, "error", nil))
+	}
+
+	// Stok, penukaran, dan pemotongan poin dilakukan dalam satu transaksi
+	var redemption models.Redemption
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var reward models.Reward
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, id).Error; err != nil {
+			return err
+		}
+		if !reward.AvailableAt(time.Now()) {
+			return ErrRewardUnavailable
+		}
+
+		// Kurangi stok secara atomik, gagal jika stok sudah habis
+		result := tx.Model(&models.Reward{}).Where("id = ? AND stock > 0", reward.ID).
+			UpdateColumn("stock", gorm.Expr("stock - 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRewardOutOfStock
+		}
+
+		code, err := helper.RandomCode(10)
+		if err != nil {
+			return err
+		}
+
+		redemption = models.Redemption{
+			UserID:      userID,
+			RewardID:    reward.ID,
+			Cost:        reward.Cost,
+			VoucherCode: "RCY-" + code,
+			Status:      models.RedemptionStatusPending,
+		}
+		if err := tx.Create(&redemption).Error; err != nil {
+			return err
+		}
+
+		_, err = postPointTransaction(tx, &models.PointTransaction{
+			UserID:       userID,
+			Delta:        -int(reward.Cost),
+			Reason:       models.PointReasonRedemption,
+			RedemptionID: &redemption.ID,
+			ActorID:      &userID,
+			Note:         reward.Name,
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Reward not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrRewardUnavailable), errors.Is(err, ErrRewardOutOfStock):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case errors.Is(err, ErrInsufficientPoints):
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Insufficient points", http.StatusBadRequest, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to redeem reward", http.StatusInternalServerError, "error", nil))
+	}
+
+	// Evaluasi badge penukaran
+	runAchievementEvaluation(userID, models.AchievementEventRedemption)
+
+	config.DB.Preload("Reward").First(&redemption, redemption.ID)
+	return c.JSON(http.StatusOK, helper.APIResponse("Reward redeemed successfully", http.StatusOK, "success", toRedemptionResponse(redemption)))
+}
+
+// Fungsi untuk menampilkan riwayat penukaran milik user yang sedang login
+func GetMyRedemptions(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+
+	var redemptions []models.Redemption
+	if err := config.DB.Preload("Reward").Where("user_id = ?", userID).Order("created_at DESC").Find(&redemptions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve redemptions", http.StatusInternalServerError, "error", nil))
+	}
+
+	responseData := []RedemptionResponse{}
+	for _, redemption := range redemptions {
+		responseData = append(responseData, toRedemptionResponse(redemption))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Redemptions retrieved successfully", http.StatusOK, "success", responseData))
+}
+
+// Fungsi admin untuk menambahkan hadiah ke katalog
+func CreateReward(c echo.Context) error {
+	var input RewardInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var reward models.Reward
+	if err := input.toModel(&reward); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+	if err := config.DB.Create(&reward).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to create reward", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Reward created successfully", http.StatusOK, "success", toRewardResponse(reward)))
+}
+
+// Fungsi admin untuk memperbarui hadiah
+func UpdateReward(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid reward ID", http.StatusBadRequest, "error", nil))
+	}
+
+	var input RewardInput
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	// Validasi tanggal sebelum membuka transaksi
+	if err := input.toModel(&models.Reward{}); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(err.Error(), http.StatusBadRequest, "error", nil))
+	}
+
+	var reward models.Reward
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris agar perubahan stok tidak bertabrakan dengan penukaran yang sedang berjalan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reward, id).Error; err != nil {
+			return err
+		}
+		if err := input.toModel(&reward); err != nil {
+			return err
+		}
+		return tx.Save(&reward).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Reward not found", http.StatusNotFound, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update reward", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Reward updated successfully", http.StatusOK, "success", toRewardResponse(reward)))
+}
+
+// Fungsi admin untuk menghapus hadiah yang belum pernah ditukar
+func DeleteReward(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid reward ID", http.StatusBadRequest, "error", nil))
+	}
+
+	// Hadiah yang sudah pernah ditukar cukup dinonaktifkan agar riwayat tetap utuh
+	var redemptionCount int64
+	if err := config.DB.Model(&models.Redemption{}).Where("reward_id = ?", id).Count(&redemptionCount).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to delete reward", http.StatusInternalServerError, "error", nil))
+	}
+	if redemptionCount > 0 {
+		return c.JSON(http.StatusConflict, helper.APIResponse("Reward has redemptions, deactivate it instead", http.StatusConflict, "error", nil))
+	}
+
+	result := config.DB.Delete(&models.Reward{}, id)
+	if result.Error != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to delete reward", http.StatusInternalServerError, "error", nil))
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Reward not found", http.StatusNotFound, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Reward deleted successfully", http.StatusOK, "success", nil))
+}
+
+// Fungsi admin untuk melihat semua penukaran hadiah
+func GetAllRedemptions(c echo.Context) error {
+	// Ambil parameter query untuk paginasi
+	pageParam := c.QueryParam("page")
+	limitParam := c.QueryParam("limit")
+
+	// Default nilai untuk paginasi
+	page := 1
+	limit := 10
+
+	// Parse parameter jika ada
+	if pageParam != "" {
+		if p, err := strconv.Atoi(pageParam); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	// Hitung offset berdasarkan page dan limit
+	offset := (page - 1) * limit
+
+	db := config.DB.Model(&models.Redemption{})
+	if status := c.QueryParam("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if code := c.QueryParam("voucher_code"); code != "" {
+		db = db.Where("voucher_code = ?", code)
+	}
+
+	var totalItems int64
+	if err := db.Count(&totalItems).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to count redemptions", http.StatusInternalServerError, "error", nil))
+	}
+
+	var redemptions []models.Redemption
+	if err := db.Preload("User").Preload("Reward").Order("created_at DESC").Offset(offset).Limit(limit).Find(&redemptions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve redemptions", http.StatusInternalServerError, "error", nil))
+	}
+
+	var items []RedemptionResponse
+	for _, redemption := range redemptions {
+		items = append(items, toRedemptionResponse(redemption))
+	}
+
+	// Hitung total halaman
+	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": items,
+			"pagination": map[string]interface{}{
+				"current_page":      page,
+				"per_page":          limit,
+				"total_redemptions": totalItems,
+				"total_pages":       totalPages,
+			},
+		},
+		"error": nil,
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Redemptions retrieved successfully", http.StatusOK, "success", response))
+}
+
+// Fungsi admin untuk menandai penukaran sudah diserahkan ke user
+func FulfillRedemption(c echo.Context) error {
+	return processRedemption(c, models.RedemptionStatusFulfilled)
+}
+
+// Fungsi admin untuk membatalkan penukaran; poin dikembalikan dan stok dipulihkan
+func CancelRedemption(c echo.Context) error {
+	return processRedemption(c, models.RedemptionStatusCancelled)
+}
+
+func processRedemption(c echo.Context, status string) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid redemption ID", http.StatusBadRequest, "error", nil))
+	}
+
+	input := struct {
+		Reason string `json:"reason"` // Opsional: alasan pembatalan
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+
+	adminID, _ := c.Get("userID").(uint)
+
+	var redemption models.Redemption
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&redemption, id).Error; err != nil {
+			return err
+		}
+		if redemption.Status != models.RedemptionStatusPending {
+			return ErrRedemptionNotPending
+		}
+
+		now := time.Now()
+		redemption.Status = status
+		redemption.HandledByID = &adminID
+		if status == models.RedemptionStatusFulfilled {
+			redemption.FulfilledAt = &now
+		} else {
+			redemption.CancelledAt = &now
+			redemption.CancelReason = input.Reason
+
+			// Kembalikan poin dan stok hadiah
+			if _, err := postPointTransaction(tx, &models.PointTransaction{
+				UserID:       redemption.UserID,
+				Delta:        int(redemption.Cost),
+				Reason:       models.PointReasonRedemptionRefund,
+				RedemptionID: &redemption.ID,
+				ActorID:      &adminID,
+				Note:         input.Reason,
+			}); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Reward{}).Where("id = ?", redemption.RewardID).
+				UpdateColumn("stock", gorm.Expr("stock + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(&redemption).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Redemption not found", http.StatusNotFound, "error", nil))
+	case errors.Is(err, ErrRedemptionNotPending):
+		return c.JSON(http.StatusConflict, helper.APIResponse(err.Error(), http.StatusConflict, "error", nil))
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update redemption", http.StatusInternalServerError, "error", nil))
+	}
+
+	config.DB.Preload("User").Preload("Reward").First(&redemption, redemption.ID)
+	return c.JSON(http.StatusOK, helper.APIResponse("Redemption "+status+" successfully", http.StatusOK, "success", toRedemptionResponse(redemption)))
+}
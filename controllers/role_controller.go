@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Error untuk pengaturan role dan permission
+var (
+	errAdminRoleLocked  = errors.New("the admin role always has every permission and cannot be edited")
+	errUnknownRole      = errors.New("unknown role")
+	errChangeOwnRole    = errors.New("you cannot change your own role")
+	errCrewRoleViaCrews = errors.New("the crew role is granted by adding the user to a crew")
+
+	errAdminRoleViaInvitation = errors.New("admins can only be added through an invitation (/api/v1/admin/invitations)")
+)
+
+// Struct untuk respons permission satu role
+type RolePermissionsResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	Editable    bool     `json:"editable"` // Admin selalu memiliki semua permission
+}
+
+// loadRolePermissions mengambil permission setiap role yang dikenal, urut sesuai models.Roles
+func loadRolePermissions() ([]RolePermissionsResponse, error) {
+	var rows []models.RolePermission
+	if err := config.DB.Order("role, permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byRole := map[string][]string{}
+	for _, row := range rows {
+		byRole[row.Role] = append(byRole[row.Role], row.Permission)
+	}
+
+	responses := []RolePermissionsResponse{}
+	for _, role := range models.Roles {
+		permissions := byRole[role]
+		if role == models.RoleAdmin {
+			permissions = models.Permissions
+		}
+		if permissions == nil {
+			permissions = []string{}
+		}
+		responses = append(responses, RolePermissionsResponse{
+			Role:        role,
+			Permissions: permissions,
+			Editable:    role != models.RoleAdmin,
+		})
+	}
+	return responses, nil
+}
+
+// Fungsi untuk menampilkan semua role beserta permission-nya
+func GetRoles(c echo.Context) error {
+	roles, err := loadRolePermissions()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve roles", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Roles retrieved successfully", http.StatusOK, "success", map[string]interface{}{
+		"roles":       roles,
+		"permissions": models.Permissions,
+	}))
+}
+
+// Fungsi untuk mengganti seluruh permission sebuah role. Perubahan langsung berlaku di request berikutnya.
+func UpdateRolePermissions(c echo.Context) error {
+	role := c.Param("role")
+	if !models.IsRole(role) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse(errUnknownRole.Error(), http.StatusNotFound, "error", nil))
+	}
+	if role == models.RoleAdmin {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(errAdminRoleLocked.Error(), http.StatusBadRequest, "error", nil))
+	}
+
+	input := struct {
+		Permissions []string `json:"permissions" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	seen := map[string]bool{}
+	permissions := []string{}
+	var unknown []string
+	for _, permission := range input.Permissions {
+		permission = strings.TrimSpace(permission)
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		if !models.IsPermission(permission) {
+			unknown = append(unknown, permission)
+			continue
+		}
+		permissions = append(permissions, permission)
+	}
+	if len(unknown) > 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Unknown permissions", http.StatusBadRequest, "error", unknown))
+	}
+	sort.Strings(permissions)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		for _, permission := range permissions {
+			if err := tx.Create(&models.RolePermission{Role: role, Permission: permission}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update role permissions", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Role permissions updated successfully", http.StatusOK, "success", RolePermissionsResponse{
+		Role:        role,
+		Permissions: permissions,
+		Editable:    true,
+	}))
+}
+
+// Fungsi untuk mengubah role user. Role admin hanya lewat undangan dan role crew lewat keanggotaan tim.
+// Semua sesi user diakhiri agar token baru membawa role yang baru.
+func UpdateUserRole(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid user ID", http.StatusBadRequest, "error", nil))
+	}
+	input := struct {
+		Role string `json:"role" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+	if !models.IsRole(input.Role) {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(errUnknownRole.Error(), http.StatusBadRequest, "error", models.Roles))
+	}
+	if input.Role == models.RoleCrew {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(errCrewRoleViaCrews.Error(), http.StatusBadRequest, "error", nil))
+	}
+	if input.Role == models.RoleAdmin {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse(errAdminRoleViaInvitation.Error(), http.StatusBadRequest, "error", nil))
+	}
+	if actorID, _ := c.Get("userID").(uint); actorID == uint(userID) {
+		return c.JSON(http.StatusForbidden, helper.APIResponse(errChangeOwnRole.Error(), http.StatusForbidden, "error", nil))
+	}
+
+	var user models.User
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.Role == input.Role {
+			return nil
+		}
+		// Anggota tim yang dipindah ke role lain dikeluarkan dari timnya
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.CrewMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("role", input.Role).Error; err != nil {
+			return err
+		}
+		_, err := revokeSessions(tx, user.ID, 0)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("User not found", http.StatusNotFound, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to update user role", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("User role updated successfully", http.StatusOK, "success", map[string]interface{}{
+		"id_user": user.ID,
+		"role":    user.Role,
+	}))
+}
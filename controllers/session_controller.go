@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Struct untuk respons sesi login
+type UserSessionResponse struct {
+	ID         uint   `json:"id"`
+	DeviceName string `json:"device_name"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	Current    bool   `json:"current"` // Sesi yang dipakai untuk request ini
+}
+
+// deviceNameFromUserAgent menebak nama perangkat dari User-Agent jika client tidak mengirim device_name
+func deviceNameFromUserAgent(userAgent string) string {
+	platforms := []struct{ marker, name string }{
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Macintosh", "Mac"},
+		{"CrOS", "Chromebook"},
+		{"Linux", "Linux"},
+	}
+	for _, platform := range platforms {
+		if strings.Contains(userAgent, platform.marker) {
+			return platform.name
+		}
+	}
+	return "Unknown device"
+}
+
+// startSession mencatat sesi baru saat login dan mengembalikan family refresh token-nya
+func startSession(tx *gorm.DB, c echo.Context, userID uint, deviceName string) (models.UserSession, error) {
+	familyID, err := helper.RandomToken(16)
+	if err != nil {
+		return models.UserSession{}, err
+	}
+	userAgent := c.Request().UserAgent()
+	deviceName = strings.TrimSpace(deviceName)
+	if deviceName == "" {
+		deviceName = deviceNameFromUserAgent(userAgent)
+	}
+	session := models.UserSession{
+		UserID:     userID,
+		FamilyID:   familyID,
+		DeviceName: truncateRunes(deviceName, 100),
+		IPAddress:  truncateRunes(c.RealIP(), 45),
+		UserAgent:  truncateRunes(userAgent, 255),
+		LastUsedAt: time.Now(),
+	}
+	return session, tx.Create(&session).Error
+}
+
+// touchSession memperbarui waktu dan IP terakhir sesi saat refresh token ditukar
+func touchSession(tx *gorm.DB, c echo.Context, familyID string) error {
+	return tx.Model(&models.UserSession{}).Where("family_id = ?", familyID).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"ip_address":   truncateRunes(c.RealIP(), 45),
+	}).Error
+}
+
+// revokeSessions mengakhiri sesi aktif user beserta family refresh token-nya; sessionID 0 berarti semua sesi.
+// Access token dari sesi tersebut langsung ditolak oleh AuthMiddleware.
+func revokeSessions(tx *gorm.DB, userID uint, sessionID uint) (int, error) {
+	query := tx.Where("user_id = ? AND revoked_at IS NULL", userID)
+	if sessionID != 0 {
+		query = query.Where("id = ?", sessionID)
+	}
+	var sessions []models.UserSession
+	if err := query.Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, session := range sessions {
+		if err := tx.Model(&session).Update("revoked_at", now).Error; err != nil {
+			return 0, err
+		}
+		if err := revokeTokenFamily(tx, session.FamilyID); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// endSession mengakhiri sesi berdasarkan family refresh token, dipakai saat logout
+func endSession(tx *gorm.DB, familyID string) error {
+	if err := tx.Model(&models.UserSession{}).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return revokeTokenFamily(tx, familyID)
+}
+
+// loadUserSessions mengambil sesi aktif user, yang terakhir dipakai lebih dulu
+func loadUserSessions(userID uint, currentFamilyID string) ([]UserSessionResponse, error) {
+	var sessions []models.UserSession
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_used_at DESC, id DESC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	responses := []UserSessionResponse{}
+	for _, session := range sessions {
+		responses = append(responses, UserSessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.Format("2006-01-02 15:04:05"),
+			LastUsedAt: session.LastUsedAt.Format("2006-01-02 15:04:05"),
+			Current:    currentFamilyID != "" && session.FamilyID == currentFamilyID,
+		})
+	}
+	return responses, nil
+}
+
+// Fungsi untuk menampilkan perangkat tempat user sedang login
+func GetMySessions(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	sessionID, _ := c.Get("sessionID").(string)
+
+	sessions, err := loadUserSessions(userID, sessionID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to retrieve sessions", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Sessions retrieved successfully", http.StatusOK, "success", sessions))
+}
+
+// Fungsi untuk mengakhiri satu sesi milik user
+func RevokeMySession(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	return revokeSessionResponse(c, userID, c.Param("id"))
+}
+
+// Fungsi untuk keluar dari semua perangkat, termasuk sesi yang sedang dipakai
+func RevokeAllMySessions(c echo.Context) error {
+	userID, ok := c.Get("userID").(uint)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+	}
+	return revokeAllSessionsResponse(c, userID)
+}
+
+// Fungsi untuk mengakhiri satu sesi user tertentu (admin)
+func AdminRevokeUserSession(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid user ID", http.StatusBadRequest, "error", nil))
+	}
+	return revokeSessionResponse(c, uint(userID), c.Param("session_id"))
+}
+
+// Fungsi untuk mengakhiri semua sesi user tertentu (admin), misalnya akun yang dibobol atau diblokir
+func AdminRevokeUserSessions(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid user ID", http.StatusBadRequest, "error", nil))
+	}
+	return revokeAllSessionsResponse(c, uint(userID))
+}
+
+func revokeSessionResponse(c echo.Context, userID uint, param string) error {
+	sessionID, err := strconv.Atoi(param)
+	if err != nil || sessionID <= 0 {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid session ID", http.StatusBadRequest, "error", nil))
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		revoked, err := revokeSessions(tx, userID, uint(sessionID))
+		if err == nil && revoked == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, helper.APIResponse("Session not found", http.StatusNotFound, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to revoke session", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("Session revoked successfully", http.StatusOK, "success", nil))
+}
+
+func revokeAllSessionsResponse(c echo.Context, userID uint) error {
+	var revoked int
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revoked, err = revokeSessions(tx, userID, 0)
+		return err
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to revoke sessions", http.StatusInternalServerError, "error", nil))
+	}
+	return c.JSON(http.StatusOK, helper.APIResponse("All sessions revoked successfully", http.StatusOK, "success", map[string]int{
+		"revoked": revoked,
+	}))
+}
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// exportOptions berisi pilihan ekspor tabel yang dibaca dari query string
+type exportOptions struct {
+	Format   string         // csv atau xlsx
+	Columns  []string       // Kunci kolom yang diekspor, urut sesuai permintaan
+	Lang     string         // Bahasa judul kolom: en atau id
+	Location *time.Location // Zona waktu untuk kolom tanggal
+}
+
+// exportColumn mendefinisikan satu kolom ekspor untuk baris bertipe T
+type exportColumn[T any] struct {
+	Key      string
+	HeaderEN string
+	HeaderID string
+	Numeric  bool
+	Value    func(row *T, loc *time.Location) string
+}
+
+// tableExport adalah data yang bisa diekspor sebagai CSV/XLSX
+type tableExport interface {
+	columnKeys() []string
+	query(c echo.Context, loc *time.Location) (*gorm.DB, error)
+	write(rows *sql.Rows, options exportOptions, table helper.TableWriter, flush func() error) (int, error)
+}
+
+type tableExportSpec[T any] struct {
+	columns    []exportColumn[T]
+	buildQuery func(c echo.Context, loc *time.Location) (*gorm.DB, error)
+}
+
+func (s tableExportSpec[T]) columnKeys() []string {
+	keys := make([]string, 0, len(s.columns))
+	for _, column := range s.columns {
+		keys = append(keys, column.Key)
+	}
+	return keys
+}
+
+func (s tableExportSpec[T]) query(c echo.Context, loc *time.Location) (*gorm.DB, error) {
+	return s.buildQuery(c, loc)
+}
+
+// write menulis baris judul lalu setiap baris hasil query. flush dipanggil setiap exportFlushEvery baris.
+func (s tableExportSpec[T]) write(rows *sql.Rows, options exportOptions, table helper.TableWriter, flush func() error) (int, error) {
+	columns := make([]exportColumn[T], 0, len(options.Columns))
+	for _, key := range options.Columns {
+		for _, column := range s.columns {
+			if column.Key == key {
+				columns = append(columns, column)
+				break
+			}
+		}
+	}
+
+	cells := make([]helper.TableCell, len(columns))
+	for i, column := range columns {
+		cells[i] = helper.TableCell{Value: column.HeaderEN}
+		if options.Lang == "id" {
+			cells[i].Value = column.HeaderID
+		}
+	}
+	if err := table.WriteRow(cells); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for rows.Next() {
+		var row T
+		if err := config.DB.ScanRows(rows, &row); err != nil {
+			return count, err
+		}
+		for i, column := range columns {
+			cells[i] = helper.TableCell{Value: column.Value(&row, options.Location), Numeric: column.Numeric}
+		}
+		if err := table.WriteRow(cells); err != nil {
+			return count, err
+		}
+		count++
+
+		if count%exportFlushEvery == 0 {
+			if err := table.Flush(); err != nil {
+				return count, err
+			}
+			if flush != nil {
+				if err := flush(); err != nil {
+					return count, err
+				}
+			}
+		}
+	}
+	return count, rows.Err()
+}
+
+// tableExports berisi data yang bisa diekspor lewat /admin/exports/:resource
+var tableExports = map[string]tableExport{
+	"reports":            reportTableExport,
+	"users":              userTableExport,
+	"points":             pointBalanceTableExport,
+	"point-transactions": pointTransactionTableExport,
+}
+
+// Format nilai kolom ekspor
+func exportTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05")
+}
+
+func exportDate(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("2006-01-02")
+}
+
+func exportUint(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
+
+func exportOptionalUint(value *uint) string {
+	if value == nil {
+		return ""
+	}
+	return exportUint(*value)
+}
+
+func exportFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+type reportExportRow struct {
+	ID             uint
+	TanggalLaporan time.Time
+	Status         string
+	Category       string
+	Description    string
+	Location       string
+	Street         string
+	Kelurahan      string
+	Kecamatan      string
+	City           string
+	Province       string
+	RegionID       *uint
+	Latitude       float64
+	Longitude      float64
+	Photo          string
+	UserID         uint
+	ReporterName   string
+	ReporterEmail  string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Ekspor laporan memakai filter yang sama dengan GetAllReportRubbish ditambah rentang tanggal laporan
+var reportTableExport = tableExportSpec[reportExportRow]{
+	columns: []exportColumn[reportExportRow]{
+		{"id", "Report ID", "ID Laporan", true, func(r *reportExportRow, _ *time.Location) string { return exportUint(r.ID) }},
+		{"tanggal_laporan", "Report Date", "Tanggal Laporan", false, func(r *reportExportRow, loc *time.Location) string { return exportDate(r.TanggalLaporan, loc) }},
+		{"status", "Status", "Status", false, func(r *reportExportRow, _ *time.Location) string { return r.Status }},
+		{"category", "Category", "Kategori", false, func(r *reportExportRow, _ *time.Location) string { return r.Category }},
+		{"description", "Description", "Deskripsi", false, func(r *reportExportRow, _ *time.Location) string { return r.Description }},
+		{"location", "Location", "Lokasi", false, func(r *reportExportRow, _ *time.Location) string { return r.Location }},
+		{"street", "Street", "Jalan", false, func(r *reportExportRow, _ *time.Location) string { return r.Street }},
+		{"kelurahan", "Village", "Kelurahan", false, func(r *reportExportRow, _ *time.Location) string { return r.Kelurahan }},
+		{"kecamatan", "District", "Kecamatan", false, func(r *reportExportRow, _ *time.Location) string { return r.Kecamatan }},
+		{"city", "City", "Kota/Kabupaten", false, func(r *reportExportRow, _ *time.Location) string { return r.City }},
+		{"province", "Province", "Provinsi", false, func(r *reportExportRow, _ *time.Location) string { return r.Province }},
+		{"region_id", "Region ID", "ID Wilayah", true, func(r *reportExportRow, _ *time.Location) string { return exportOptionalUint(r.RegionID) }},
+		{"latitude", "Latitude", "Lintang", true, func(r *reportExportRow, _ *time.Location) string { return exportFloat(r.Latitude) }},
+		{"longitude", "Longitude", "Bujur", true, func(r *reportExportRow, _ *time.Location) string { return exportFloat(r.Longitude) }},
+		{"photo_url", "Photo URL", "URL Foto", false, func(r *reportExportRow, _ *time.Location) string { return r.Photo }},
+		{"reporter_id", "Reporter ID", "ID Pelapor", true, func(r *reportExportRow, _ *time.Location) string { return exportUint(r.UserID) }},
+		{"reporter_name", "Reporter Name", "Nama Pelapor", false, func(r *reportExportRow, _ *time.Location) string { return r.ReporterName }},
+		{"reporter_email", "Reporter Email", "Email Pelapor", false, func(r *reportExportRow, _ *time.Location) string { return r.ReporterEmail }},
+		{"created_at", "Created At", "Dibuat Pada", false, func(r *reportExportRow, loc *time.Location) string { return exportTime(r.CreatedAt, loc) }},
+		{"updated_at", "Updated At", "Diperbarui Pada", false, func(r *reportExportRow, loc *time.Location) string { return exportTime(r.UpdatedAt, loc) }},
+	},
+	buildQuery: func(c echo.Context, loc *time.Location) (*gorm.DB, error) {
+		start, end, ok := parseDateRangeParamsIn(c, loc)
+		if !ok {
+			return nil, errInvalidExportDateRange
+		}
+		db, err := applyReportListFilters(config.DB.Table("report_rubbishes"), c)
+		if err != nil {
+			return nil, err
+		}
+		return applyDateRange(db, start, end).
+			Select("report_rubbishes.id, report_rubbishes.tanggal_laporan, report_rubbishes.status, report_rubbishes.category, " +
+				"report_rubbishes.description, report_rubbishes.location, report_rubbishes.street, report_rubbishes.kelurahan, " +
+				"report_rubbishes.kecamatan, report_rubbishes.city, report_rubbishes.province, report_rubbishes.region_id, " +
+				"report_rubbishes.latitude, report_rubbishes.longitude, report_rubbishes.photo, report_rubbishes.user_id, " +
+				"users.nama_lengkap AS reporter_name, users.email AS reporter_email, " +
+				"report_rubbishes.created_at, report_rubbishes.updated_at").
+			Joins("LEFT JOIN users ON users.id = report_rubbishes.user_id").
+			Order("report_rubbishes.tanggal_laporan ASC, report_rubbishes.id ASC"), nil
+	},
+}
+
+type userExportRow struct {
+	ID           uint
+	NamaLengkap  string
+	Email        string
+	NoTelepon    string
+	TanggalLahir time.Time
+	Role         string
+	Points       uint
+	CreatedAt    time.Time
+}
+
+// Ekspor pengguna, sama seperti GetAllUsers tanpa paginasi
+var userTableExport = tableExportSpec[userExportRow]{
+	columns: []exportColumn[userExportRow]{
+		{"id", "User ID", "ID Pengguna", true, func(r *userExportRow, _ *time.Location) string { return exportUint(r.ID) }},
+		{"nama_lengkap", "Full Name", "Nama Lengkap", false, func(r *userExportRow, _ *time.Location) string { return r.NamaLengkap }},
+		{"email", "Email", "Email", false, func(r *userExportRow, _ *time.Location) string { return r.Email }},
+		{"no_telepon", "Phone Number", "No. Telepon", false, func(r *userExportRow, _ *time.Location) string { return r.NoTelepon }},
+		// Tanggal lahir disimpan tanpa jam sehingga tidak dikonversi ke zona waktu lain
+		{"tanggal_lahir", "Date of Birth", "Tanggal Lahir", false, func(r *userExportRow, _ *time.Location) string {
+			return exportDate(r.TanggalLahir, r.TanggalLahir.Location())
+		}},
+		{"role", "Role", "Peran", false, func(r *userExportRow, _ *time.Location) string { return r.Role }},
+		{"points", "Points", "Poin", true, func(r *userExportRow, _ *time.Location) string { return exportUint(r.Points) }},
+		{"created_at", "Registered At", "Terdaftar Pada", false, func(r *userExportRow, loc *time.Location) string { return exportTime(r.CreatedAt, loc) }},
+	},
+	buildQuery: func(c echo.Context, _ *time.Location) (*gorm.DB, error) {
+		return config.DB.Model(&models.User{}).
+			Select("id, nama_lengkap, email, no_telepon, tanggal_lahir, role, points, created_at").
+			Order("id ASC"), nil
+	},
+}
+
+type pointBalanceExportRow struct {
+	UserID      uint
+	NamaLengkap string
+	Email       string
+	NoTelepon   string
+	Points      uint
+	TotalEarned int
+	TotalSpent  int
+}
+
+// Ekspor saldo poin per pengguna, sama seperti GetAllUserPoints
+var pointBalanceTableExport = tableExportSpec[pointBalanceExportRow]{
+	columns: []exportColumn[pointBalanceExportRow]{
+		{"user_id", "User ID", "ID Pengguna", true, func(r *pointBalanceExportRow, _ *time.Location) string { return exportUint(r.UserID) }},
+		{"nama_lengkap", "Full Name", "Nama Lengkap", false, func(r *pointBalanceExportRow, _ *time.Location) string { return r.NamaLengkap }},
+		{"email", "Email", "Email", false, func(r *pointBalanceExportRow, _ *time.Location) string { return r.Email }},
+		{"no_telepon", "Phone Number", "No. Telepon", false, func(r *pointBalanceExportRow, _ *time.Location) string { return r.NoTelepon }},
+		{"points", "Balance", "Saldo Poin", true, func(r *pointBalanceExportRow, _ *time.Location) string { return exportUint(r.Points) }},
+		{"total_earned", "Total Earned", "Total Poin Masuk", true, func(r *pointBalanceExportRow, _ *time.Location) string { return strconv.Itoa(r.TotalEarned) }},
+		{"total_spent", "Total Spent", "Total Poin Keluar", true, func(r *pointBalanceExportRow, _ *time.Location) string { return strconv.Itoa(r.TotalSpent) }},
+	},
+	buildQuery: func(c echo.Context, _ *time.Location) (*gorm.DB, error) {
+		return config.DB.Table("users").
+			Select("users.id AS user_id, users.points, users.nama_lengkap, users.email, users.no_telepon, " +
+				"COALESCE(SUM(CASE WHEN pt.delta > 0 THEN pt.delta ELSE 0 END), 0) AS total_earned, " +
+				"COALESCE(SUM(CASE WHEN pt.delta < 0 THEN -pt.delta ELSE 0 END), 0) AS total_spent").
+			Joins("LEFT JOIN point_transactions pt ON pt.user_id = users.id").
+			Group("users.id, users.points, users.nama_lengkap, users.email, users.no_telepon").
+			Order("users.id"), nil
+	},
+}
+
+type pointTransactionExportRow struct {
+	ID           uint
+	UserID       uint
+	NamaLengkap  string
+	Delta        int
+	BalanceAfter uint
+	Reason       string
+	ReportID     *uint
+	RedemptionID *uint
+	ActorID      *uint
+	Note         string
+	CreatedAt    time.Time
+}
+
+// Ekspor ledger poin, bisa difilter dengan user_id, reason dan rentang tanggal transaksi
+var pointTransactionTableExport = tableExportSpec[pointTransactionExportRow]{
+	columns: []exportColumn[pointTransactionExportRow]{
+		{"id", "Transaction ID", "ID Transaksi", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportUint(r.ID) }},
+		{"created_at", "Date", "Tanggal", false, func(r *pointTransactionExportRow, loc *time.Location) string { return exportTime(r.CreatedAt, loc) }},
+		{"user_id", "User ID", "ID Pengguna", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportUint(r.UserID) }},
+		{"nama_lengkap", "Full Name", "Nama Lengkap", false, func(r *pointTransactionExportRow, _ *time.Location) string { return r.NamaLengkap }},
+		{"delta", "Points", "Poin", true, func(r *pointTransactionExportRow, _ *time.Location) string { return strconv.Itoa(r.Delta) }},
+		{"balance_after", "Balance After", "Saldo Akhir", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportUint(r.BalanceAfter) }},
+		{"reason", "Reason", "Alasan", false, func(r *pointTransactionExportRow, _ *time.Location) string { return r.Reason }},
+		{"report_id", "Report ID", "ID Laporan", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportOptionalUint(r.ReportID) }},
+		{"redemption_id", "Redemption ID", "ID Penukaran", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportOptionalUint(r.RedemptionID) }},
+		{"actor_id", "Actor ID", "ID Admin", true, func(r *pointTransactionExportRow, _ *time.Location) string { return exportOptionalUint(r.ActorID) }},
+		{"note", "Note", "Catatan", false, func(r *pointTransactionExportRow, _ *time.Location) string { return r.Note }},
+	},
+	buildQuery: func(c echo.Context, loc *time.Location) (*gorm.DB, error) {
+		start, end, ok := parseDateRangeParamsIn(c, loc)
+		if !ok {
+			return nil, errInvalidExportDateRange
+		}
+		db := config.DB.Table("point_transactions pt").
+			Select("pt.id, pt.user_id, users.nama_lengkap, pt.delta, pt.balance_after, pt.reason, " +
+				"pt.report_id, pt.redemption_id, pt.actor_id, pt.note, pt.created_at").
+			Joins("LEFT JOIN users ON users.id = pt.user_id")
+		if userID := c.QueryParam("user_id"); userID != "" {
+			id, err := strconv.Atoi(userID)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("%w: invalid user_id", errInvalidExportFilter)
+			}
+			db = db.Where("pt.user_id = ?", id)
+		}
+		if reason := c.QueryParam("reason"); reason != "" {
+			db = db.Where("pt.reason IN ?", strings.Split(reason, ","))
+		}
+		if start != nil {
+			db = db.Where("pt.created_at >= ?", *start)
+		}
+		if end != nil {
+			db = db.Where("pt.created_at < ?", *end)
+		}
+		return db.Order("pt.created_at ASC, pt.id ASC"), nil
+	},
+}
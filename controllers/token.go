@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Masa berlaku bawaan token, bisa diubah lewat ACCESS_TOKEN_TTL_MINUTES dan REFRESH_TOKEN_TTL_HOURS
+const (
+	defaultAccessTokenTTLMinutes = 15
+	defaultRefreshTokenTTLHours  = 24 * 30
+)
+
+// Error untuk refresh token
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, all sessions of this login were revoked")
+)
+
+// Struct untuk pasangan access token dan refresh token
+type TokenPair struct {
+	Token            string    `json:"token"`
+	TokenExpiresAt   time.Time `json:"token_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+func accessTokenTTL() time.Duration {
+	minutes := config.GetEnvInt("ACCESS_TOKEN_TTL_MINUTES", defaultAccessTokenTTLMinutes)
+	if minutes < 1 {
+		minutes = defaultAccessTokenTTLMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func refreshTokenTTL() time.Duration {
+	hours := config.GetEnvInt("REFRESH_TOKEN_TTL_HOURS", defaultRefreshTokenTTLHours)
+	if hours < 1 {
+		hours = defaultRefreshTokenTTLHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// issueTokenPair membuat access token baru dan refresh token baru di family (sesi) yang diberikan
+func issueTokenPair(tx *gorm.DB, user models.User, familyID string) (TokenPair, error) {
+	var pair TokenPair
+	refreshToken, err := helper.RandomToken(32)
+	if err != nil {
+		return pair, err
+	}
+	now := time.Now()
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: helper.HashToken(refreshToken),
+		ExpiresAt: now.Add(refreshTokenTTL()),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return pair, err
+	}
+
+	token, expiresAt, err := GenerateJWT(user.ID, user.NamaLengkap, user.Role, familyID)
+	if err != nil {
+		return pair, err
+	}
+	return TokenPair{
+		Token:            token,
+		TokenExpiresAt:   expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// revokeTokenFamily mencabut semua refresh token yang masih aktif di satu family
+func revokeTokenFamily(tx *gorm.DB, familyID string) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeAccessToken memasukkan jti access token ke daftar tolak sampai token kedaluwarsa
+func revokeAccessToken(tx *gorm.DB, jti string, userID uint, expiresAt time.Time) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// PurgeExpiredTokens menghapus refresh token dan daftar tolak access token yang sudah kedaluwarsa
+func PurgeExpiredTokens() error {
+	now := time.Now()
+	if err := config.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return config.DB.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error
+}
+
+// Fungsi untuk menukar refresh token dengan access token dan refresh token baru.
+// Refresh token lama tidak bisa dipakai lagi; jika dipakai ulang, seluruh family dicabut.
+func RefreshTokenHandler(c echo.Context) error {
+	input := struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}{}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Invalid input format", http.StatusBadRequest, "error", nil))
+	}
+	if err := c.Validate(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, helper.APIResponse("Validation error", http.StatusBadRequest, "error", helper.FormatValidationError(err)))
+	}
+
+	var pair TokenPair
+	reused := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", helper.HashToken(input.RefreshToken)).
+			First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		if record.UsedAt != nil {
+			// Sesi dan family diakhiri; transaksi tetap di-commit agar pencabutan tersimpan
+			reused = true
+			return endSession(tx, record.FamilyID)
+		}
+
+		var user models.User
+		if err := tx.First(&user, record.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if err := tx.Model(&record).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := touchSession(tx, c, record.FamilyID); err != nil {
+			return err
+		}
+		var err error
+		pair, err = issueTokenPair(tx, user, record.FamilyID)
+		return err
+	})
+	if err == nil && reused {
+		err = ErrRefreshTokenReused
+	}
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		return c.JSON(http.StatusUnauthorized, helper.APIResponse(err.Error(), http.StatusUnauthorized, "error", nil))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to refresh token", http.StatusInternalServerError, "error", nil))
+	}
+
+	return c.JSON(http.StatusOK, helper.APIResponse("Token refreshed successfully", http.StatusOK, "success", pair))
+}
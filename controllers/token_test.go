@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"Backend-Recything/internal/testdb"
+	"Backend-Recything/middlewares"
+	"Backend-Recything/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// refreshRequest memanggil RefreshTokenHandler dan mengembalikan status beserta pasangan token baru
+func refreshRequest(t *testing.T, refreshToken string) (int, TokenPair) {
+	t.Helper()
+	e := echo.New()
+	e.Validator = &middlewares.CustomValidator{Validator: validator.New()}
+	body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/token/refresh", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	if err := RefreshTokenHandler(e.NewContext(req, rec)); err != nil {
+		t.Fatalf("RefreshTokenHandler() error = %v", err)
+	}
+	var response struct {
+		Data TokenPair `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return rec.Code, response.Data
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+
+	// Setiap langkah mengirim refresh token: "first" adalah token dari login,
+	// "latest" adalah token terbaru hasil rotasi dan "unknown" token yang tidak pernah diterbitkan
+	type step struct {
+		send       string
+		wantStatus int
+	}
+	tests := []struct {
+		name               string
+		setup              func(t *testing.T, db *gorm.DB, familyID string)
+		steps              []step
+		wantSessionRevoked bool
+	}{
+		{
+			name:  "token rotates on every refresh",
+			steps: []step{{"first", http.StatusOK}, {"latest", http.StatusOK}, {"latest", http.StatusOK}},
+		},
+		{
+			name:               "reused token ends the session",
+			steps:              []step{{"first", http.StatusOK}, {"first", http.StatusUnauthorized}, {"latest", http.StatusUnauthorized}},
+			wantSessionRevoked: true,
+		},
+		{
+			name:               "reuse after several rotations revokes the newest token",
+			steps:              []step{{"first", http.StatusOK}, {"latest", http.StatusOK}, {"first", http.StatusUnauthorized}, {"latest", http.StatusUnauthorized}},
+			wantSessionRevoked: true,
+		},
+		{
+			name:  "unknown token",
+			steps: []step{{"unknown", http.StatusUnauthorized}, {"first", http.StatusOK}},
+		},
+		{
+			name: "expired token",
+			setup: func(t *testing.T, db *gorm.DB, familyID string) {
+				db.Model(&models.RefreshToken{}).Where("family_id = ?", familyID).Update("expires_at", time.Now().Add(-time.Minute))
+			},
+			steps: []step{{"first", http.StatusUnauthorized}},
+		},
+		{
+			name: "token of a logged out session",
+			setup: func(t *testing.T, db *gorm.DB, familyID string) {
+				if err := endSession(db, familyID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			steps:              []step{{"first", http.StatusUnauthorized}},
+			wantSessionRevoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t, &models.User{}, &models.UserSession{}, &models.RefreshToken{})
+			user := createTestUser(t, db, 0)
+			session := models.UserSession{UserID: user.ID, FamilyID: "family-1", LastUsedAt: time.Now()}
+			if err := db.Create(&session).Error; err != nil {
+				t.Fatal(err)
+			}
+			pair, err := issueTokenPair(db, user, session.FamilyID)
+			if err != nil {
+				t.Fatalf("issueTokenPair() error = %v", err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, db, session.FamilyID)
+			}
+
+			first, latest := pair.RefreshToken, pair.RefreshToken
+			for i, s := range tt.steps {
+				token := map[string]string{"first": first, "latest": latest, "unknown": "not-a-real-token"}[s.send]
+				status, next := refreshRequest(t, token)
+				if status != s.wantStatus {
+					t.Fatalf("step %d (%s) status = %d, want %d", i, s.send, status, s.wantStatus)
+				}
+				if status != http.StatusOK {
+					continue
+				}
+				if next.RefreshToken == "" || next.RefreshToken == token || next.Token == "" {
+					t.Fatalf("step %d (%s) did not rotate the refresh token", i, s.send)
+				}
+				latest = next.RefreshToken
+			}
+
+			var stored models.UserSession
+			if err := db.First(&stored, session.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if revoked := stored.RevokedAt != nil; revoked != tt.wantSessionRevoked {
+				t.Errorf("session revoked = %v, want %v", revoked, tt.wantSessionRevoked)
+			}
+			if tt.wantSessionRevoked {
+				var active int64
+				db.Model(&models.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", session.FamilyID).Count(&active)
+				if active != 0 {
+					t.Errorf("%d refresh tokens still active after the session ended", active)
+				}
+			}
+		})
+	}
+}
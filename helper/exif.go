@@ -0,0 +1,375 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error untuk pembacaan dan pembersihan metadata foto
+var (
+	ErrNoExif           = errors.New("image has no EXIF metadata") // Gambar bukan JPEG atau tidak memiliki segmen EXIF
+	ErrUnsupportedImage = errors.New("only JPEG and PNG images are supported")
+)
+
+// Tag EXIF yang dibaca
+const (
+	exifTagMake               = 0x010F
+	exifTagModel              = 0x0110
+	exifTagDateTime           = 0x0132
+	exifTagExifIFD            = 0x8769
+	exifTagGPSIFD             = 0x8825
+	exifTagDateTimeOriginal   = 0x9003
+	exifTagOffsetTimeOriginal = 0x9011
+	gpsTagLatitudeRef         = 0x0001
+	gpsTagLatitude            = 0x0002
+	gpsTagLongitudeRef        = 0x0003
+	gpsTagLongitude           = 0x0004
+)
+
+// Ukuran byte per tipe data TIFF (indeks = kode tipe)
+var tiffTypeSizes = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+var exifHeader = []byte("Exif\x00\x00")
+var xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+// ExifData berisi metadata foto yang dipakai untuk cek keaslian laporan
+type ExifData struct {
+	TakenAt   *time.Time // DateTimeOriginal, atau DateTime jika tidak ada
+	Latitude  *float64
+	Longitude *float64
+	Camera    string // Make dan Model kamera
+}
+
+type tiffEntry struct {
+	tag       uint16
+	typ       uint16
+	count     uint32
+	entryPos  int // Posisi entri di dalam data TIFF
+	valuePos  int // Posisi nilai (inline atau lewat offset) di dalam data TIFF
+	valueSize int
+}
+
+// tiffReader membaca IFD dari blok TIFF di dalam segmen EXIF
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newTIFFReader(data []byte) (*tiffReader, error) {
+	if len(data) < 8 {
+		return nil, ErrNoExif
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, ErrNoExif
+	}
+	return &tiffReader{data: data, order: order}, nil
+}
+
+// ifd membaca semua entri di IFD pada offset tertentu. Entri yang rusak dilewati.
+func (r *tiffReader) ifd(offset int) []tiffEntry {
+	if offset < 8 || offset+2 > len(r.data) {
+		return nil
+	}
+	count := int(r.order.Uint16(r.data[offset:]))
+	entries := make([]tiffEntry, 0, count)
+	for i := 0; i < count; i++ {
+		pos := offset + 2 + i*12
+		if pos+12 > len(r.data) {
+			break
+		}
+		entry := tiffEntry{
+			tag:      r.order.Uint16(r.data[pos:]),
+			typ:      r.order.Uint16(r.data[pos+2:]),
+			count:    r.order.Uint32(r.data[pos+4:]),
+			entryPos: pos,
+		}
+		if int(entry.typ) >= len(tiffTypeSizes) || tiffTypeSizes[entry.typ] == 0 || entry.count > 1<<20 {
+			continue
+		}
+		entry.valueSize = tiffTypeSizes[entry.typ] * int(entry.count)
+		entry.valuePos = pos + 8
+		if entry.valueSize > 4 {
+			entry.valuePos = int(r.order.Uint32(r.data[pos+8:]))
+		}
+		if entry.valuePos+entry.valueSize > len(r.data) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (r *tiffReader) ifd0Offset() int {
+	return int(r.order.Uint32(r.data[4:8]))
+}
+
+func (r *tiffReader) uint32Value(entry tiffEntry) int {
+	if entry.valueSize < 4 {
+		return 0
+	}
+	return int(r.order.Uint32(r.data[entry.valuePos:]))
+}
+
+func (r *tiffReader) stringValue(entry tiffEntry) string {
+	value := r.data[entry.valuePos : entry.valuePos+entry.valueSize]
+	return strings.TrimSpace(string(bytes.TrimRight(value, "\x00")))
+}
+
+// rationalValues membaca nilai bertipe RATIONAL (pembilang/penyebut uint32)
+func (r *tiffReader) rationalValues(entry tiffEntry) []float64 {
+	if entry.typ != 5 {
+		return nil
+	}
+	values := make([]float64, 0, entry.count)
+	for i := 0; i < int(entry.count); i++ {
+		pos := entry.valuePos + i*8
+		num, den := r.order.Uint32(r.data[pos:]), r.order.Uint32(r.data[pos+4:])
+		if den == 0 {
+			return nil
+		}
+		values = append(values, float64(num)/float64(den))
+	}
+	return values
+}
+
+// subIFD mencari pointer ke sub-IFD (Exif atau GPS) di IFD0
+func (r *tiffReader) subIFD(ifd0 []tiffEntry, tag uint16) (tiffEntry, []tiffEntry) {
+	for _, entry := range ifd0 {
+		if entry.tag == tag {
+			return entry, r.ifd(r.uint32Value(entry))
+		}
+	}
+	return tiffEntry{}, nil
+}
+
+// findExifTIFF mengembalikan posisi awal dan akhir blok TIFF di dalam JPEG
+func findExifTIFF(data []byte) (int, int, error) {
+	start, end := -1, -1
+	err := walkJPEGSegments(data, func(marker byte, segStart, payloadStart, segEnd int) bool {
+		if marker == 0xE1 && bytes.HasPrefix(data[payloadStart:segEnd], exifHeader) {
+			start, end = payloadStart+len(exifHeader), segEnd
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if start < 0 {
+		return 0, 0, ErrNoExif
+	}
+	return start, end, nil
+}
+
+// walkJPEGSegments memanggil fn untuk setiap segmen sebelum data gambar (SOS).
+// fn mengembalikan false untuk berhenti.
+func walkJPEGSegments(data []byte, fn func(marker byte, segStart, payloadStart, segEnd int) bool) error {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return ErrNoExif
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return ErrNoExif
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++ // Byte pengisi
+			continue
+		}
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return ErrNoExif
+		}
+		if !fn(marker, pos, pos+4, pos+2+length) {
+			return nil
+		}
+		pos += 2 + length
+	}
+	return nil
+}
+
+// ReadExif membaca waktu pengambilan, koordinat GPS dan kamera dari foto JPEG
+func ReadExif(data []byte) (ExifData, error) {
+	var result ExifData
+	start, end, err := findExifTIFF(data)
+	if err != nil {
+		return result, err
+	}
+	r, err := newTIFFReader(data[start:end])
+	if err != nil {
+		return result, err
+	}
+
+	ifd0 := r.ifd(r.ifd0Offset())
+	var cameraMake, model, dateTime string
+	for _, entry := range ifd0 {
+		switch entry.tag {
+		case exifTagMake:
+			cameraMake = r.stringValue(entry)
+		case exifTagModel:
+			model = r.stringValue(entry)
+		case exifTagDateTime:
+			dateTime = r.stringValue(entry)
+		}
+	}
+	result.Camera = strings.TrimSpace(cameraMake + " " + model)
+
+	var original, offset string
+	_, exifIFD := r.subIFD(ifd0, exifTagExifIFD)
+	for _, entry := range exifIFD {
+		switch entry.tag {
+		case exifTagDateTimeOriginal:
+			original = r.stringValue(entry)
+		case exifTagOffsetTimeOriginal:
+			offset = r.stringValue(entry)
+		}
+	}
+	if original == "" {
+		original, offset = dateTime, ""
+	}
+	if original != "" {
+		if takenAt, ok := parseExifTime(original, offset); ok {
+			result.TakenAt = &takenAt
+		}
+	}
+
+	var latRef, lngRef string
+	var lat, lng []float64
+	_, gpsIFD := r.subIFD(ifd0, exifTagGPSIFD)
+	for _, entry := range gpsIFD {
+		switch entry.tag {
+		case gpsTagLatitudeRef:
+			latRef = r.stringValue(entry)
+		case gpsTagLatitude:
+			lat = r.rationalValues(entry)
+		case gpsTagLongitudeRef:
+			lngRef = r.stringValue(entry)
+		case gpsTagLongitude:
+			lng = r.rationalValues(entry)
+		}
+	}
+	if len(lat) == 3 && len(lng) == 3 {
+		latitude := lat[0] + lat[1]/60 + lat[2]/3600
+		longitude := lng[0] + lng[1]/60 + lng[2]/3600
+		if latRef == "S" {
+			latitude = -latitude
+		}
+		if lngRef == "W" {
+			longitude = -longitude
+		}
+		// Kamera tanpa sinyal GPS sering menulis 0,0
+		if latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180 && (latitude != 0 || longitude != 0) {
+			result.Latitude, result.Longitude = &latitude, &longitude
+		}
+	}
+	return result, nil
+}
+
+// parseExifTime membaca format "2006:01:02 15:04:05". Tanpa OffsetTimeOriginal,
+// waktu dianggap berada di zona waktu server.
+func parseExifTime(value string, offset string) (time.Time, bool) {
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t, true
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", value, time.Local)
+	if err != nil || t.Year() < 1990 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// SanitizeImage mengembalikan salinan foto yang aman dipublikasikan. JPEG dibersihkan dengan StripGPS,
+// PNG di-encode ulang sehingga semua chunk metadata (eXIf, tEXt, iTXt) terbuang. Format lain seperti
+// WebP dan HEIC tidak bisa dibersihkan tanpa decoder sehingga ditolak dengan ErrUnsupportedImage.
+func SanitizeImage(data []byte) ([]byte, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return StripGPS(data), nil
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedImage
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnsupportedImage
+	}
+}
+
+// StripGPS mengembalikan salinan JPEG tanpa lokasi: isi GPS IFD di EXIF dikosongkan dan
+// segmen XMP (yang juga bisa menyimpan koordinat) dibuang. Metadata lain seperti orientasi
+// tetap dipertahankan. Gambar selain JPEG dikembalikan apa adanya; pakai SanitizeImage untuk upload.
+func StripGPS(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+
+	var xmpSegments [][2]int
+	walkJPEGSegments(data, func(marker byte, segStart, payloadStart, segEnd int) bool {
+		if marker == 0xE1 && bytes.HasPrefix(data[payloadStart:segEnd], xmpHeader) {
+			xmpSegments = append(xmpSegments, [2]int{segStart, segEnd})
+		}
+		return true
+	})
+
+	stripped := make([]byte, 0, len(data))
+	last := 0
+	for _, segment := range xmpSegments {
+		stripped = append(stripped, data[last:segment[0]]...)
+		last = segment[1]
+	}
+	stripped = append(stripped, data[last:]...)
+
+	start, end, err := findExifTIFF(stripped)
+	if err != nil {
+		return stripped
+	}
+	r, err := newTIFFReader(stripped[start:end])
+	if err != nil {
+		return stripped
+	}
+	pointer, gpsIFD := r.subIFD(r.ifd(r.ifd0Offset()), exifTagGPSIFD)
+	if pointer.valueSize == 0 {
+		return stripped
+	}
+	for _, entry := range gpsIFD {
+		if entry.valueSize > 4 {
+			clear(r.data[entry.valuePos : entry.valuePos+entry.valueSize])
+		}
+		clear(r.data[entry.entryPos : entry.entryPos+12])
+	}
+	// GPS IFD dibiarkan ada tetapi tanpa entri
+	offset := r.uint32Value(pointer)
+	if offset+2 <= len(r.data) {
+		r.order.PutUint16(r.data[offset:], 0)
+	}
+	return stripped
+}
@@ -0,0 +1,351 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+)
+
+// testTIFFEntry adalah satu entri IFD untuk membuat blok EXIF di test
+type testTIFFEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, value string) testTIFFEntry {
+	return testTIFFEntry{tag: tag, typ: 2, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+func pointerEntry(tag uint16) testTIFFEntry {
+	return testTIFFEntry{tag: tag, typ: 4, count: 1, value: make([]byte, 4)}
+}
+
+func rationalEntry(tag uint16, values ...uint32) testTIFFEntry {
+	value := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		value = binary.LittleEndian.AppendUint32(value, v)
+		value = binary.LittleEndian.AppendUint32(value, 1)
+	}
+	return testTIFFEntry{tag: tag, typ: 5, count: uint32(len(values)), value: value}
+}
+
+func ifdSize(entries []testTIFFEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, entry := range entries {
+		if len(entry.value) > 4 {
+			size += len(entry.value)
+		}
+	}
+	return size
+}
+
+// encodeIFD menulis IFD little-endian yang diletakkan di offset base dalam blok TIFF
+func encodeIFD(base int, entries []testTIFFEntry) []byte {
+	out := binary.LittleEndian.AppendUint16(nil, uint16(len(entries)))
+	var extra []byte
+	extraPos := base + 2 + 12*len(entries) + 4
+	for _, entry := range entries {
+		out = binary.LittleEndian.AppendUint16(out, entry.tag)
+		out = binary.LittleEndian.AppendUint16(out, entry.typ)
+		out = binary.LittleEndian.AppendUint32(out, entry.count)
+		if len(entry.value) > 4 {
+			out = binary.LittleEndian.AppendUint32(out, uint32(extraPos+len(extra)))
+			extra = append(extra, entry.value...)
+			continue
+		}
+		value := make([]byte, 4)
+		copy(value, entry.value)
+		out = append(out, value...)
+	}
+	out = append(out, 0, 0, 0, 0)
+	return append(out, extra...)
+}
+
+// buildTIFF menyusun IFD0 beserta sub-IFD Exif dan GPS jika ada isinya
+func buildTIFF(ifd0, exifIFD, gpsIFD []testTIFFEntry) []byte {
+	if len(exifIFD) > 0 {
+		ifd0 = append(ifd0, pointerEntry(exifTagExifIFD))
+	}
+	if len(gpsIFD) > 0 {
+		ifd0 = append(ifd0, pointerEntry(exifTagGPSIFD))
+	}
+	exifOffset := 8 + ifdSize(ifd0)
+	gpsOffset := exifOffset
+	if len(exifIFD) > 0 {
+		gpsOffset += ifdSize(exifIFD)
+	}
+	for i := range ifd0 {
+		switch ifd0[i].tag {
+		case exifTagExifIFD:
+			ifd0[i].value = binary.LittleEndian.AppendUint32(nil, uint32(exifOffset))
+		case exifTagGPSIFD:
+			ifd0[i].value = binary.LittleEndian.AppendUint32(nil, uint32(gpsOffset))
+		}
+	}
+
+	out := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	out = append(out, encodeIFD(8, ifd0)...)
+	if len(exifIFD) > 0 {
+		out = append(out, encodeIFD(exifOffset, exifIFD)...)
+	}
+	if len(gpsIFD) > 0 {
+		out = append(out, encodeIFD(gpsOffset, gpsIFD)...)
+	}
+	return out
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 60), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func app1Segment(payload []byte) []byte {
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// buildJPEG membuat JPEG asli lalu menyisipkan segmen APP1 setelah SOI
+func buildJPEG(t *testing.T, segments ...[]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	out := append([]byte{}, encoded[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, encoded[2:]...)
+}
+
+func exifSegment(tiff []byte) []byte {
+	return app1Segment(append(append([]byte{}, exifHeader...), tiff...))
+}
+
+func xmpSegment(xml string) []byte {
+	return app1Segment(append(append([]byte{}, xmpHeader...), xml...))
+}
+
+// gpsEntries menulis koordinat dalam derajat/menit/detik bulat
+func gpsEntries(latRef string, lat [3]uint32, lngRef string, lng [3]uint32) []testTIFFEntry {
+	return []testTIFFEntry{
+		asciiEntry(gpsTagLatitudeRef, latRef),
+		rationalEntry(gpsTagLatitude, lat[0], lat[1], lat[2]),
+		asciiEntry(gpsTagLongitudeRef, lngRef),
+		rationalEntry(gpsTagLongitude, lng[0], lng[1], lng[2]),
+	}
+}
+
+func fullExifJPEG(t *testing.T) []byte {
+	tiff := buildTIFF(
+		[]testTIFFEntry{asciiEntry(exifTagMake, "Canon"), asciiEntry(exifTagModel, "EOS 80D")},
+		[]testTIFFEntry{asciiEntry(exifTagDateTimeOriginal, "2024:03:15 08:30:00"), asciiEntry(exifTagOffsetTimeOriginal, "+07:00")},
+		gpsEntries("S", [3]uint32{6, 12, 36}, "E", [3]uint32{106, 48, 0}),
+	)
+	return buildJPEG(t, exifSegment(tiff), xmpSegment(`<x:xmpmeta exif:GPSLatitude="6,12.6S"/>`))
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestReadExif(t *testing.T) {
+	takenAt := time.Date(2024, 3, 15, 8, 30, 0, 0, time.FixedZone("", 7*3600))
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantErr    error
+		wantCamera string
+		wantTaken  *time.Time
+		wantLat    *float64
+		wantLng    *float64
+	}{
+		{
+			name:       "camera, time with offset and southern GPS",
+			data:       fullExifJPEG(t),
+			wantCamera: "Canon EOS 80D",
+			wantTaken:  &takenAt,
+			wantLat:    ptrFloat(-(6 + 12.0/60 + 36.0/3600)),
+			wantLng:    ptrFloat(106 + 48.0/60),
+		},
+		{
+			name: "western GPS without camera",
+			data: buildJPEG(t, exifSegment(buildTIFF(nil, nil,
+				gpsEntries("N", [3]uint32{40, 42, 0}, "W", [3]uint32{74, 0, 0})))),
+			wantLat: ptrFloat(40 + 42.0/60),
+			wantLng: ptrFloat(-74),
+		},
+		{
+			name: "zero GPS is ignored",
+			data: buildJPEG(t, exifSegment(buildTIFF([]testTIFFEntry{asciiEntry(exifTagMake, "Phone")}, nil,
+				gpsEntries("N", [3]uint32{0, 0, 0}, "E", [3]uint32{0, 0, 0})))),
+			wantCamera: "Phone",
+		},
+		{
+			name: "DateTime is used without DateTimeOriginal",
+			data: buildJPEG(t, exifSegment(buildTIFF([]testTIFFEntry{asciiEntry(exifTagDateTime, "2024:03:15 08:30:00")}, nil, nil))),
+			wantTaken: func() *time.Time {
+				tm := time.Date(2024, 3, 15, 8, 30, 0, 0, time.Local)
+				return &tm
+			}(),
+		},
+		{
+			name: "implausible date is ignored",
+			data: buildJPEG(t, exifSegment(buildTIFF([]testTIFFEntry{asciiEntry(exifTagDateTime, "0000:00:00 00:00:00")}, nil, nil))),
+		},
+		{name: "JPEG without EXIF", data: buildJPEG(t), wantErr: ErrNoExif},
+		{name: "not a JPEG", data: []byte("\x89PNG\r\n\x1a\n"), wantErr: ErrNoExif},
+		{name: "truncated segment", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00}, wantErr: ErrNoExif},
+		{name: "empty", data: nil, wantErr: ErrNoExif},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadExif(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ReadExif() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadExif() error = %v", err)
+			}
+			if got.Camera != tt.wantCamera {
+				t.Errorf("Camera = %q, want %q", got.Camera, tt.wantCamera)
+			}
+			if (got.TakenAt == nil) != (tt.wantTaken == nil) || (got.TakenAt != nil && !got.TakenAt.Equal(*tt.wantTaken)) {
+				t.Errorf("TakenAt = %v, want %v", got.TakenAt, tt.wantTaken)
+			}
+			if (got.Latitude == nil) != (tt.wantLat == nil) || (got.Latitude != nil && !approxEqual(*got.Latitude, *tt.wantLat)) {
+				t.Errorf("Latitude = %v, want %v", derefFloat(got.Latitude), derefFloat(tt.wantLat))
+			}
+			if (got.Longitude == nil) != (tt.wantLng == nil) || (got.Longitude != nil && !approxEqual(*got.Longitude, *tt.wantLng)) {
+				t.Errorf("Longitude = %v, want %v", derefFloat(got.Longitude), derefFloat(tt.wantLng))
+			}
+		})
+	}
+}
+
+func TestStripGPS(t *testing.T) {
+	original := fullExifJPEG(t)
+	before := append([]byte{}, original...)
+
+	stripped := StripGPS(original)
+	if !bytes.Equal(original, before) {
+		t.Fatal("StripGPS modified its input")
+	}
+	if bytes.Contains(stripped, xmpHeader) {
+		t.Error("XMP segment was not removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+
+	exif, err := ReadExif(stripped)
+	if err != nil {
+		t.Fatalf("ReadExif() after strip error = %v", err)
+	}
+	if exif.Latitude != nil || exif.Longitude != nil {
+		t.Errorf("GPS still present: %v, %v", derefFloat(exif.Latitude), derefFloat(exif.Longitude))
+	}
+	if exif.Camera != "Canon EOS 80D" || exif.TakenAt == nil {
+		t.Errorf("non-GPS metadata lost: camera %q, taken at %v", exif.Camera, exif.TakenAt)
+	}
+
+	// Gambar tanpa GPS atau bukan JPEG dikembalikan apa adanya
+	for _, data := range [][]byte{buildJPEG(t), []byte("not an image")} {
+		if got := StripGPS(data); !bytes.Equal(got, data) {
+			t.Errorf("StripGPS changed data without GPS (%d bytes)", len(data))
+		}
+	}
+}
+
+// pngWithText menyisipkan chunk tEXt berisi metadata sebelum IEND
+func pngWithText(t *testing.T, keyword, text string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	iend := len(encoded) - 12
+
+	payload := append([]byte("tEXt"), append(append([]byte(keyword), 0), text...)...)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)-4))
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(payload))
+
+	out := append([]byte{}, encoded[:iend]...)
+	out = append(out, chunk...)
+	return append(out, encoded[iend:]...)
+}
+
+func TestSanitizeImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantErr    error
+		wantFormat string
+		forbidden  []byte // Byte yang tidak boleh tersisa di hasil
+	}{
+		{name: "JPEG loses GPS and XMP", data: fullExifJPEG(t), wantFormat: "jpeg", forbidden: xmpHeader},
+		{name: "PNG is re-encoded without text chunks", data: pngWithText(t, "GPS", "-6.21,106.8"), wantFormat: "png", forbidden: []byte("tEXt")},
+		{name: "WebP is rejected", data: []byte("RIFF\x24\x00\x00\x00WEBPVP8 \x18\x00\x00\x00"), wantErr: ErrUnsupportedImage},
+		{name: "corrupt PNG is rejected", data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00"), wantErr: ErrUnsupportedImage},
+		{name: "text is rejected", data: []byte("hello"), wantErr: ErrUnsupportedImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeImage(tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SanitizeImage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SanitizeImage() error = %v", err)
+			}
+			if _, format, err := image.Decode(bytes.NewReader(got)); err != nil || format != tt.wantFormat {
+				t.Fatalf("sanitized image decodes as %q (err %v), want %q", format, err, tt.wantFormat)
+			}
+			if bytes.Contains(got, tt.forbidden) {
+				t.Errorf("sanitized image still contains %q", tt.forbidden)
+			}
+			if exif, err := ReadExif(got); err == nil && (exif.Latitude != nil || exif.Longitude != nil) {
+				t.Error("sanitized image still has GPS coordinates")
+			}
+		})
+	}
+}
+
+func ptrFloat(v float64) *float64 {
+	return &v
+}
+
+func derefFloat(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
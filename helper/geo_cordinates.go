@@ -1,41 +1,113 @@
 package helper
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strconv"
 )
 
+// Endpoint bawaan Nominatim (OpenStreetMap)
+const defaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+type nominatimAddress struct {
+	Road          string `json:"road"`
+	HouseNumber   string `json:"house_number"`
+	Village       string `json:"village"`
+	Suburb        string `json:"suburb"`
+	Neighbourhood string `json:"neighbourhood"`
+	CityDistrict  string `json:"city_district"`
+	District      string `json:"district"`
+	Subdistrict   string `json:"subdistrict"`
+	City          string `json:"city"`
+	Town          string `json:"town"`
+	Municipality  string `json:"municipality"`
+	County        string `json:"county"`
+	Regency       string `json:"regency"`
+	State         string `json:"state"`
+	Province      string `json:"province"`
+}
+
 type GeocodeResponse struct {
-	Lat string `json:"lat"`
-	Lon string `json:"lon"`
+	Lat         string           `json:"lat"`
+	Lon         string           `json:"lon"`
+	DisplayName string           `json:"display_name"`
+	Address     nominatimAddress `json:"address"`
 }
 
-func GetCoordinates(address string) (float64, float64, error) {
-	baseURL := "https://nominatim.openstreetmap.org/search"
+// NominatimGeocoder memakai API search dan reverse dari Nominatim
+type NominatimGeocoder struct {
+	BaseURL string
+	http    geocodeHTTPClient
+}
+
+// NewNominatimGeocoder membuat geocoder Nominatim; baseURL kosong memakai server publik
+func NewNominatimGeocoder(baseURL string, client geocodeHTTPClient) *NominatimGeocoder {
+	if baseURL == "" {
+		baseURL = defaultNominatimURL
+	}
+	return &NominatimGeocoder{BaseURL: baseURL, http: client}
+}
+
+func (n *NominatimGeocoder) Name() string {
+	return "nominatim"
+}
+
+func (n *NominatimGeocoder) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
 	params := url.Values{}
 	params.Add("q", address)
 	params.Add("format", "json")
-
-	resp, err := http.Get(fmt.Sprintf("%s?%s", baseURL, params.Encode()))
-	if err != nil {
-		return 0, 0, err
-	}
-	defer resp.Body.Close()
+	params.Add("addressdetails", "1")
+	params.Add("limit", "1")
 
 	var results []GeocodeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return 0, 0, err
+	if err := n.http.getJSON(ctx, fmt.Sprintf("%s/search?%s", n.BaseURL, params.Encode()), &results); err != nil {
+		return GeocodeResult{}, fmt.Errorf("nominatim: %w", err)
 	}
-
 	if len(results) == 0 {
-		return 0, 0, fmt.Errorf("no coordinates found for address: %s", address)
+		return GeocodeResult{}, fmt.Errorf("nominatim: %w", ErrGeocodeNotFound)
 	}
+	return n.toResult(results[0])
+}
 
-	lat, _ := strconv.ParseFloat(results[0].Lat, 64)
-	lon, _ := strconv.ParseFloat(results[0].Lon, 64)
+func (n *NominatimGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error) {
+	params := url.Values{}
+	params.Add("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Add("lon", strconv.FormatFloat(lng, 'f', -1, 64))
+	params.Add("format", "json")
+	params.Add("addressdetails", "1")
+
+	var result GeocodeResponse
+	if err := n.http.getJSON(ctx, fmt.Sprintf("%s/reverse?%s", n.BaseURL, params.Encode()), &result); err != nil {
+		return GeocodeResult{}, fmt.Errorf("nominatim: %w", err)
+	}
+	if result.Lat == "" {
+		return GeocodeResult{}, fmt.Errorf("nominatim: %w", ErrGeocodeNotFound)
+	}
+	return n.toResult(result)
+}
+
+func (n *NominatimGeocoder) toResult(response GeocodeResponse) (GeocodeResult, error) {
+	lat, err := strconv.ParseFloat(response.Lat, 64)
+	if err != nil {
+		return GeocodeResult{}, fmt.Errorf("nominatim: invalid latitude %q", response.Lat)
+	}
+	lon, err := strconv.ParseFloat(response.Lon, 64)
+	if err != nil {
+		return GeocodeResult{}, fmt.Errorf("nominatim: invalid longitude %q", response.Lon)
+	}
 
-	return lat, lon, nil
+	// Nama field wilayah OSM di Indonesia tidak seragam, jadi dicoba beberapa kemungkinan
+	address := response.Address
+	return GeocodeResult{
+		Latitude:    lat,
+		Longitude:   lon,
+		DisplayName: response.DisplayName,
+		Street:      joinStreet(address.Road, address.HouseNumber),
+		Kelurahan:   firstNonEmpty(address.Village, address.Suburb, address.Neighbourhood),
+		Kecamatan:   firstNonEmpty(address.CityDistrict, address.District, address.Subdistrict),
+		City:        firstNonEmpty(address.City, address.Town, address.Municipality, address.County, address.Regency),
+		Province:    firstNonEmpty(address.State, address.Province),
+		Provider:    n.Name(),
+	}, nil
 }
@@ -0,0 +1,262 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error umum dari provider geocoding
+var (
+	ErrGeocodeNotFound = errors.New("no geocoding result found")
+	ErrNoGeocoder      = errors.New("no geocoding provider configured")
+)
+
+// GeocodeResult adalah hasil geocoding yang sudah dinormalisasi antar provider
+type GeocodeResult struct {
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	DisplayName string  `json:"display_name"`
+	Street      string  `json:"street"`
+	Kelurahan   string  `json:"kelurahan"` // Kelurahan/desa
+	Kecamatan   string  `json:"kecamatan"`
+	City        string  `json:"city"` // Kota/kabupaten
+	Province    string  `json:"province"`
+	Provider    string  `json:"provider"`
+}
+
+// HasAdminAreas menandakan apakah hasil sudah memuat wilayah administratif yang lengkap
+func (r GeocodeResult) HasAdminAreas() bool {
+	return r.Kecamatan != "" && r.City != ""
+}
+
+// firstNonEmpty mengembalikan string pertama yang tidak kosong
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// joinStreet menggabungkan nama jalan dan nomor rumah
+func joinStreet(street, houseNumber string) string {
+	if street == "" || houseNumber == "" {
+		return street
+	}
+	return street + " " + houseNumber
+}
+
+// Geocoder mengubah alamat menjadi koordinat dan sebaliknya
+type Geocoder interface {
+	Name() string
+	Geocode(ctx context.Context, address string) (GeocodeResult, error)
+	ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error)
+}
+
+// GeocodeCache menyimpan hasil geocoding secara persisten.
+// Get mengembalikan false jika key belum ada atau sudah kedaluwarsa.
+type GeocodeCache interface {
+	Get(key string) (GeocodeResult, bool)
+	Set(key string, result GeocodeResult)
+}
+
+// geocodeHTTPClient menjalankan request HTTP dengan timeout dan retry untuk provider geocoding
+type geocodeHTTPClient struct {
+	client    *http.Client
+	retries   int
+	backoff   time.Duration
+	userAgent string
+}
+
+func newGeocodeHTTPClient(timeout time.Duration, retries int) geocodeHTTPClient {
+	return geocodeHTTPClient{
+		client:    &http.Client{Timeout: timeout},
+		retries:   retries,
+		backoff:   300 * time.Millisecond,
+		userAgent: "Recything-Backend/1.0",
+	}
+}
+
+// getJSON mengirim GET request dan men-decode respons JSON ke out.
+// Kegagalan jaringan, 429 dan 5xx dicoba ulang dengan jeda yang makin panjang.
+func (g geocodeHTTPClient) getJSON(ctx context.Context, requestURL string, out interface{}) error {
+	var lastErr error
+	for attempt := 0; attempt <= g.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(g.backoff * time.Duration(1<<(attempt-1))):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+		if err != nil {
+			return err
+		}
+		// Nominatim mewajibkan User-Agent yang mengidentifikasi aplikasi
+		req.Header.Set("User-Agent", g.userAgent)
+
+		resp, err := g.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("received status code %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("received status code %d", resp.StatusCode)
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(out)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON response: %w", err)
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// FallbackGeocoder mencoba setiap provider secara berurutan sampai ada yang berhasil
+type FallbackGeocoder struct {
+	Providers []Geocoder
+}
+
+func (f FallbackGeocoder) Name() string {
+	names := make([]string, 0, len(f.Providers))
+	for _, provider := range f.Providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (f FallbackGeocoder) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	return f.try(func(provider Geocoder) (GeocodeResult, error) { return provider.Geocode(ctx, address) })
+}
+
+func (f FallbackGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error) {
+	return f.try(func(provider Geocoder) (GeocodeResult, error) { return provider.ReverseGeocode(ctx, lat, lng) })
+}
+
+func (f FallbackGeocoder) try(call func(Geocoder) (GeocodeResult, error)) (GeocodeResult, error) {
+	err := ErrNoGeocoder
+	for _, provider := range f.Providers {
+		var result GeocodeResult
+		result, err = call(provider)
+		if err == nil {
+			return result, nil
+		}
+		log.Printf("Geocoder %s failed: %v", provider.Name(), err)
+	}
+	return GeocodeResult{}, err
+}
+
+// CachedGeocoder menyimpan hasil geocoding agar alamat yang sama tidak dikirim ulang ke provider
+type CachedGeocoder struct {
+	Next  Geocoder
+	Cache GeocodeCache
+}
+
+func (c CachedGeocoder) Name() string {
+	return c.Next.Name()
+}
+
+func (c CachedGeocoder) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	key := "fwd:" + NormalizeGeocodeQuery(address)
+	if result, ok := c.Cache.Get(key); ok {
+		return result, nil
+	}
+	result, err := c.Next.Geocode(ctx, address)
+	if err != nil {
+		return result, err
+	}
+	c.Cache.Set(key, result)
+	return result, nil
+}
+
+func (c CachedGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error) {
+	// Koordinat dibulatkan ke 5 desimal (~1 meter) supaya titik yang hampir sama memakai cache yang sama
+	key := fmt.Sprintf("rev:%.5f,%.5f", lat, lng)
+	if result, ok := c.Cache.Get(key); ok {
+		return result, nil
+	}
+	result, err := c.Next.ReverseGeocode(ctx, lat, lng)
+	if err != nil {
+		return result, err
+	}
+	c.Cache.Set(key, result)
+	return result, nil
+}
+
+// NormalizeGeocodeQuery menyeragamkan alamat (huruf kecil, spasi tunggal) untuk key cache
+func NormalizeGeocodeQuery(address string) string {
+	return strings.Join(strings.Fields(strings.ToLower(address)), " ")
+}
+
+// NewGeocoderFromEnv menyusun geocoder berdasarkan environment:
+//   - GEOCODER_PROVIDERS: urutan provider dipisah koma (here, nominatim, static).
+//     Bawaan: here (jika HERE_API_KEY ada) lalu nominatim.
+//   - GEOCODER_TIMEOUT_MS dan GEOCODER_RETRIES: timeout dan jumlah retry per request.
+//   - GEOCODER_STATIC_FILE: file JSON untuk provider static.
+//
+// Jika cache tidak nil, hasil geocoding disimpan di cache tersebut.
+func NewGeocoderFromEnv(cache GeocodeCache) Geocoder {
+	timeout := 5 * time.Second
+	if ms, err := strconv.Atoi(os.Getenv("GEOCODER_TIMEOUT_MS")); err == nil && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	retries := 2
+	if r, err := strconv.Atoi(os.Getenv("GEOCODER_RETRIES")); err == nil && r >= 0 {
+		retries = r
+	}
+	client := newGeocodeHTTPClient(timeout, retries)
+
+	names := strings.Split(os.Getenv("GEOCODER_PROVIDERS"), ",")
+	if strings.TrimSpace(os.Getenv("GEOCODER_PROVIDERS")) == "" {
+		names = []string{"nominatim"}
+		if os.Getenv("HERE_API_KEY") != "" {
+			names = []string{"here", "nominatim"}
+		}
+	}
+
+	var providers []Geocoder
+	for _, name := range names {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "here":
+			providers = append(providers, NewHereGeocoder(os.Getenv("HERE_API_KEY"), os.Getenv("HERE_BASE_URL"), client))
+		case "nominatim":
+			providers = append(providers, NewNominatimGeocoder(os.Getenv("NOMINATIM_BASE_URL"), client))
+		case "static":
+			static, err := LoadStaticGeocoder(os.Getenv("GEOCODER_STATIC_FILE"))
+			if err != nil {
+				log.Printf("Static geocoder disabled: %v", err)
+				continue
+			}
+			providers = append(providers, static)
+		case "":
+		default:
+			log.Printf("Unknown geocoder provider %q ignored", name)
+		}
+	}
+
+	var geocoder Geocoder = FallbackGeocoder{Providers: providers}
+	if cache != nil {
+		geocoder = CachedGeocoder{Next: geocoder, Cache: cache}
+	}
+	return geocoder
+}
@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Endpoint bawaan HERE Geocoding & Search API
+const (
+	defaultHereGeocodeURL = "https://geocode.search.hereapi.com/v1/geocode"
+	defaultHereReverseURL = "https://revgeocode.search.hereapi.com/v1/revgeocode"
+)
+
+// Struktur untuk respons dari HERE API
+type hereGeocodeResponse struct {
+	Items []struct {
+		Title    string `json:"title"`
+		Position struct {
+			Latitude  float64 `json:"lat"`
+			Longitude float64 `json:"lng"`
+		} `json:"position"`
+		Address struct {
+			Label       string `json:"label"`
+			Street      string `json:"street"`
+			HouseNumber string `json:"houseNumber"`
+			Subdistrict string `json:"subdistrict"`
+			District    string `json:"district"`
+			City        string `json:"city"`
+			County      string `json:"county"`
+			State       string `json:"state"`
+		} `json:"address"`
+	} `json:"items"`
+}
+
+// HereGeocoder memakai HERE Geocoding & Search API
+type HereGeocoder struct {
+	APIKey     string
+	GeocodeURL string
+	ReverseURL string
+	http       geocodeHTTPClient
+}
+
+// NewHereGeocoder membuat geocoder HERE; baseURL kosong memakai endpoint bawaan
+func NewHereGeocoder(apiKey string, baseURL string, client geocodeHTTPClient) *HereGeocoder {
+	if baseURL == "" {
+		baseURL = defaultHereGeocodeURL
+	}
+	return &HereGeocoder{APIKey: apiKey, GeocodeURL: baseURL, ReverseURL: defaultHereReverseURL, http: client}
+}
+
+func (h *HereGeocoder) Name() string {
+	return "here"
+}
+
+func (h *HereGeocoder) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	params := url.Values{}
+	params.Add("q", address)
+	params.Add("apiKey", h.APIKey)
+	return h.request(ctx, h.GeocodeURL+"?"+params.Encode())
+}
+
+func (h *HereGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error) {
+	params := url.Values{}
+	params.Add("at", strconv.FormatFloat(lat, 'f', -1, 64)+","+strconv.FormatFloat(lng, 'f', -1, 64))
+	params.Add("apiKey", h.APIKey)
+	return h.request(ctx, h.ReverseURL+"?"+params.Encode())
+}
+
+func (h *HereGeocoder) request(ctx context.Context, requestURL string) (GeocodeResult, error) {
+	if h.APIKey == "" {
+		return GeocodeResult{}, fmt.Errorf("here: %w", ErrNoGeocoder)
+	}
+
+	var response hereGeocodeResponse
+	if err := h.http.getJSON(ctx, requestURL, &response); err != nil {
+		return GeocodeResult{}, fmt.Errorf("here: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return GeocodeResult{}, fmt.Errorf("here: %w", ErrGeocodeNotFound)
+	}
+
+	// Hasil pertama adalah yang paling relevan
+	item := response.Items[0]
+	// Untuk Indonesia, HERE memetakan kelurahan ke subdistrict dan kecamatan ke district
+	return GeocodeResult{
+		Latitude:    item.Position.Latitude,
+		Longitude:   item.Position.Longitude,
+		DisplayName: firstNonEmpty(item.Address.Label, item.Title),
+		Street:      joinStreet(item.Address.Street, item.Address.HouseNumber),
+		Kelurahan:   item.Address.Subdistrict,
+		Kecamatan:   item.Address.District,
+		City:        firstNonEmpty(item.Address.City, item.Address.County),
+		Province:    item.Address.State,
+		Provider:    h.Name(),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// StaticGeocoder menjawab dari daftar alamat tetap, untuk pengujian dan pengembangan offline
+type StaticGeocoder struct {
+	Entries map[string]GeocodeResult // Key berupa alamat yang sudah dinormalisasi
+}
+
+// NewStaticGeocoder membuat geocoder static dari peta alamat ke hasil
+func NewStaticGeocoder(entries map[string]GeocodeResult) *StaticGeocoder {
+	normalized := make(map[string]GeocodeResult, len(entries))
+	for address, result := range entries {
+		result.Provider = "static"
+		if result.DisplayName == "" {
+			result.DisplayName = address
+		}
+		normalized[NormalizeGeocodeQuery(address)] = result
+	}
+	return &StaticGeocoder{Entries: normalized}
+}
+
+// LoadStaticGeocoder membaca file JSON berbentuk {"alamat": {"latitude": .., "longitude": .., "kecamatan": .., "city": ..}}
+func LoadStaticGeocoder(path string) (*StaticGeocoder, error) {
+	if path == "" {
+		return nil, fmt.Errorf("GEOCODER_STATIC_FILE is not set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]GeocodeResult
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid static geocoder file: %w", err)
+	}
+	return NewStaticGeocoder(entries), nil
+}
+
+func (s *StaticGeocoder) Name() string {
+	return "static"
+}
+
+func (s *StaticGeocoder) Geocode(ctx context.Context, address string) (GeocodeResult, error) {
+	result, ok := s.Entries[NormalizeGeocodeQuery(address)]
+	if !ok {
+		return GeocodeResult{}, fmt.Errorf("static: %w", ErrGeocodeNotFound)
+	}
+	return result, nil
+}
+
+// ReverseGeocode mengembalikan entri terdekat dalam radius 1 km
+func (s *StaticGeocoder) ReverseGeocode(ctx context.Context, lat, lng float64) (GeocodeResult, error) {
+	var nearest GeocodeResult
+	nearestDistance := -1.0
+	for _, result := range s.Entries {
+		distance := HaversineMeters(lat, lng, result.Latitude, result.Longitude)
+		if distance <= 1000 && (nearestDistance < 0 || distance < nearestDistance) {
+			nearest, nearestDistance = result, distance
+		}
+	}
+	if nearestDistance < 0 {
+		return GeocodeResult{}, fmt.Errorf("static: %w", ErrGeocodeNotFound)
+	}
+	return nearest, nil
+}
@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"math"
+	"strings"
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Radius bumi dalam meter untuk perhitungan jarak haversine
+const earthRadiusMeters = 6371000.0
+
+// Ukuran sel geohash per presisi di ekuator (lebar, tinggi) dalam meter
+var geohashCellSize = map[int][2]float64{
+	1: {5009400, 4992600},
+	2: {1252300, 624100},
+	3: {156500, 156000},
+	4: {39100, 19500},
+	5: {4900, 4900},
+	6: {1200, 609.4},
+	7: {152.9, 152.4},
+	8: {38.2, 19},
+	9: {4.8, 4.8},
+}
+
+// EncodeGeohash mengubah koordinat menjadi string geohash dengan presisi tertentu
+func EncodeGeohash(lat, lng float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	var hash strings.Builder
+	bit, ch := 0, 0
+	even := true
+	for hash.Len() < precision {
+		if even {
+			mid := (lngRange[0] + lngRange[1]) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				lngRange[0] = mid
+			} else {
+				lngRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			hash.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// DecodeGeohashBounds mengembalikan batas sel geohash (minLat, minLng, maxLat, maxLng)
+func DecodeGeohashBounds(hash string) (float64, float64, float64, float64) {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	even := true
+	for _, c := range hash {
+		idx := strings.IndexRune(geohashBase32, c)
+		if idx < 0 {
+			break
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := idx&(1<<bit) != 0
+			if even {
+				mid := (lngRange[0] + lngRange[1]) / 2
+				if set {
+					lngRange[0] = mid
+				} else {
+					lngRange[1] = mid
+				}
+			} else {
+				mid := (latRange[0] + latRange[1]) / 2
+				if set {
+					latRange[0] = mid
+				} else {
+					latRange[1] = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return latRange[0], lngRange[0], latRange[1], lngRange[1]
+}
+
+// GeohashNeighbors mengembalikan sel itu sendiri beserta 8 sel di sekitarnya
+func GeohashNeighbors(hash string) []string {
+	minLat, minLng, maxLat, maxLng := DecodeGeohashBounds(hash)
+	centerLat, centerLng := (minLat+maxLat)/2, (minLng+maxLng)/2
+	height, width := maxLat-minLat, maxLng-minLng
+
+	seen := make(map[string]bool, 9)
+	cells := make([]string, 0, 9)
+	for _, dLat := range []float64{-1, 0, 1} {
+		for _, dLng := range []float64{-1, 0, 1} {
+			lat := centerLat + dLat*height
+			if lat > 90 || lat < -90 {
+				continue
+			}
+			lng := centerLng + dLng*width
+			// Bungkus bujur yang melewati garis tanggal internasional
+			if lng > 180 {
+				lng -= 360
+			} else if lng < -180 {
+				lng += 360
+			}
+			cell := EncodeGeohash(lat, lng, len(hash))
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+	return cells
+}
+
+// GeohashPrecisionForRadius memilih presisi terbesar yang selnya tidak lebih kecil dari radius,
+// sehingga sel pusat dan 8 tetangganya pasti mencakup seluruh lingkaran pencarian.
+func GeohashPrecisionForRadius(lat, radiusMeters float64) int {
+	cosLat := math.Cos(lat * math.Pi / 180)
+	for precision := 9; precision >= 1; precision-- {
+		size := geohashCellSize[precision]
+		if math.Min(size[0]*cosLat, size[1]) >= radiusMeters {
+			return precision
+		}
+	}
+	return 1
+}
+
+// HaversineMeters menghitung jarak dua koordinat dalam meter
+func HaversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,121 @@
+package helper
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mail adalah email teks biasa yang dikirim aplikasi
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim email lewat provider tertentu
+type Mailer interface {
+	Name() string
+	Send(mail Mail) error
+}
+
+// SMTPMailer mengirim email lewat server SMTP. STARTTLS dipakai otomatis jika didukung server.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Name() string { return "smtp" }
+
+func (m SMTPMailer) Send(mail Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{mail.To}, buildMessage(m.From, mail))
+}
+
+// buildMessage menyusun pesan RFC 5322 sederhana. Baris baru di header dibuang agar tidak bisa disisipi header lain.
+func buildMessage(from string, mail Mail) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(mail.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean.Replace(mail.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogMailer tidak mengirim email, tetapi menulisnya ke file (atau ke log jika Path kosong).
+// Dipakai untuk pengembangan lokal agar link dan kode verifikasi bisa dibaca langsung.
+type LogMailer struct {
+	Path string
+	mu   *sync.Mutex
+}
+
+// NewLogMailer membuat LogMailer yang menulis ke path, atau ke log aplikasi jika path kosong
+func NewLogMailer(path string) LogMailer {
+	return LogMailer{Path: path, mu: &sync.Mutex{}}
+}
+
+func (m LogMailer) Name() string { return "log" }
+
+func (m LogMailer) Send(mail Mail) error {
+	entry := fmt.Sprintf("----- %s\nTo: %s\nSubject: %s\n\n%s\n", time.Now().Format(time.RFC3339), mail.To, mail.Subject, mail.Body)
+	if m.Path == "" {
+		log.Print(entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(entry)
+	return err
+}
+
+// NewMailerFromEnv memilih mailer dari MAIL_DRIVER: "smtp" (SMTP_HOST, SMTP_PORT, SMTP_USERNAME,
+// SMTP_PASSWORD, MAIL_FROM) atau "log" (bawaan, MAIL_LOG_FILE opsional).
+func NewMailerFromEnv() Mailer {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MAIL_DRIVER"))) {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			log.Printf("MAIL_DRIVER=smtp without SMTP_HOST, falling back to log mailer")
+			break
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USERNAME")
+		}
+		return SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "", "log":
+	default:
+		log.Printf("Unknown mail driver %q, falling back to log mailer", os.Getenv("MAIL_DRIVER"))
+	}
+	return NewLogMailer(os.Getenv("MAIL_LOG_FILE"))
+}
@@ -0,0 +1,142 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrUnsupportedGeometry dikembalikan untuk geometri GeoJSON selain Polygon/MultiPolygon
+var ErrUnsupportedGeometry = errors.New("unsupported geometry type, expected Polygon or MultiPolygon")
+
+// Polygon adalah kumpulan ring [lng, lat]; ring pertama batas luar, sisanya lubang
+type Polygon [][][2]float64
+
+// BoundingBox adalah batas koordinat sebuah geometri
+type BoundingBox struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// Contains memeriksa apakah titik berada di dalam kotak
+func (b BoundingBox) Contains(lat, lng float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lng >= b.MinLng && lng <= b.MaxLng
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseGeoJSONPolygons membaca geometri GeoJSON Polygon atau MultiPolygon
+func ParseGeoJSONPolygons(raw []byte) ([]Polygon, BoundingBox, error) {
+	var geometry geoJSONGeometry
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return nil, BoundingBox{}, fmt.Errorf("invalid geometry: %w", err)
+	}
+
+	var polygons []Polygon
+	switch geometry.Type {
+	case "Polygon":
+		var polygon Polygon
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, BoundingBox{}, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		polygons = []Polygon{polygon}
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, BoundingBox{}, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+	default:
+		return nil, BoundingBox{}, ErrUnsupportedGeometry
+	}
+
+	box := BoundingBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+	points := 0
+	for _, polygon := range polygons {
+		if len(polygon) == 0 || len(polygon[0]) < 4 {
+			return nil, BoundingBox{}, fmt.Errorf("polygon outer ring must have at least 4 positions")
+		}
+		for _, point := range polygon[0] {
+			box.MinLng = math.Min(box.MinLng, point[0])
+			box.MaxLng = math.Max(box.MaxLng, point[0])
+			box.MinLat = math.Min(box.MinLat, point[1])
+			box.MaxLat = math.Max(box.MaxLat, point[1])
+			points++
+		}
+	}
+	if points == 0 {
+		return nil, BoundingBox{}, fmt.Errorf("geometry has no coordinates")
+	}
+	return polygons, box, nil
+}
+
+// PolygonsContain memeriksa apakah titik berada di dalam salah satu polygon (di luar lubangnya)
+func PolygonsContain(polygons []Polygon, lat, lng float64) bool {
+	for _, polygon := range polygons {
+		if len(polygon) == 0 || !ringContains(polygon[0], lat, lng) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, lat, lng) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains memakai algoritma ray casting untuk satu ring
+func ringContains(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// InteriorPoint mencari titik yang pasti berada di dalam batas luar polygon pertama.
+// Titik tengah antara dua perpotongan pertama garis lintang tengah dengan ring dipakai,
+// karena titik berat polygon cekung bisa berada di luar polygon.
+func InteriorPoint(polygons []Polygon) (float64, float64, bool) {
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		ring := polygon[0]
+		minLat, maxLat := math.Inf(1), math.Inf(-1)
+		for _, point := range ring {
+			minLat = math.Min(minLat, point[1])
+			maxLat = math.Max(maxLat, point[1])
+		}
+		lat := (minLat + maxLat) / 2
+
+		var crossings []float64
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			xi, yi := ring[i][0], ring[i][1]
+			xj, yj := ring[j][0], ring[j][1]
+			if (yi > lat) != (yj > lat) {
+				crossings = append(crossings, (xj-xi)*(lat-yi)/(yj-yi)+xi)
+			}
+		}
+		if len(crossings) < 2 {
+			continue
+		}
+		sort.Float64s(crossings)
+		return lat, (crossings[0] + crossings[1]) / 2, true
+	}
+	return 0, 0, false
+}
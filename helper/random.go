@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+)
+
+// Karakter kode tanpa huruf/angka yang mudah tertukar (0/O, 1/I)
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// RandomCode menghasilkan kode acak yang aman secara kriptografis dengan panjang n
+func RandomCode(n int) (string, error) {
+	code := make([]byte, n)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[idx.Int64()]
+	}
+	return string(code), nil
+}
+
+// RandomDigits menghasilkan kode angka acak dengan panjang n, misalnya kode verifikasi 6 digit
+func RandomDigits(n int) (string, error) {
+	digits := make([]byte, n)
+	for i := range digits {
+		idx, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + idx.Int64())
+	}
+	return string(digits), nil
+}
+
+// RandomToken menghasilkan token acak base64url dari n byte, misalnya untuk refresh token
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken mengembalikan hash SHA-256 (hex) dari token agar token asli tidak disimpan di database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
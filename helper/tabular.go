@@ -0,0 +1,213 @@
+package helper
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableCell adalah satu sel pada ekspor tabel. Sel numerik ditulis sebagai angka di XLSX.
+type TableCell struct {
+	Value   string
+	Numeric bool
+}
+
+// TableWriter menulis tabel baris per baris tanpa menampung seluruh isi di memori
+type TableWriter interface {
+	WriteRow(cells []TableCell) error
+	Flush() error
+	Close() error
+}
+
+// NewTableWriter membuat penulis tabel untuk format csv atau xlsx
+func NewTableWriter(format string, w io.Writer) (TableWriter, error) {
+	switch format {
+	case "csv":
+		return NewCSVTableWriter(w), nil
+	case "xlsx":
+		return NewXLSXTableWriter(w, "Sheet1")
+	default:
+		return nil, fmt.Errorf("unsupported table format %q", format)
+	}
+}
+
+// isNumericCell mengecek apakah sel boleh ditulis sebagai angka. Nilai yang bukan angka
+// diperlakukan sebagai teks agar tidak pernah masuk ke spreadsheet sebagai formula.
+func isNumericCell(cell TableCell) bool {
+	if !cell.Numeric || cell.Value == "" {
+		return false
+	}
+	_, err := strconv.ParseFloat(cell.Value, 64)
+	return err == nil
+}
+
+// neutralizeFormula memberi awalan ' pada teks yang diawali =, +, -, @, tab atau CR
+// agar Excel dan Google Sheets tidak menjalankannya sebagai formula (CSV injection)
+func neutralizeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type csvTableWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSVTableWriter membuat penulis CSV. BOM UTF-8 ditulis di awal agar Excel membaca huruf non-ASCII dengan benar.
+func NewCSVTableWriter(w io.Writer) TableWriter {
+	io.WriteString(w, "\ufeff")
+	return &csvTableWriter{writer: csv.NewWriter(w)}
+}
+
+func (t *csvTableWriter) WriteRow(cells []TableCell) error {
+	t.record = t.record[:0]
+	for _, cell := range cells {
+		if isNumericCell(cell) {
+			t.record = append(t.record, cell.Value)
+			continue
+		}
+		t.record = append(t.record, neutralizeFormula(cell.Value))
+	}
+	return t.writer.Write(t.record)
+}
+
+func (t *csvTableWriter) Flush() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+func (t *csvTableWriter) Close() error {
+	return t.Flush()
+}
+
+// xlsxTableWriter menulis workbook XLSX minimal berisi satu sheet.
+// Sheet ditulis langsung ke entri zip memakai inline string, sehingga tidak
+// butuh shared string table yang harus disimpan di memori sampai akhir.
+type xlsxTableWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	row     int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+// NewXLSXTableWriter membuat penulis XLSX dengan satu sheet bernama sheetName
+func NewXLSXTableWriter(w io.Writer, sheetName string) (TableWriter, error) {
+	archive := zip.NewWriter(w)
+
+	var escapedName strings.Builder
+	if err := xml.EscapeText(&escapedName, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escapedName.String())},
+	}
+	for _, part := range parts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(entry)
+	if _, err := sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &xlsxTableWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (t *xlsxTableWriter) WriteRow(cells []TableCell) error {
+	t.row++
+	if _, err := fmt.Fprintf(t.sheet, `<row r="%d">`, t.row); err != nil {
+		return err
+	}
+	for i, cell := range cells {
+		ref := xlsxColumnName(i) + strconv.Itoa(t.row)
+		// Teks selalu ditulis sebagai inline string, bukan formula
+		if isNumericCell(cell) {
+			if _, err := fmt.Fprintf(t.sheet, `<c r="%s"><v>%s</v></c>`, ref, cell.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(t.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(t.sheet, []byte(cell.Value)); err != nil {
+			return err
+		}
+		if _, err := t.sheet.WriteString(`</t></is></c>`); err != nil {
+			return err
+		}
+	}
+	_, err := t.sheet.WriteString(`</row>`)
+	return err
+}
+
+// Flush mengirim isi buffer sheet ke zip. Data terkompresi baru keluar saat buffer deflate penuh.
+func (t *xlsxTableWriter) Flush() error {
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.archive.Flush()
+}
+
+func (t *xlsxTableWriter) Close() error {
+	if _, err := t.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.archive.Close()
+}
+
+// xlsxColumnName mengubah indeks kolom (mulai 0) menjadi nama kolom Excel: A, B, ..., Z, AA, ...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNeutralizeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Jl. Sudirman", "Jl. Sudirman"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+62812", "'+62812"},
+		{"-1+1", "'-1+1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+	}
+	for _, tt := range tests {
+		if got := neutralizeFormula(tt.value); got != tt.want {
+			t.Errorf("neutralizeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVTableWriterNeutralizesText(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVTableWriter(&buf)
+	if err := w.WriteRow([]TableCell{{Value: "=1+1"}, {Value: "-5", Numeric: true}, {Value: "-x", Numeric: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.TrimPrefix(buf.String(), "\ufeff")
+	if want := "'=1+1,-5,'-x\n"; got != want {
+		t.Errorf("csv row = %q, want %q", got, want)
+	}
+}
+
+func TestXLSXTableWriterWritesTextAsInlineString(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXTableWriter(&buf, "Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]TableCell{{Value: "=1+1"}, {Value: "12.5", Numeric: true}, {Value: "</v><f>1</f>", Numeric: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := file.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			sheet = string(data)
+		}
+	}
+	if strings.Contains(sheet, "<f>") {
+		t.Errorf("sheet contains a formula: %s", sheet)
+	}
+	if !strings.Contains(sheet, `<c r="A1" t="inlineStr">`) || !strings.Contains(sheet, `<c r="B1"><v>12.5</v></c>`) || !strings.Contains(sheet, `<c r="C1" t="inlineStr">`) {
+		t.Errorf("unexpected sheet cells: %s", sheet)
+	}
+}
@@ -0,0 +1,40 @@
+// Package testdb menyediakan database SQLite di memori untuk test yang membutuhkan config.DB.
+// Paket ini hanya diimpor dari file _test.go sehingga driver SQLite tidak ikut ke binary server.
+package testdb
+
+import (
+	"Backend-Recything/config"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open membuka database di memori yang hanya dipakai satu test, memigrasikan model yang diberikan
+// dan memasangnya sebagai config.DB selama test berjalan
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
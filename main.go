@@ -4,7 +4,9 @@ import (
 	"Backend-Recything/config"
 	"Backend-Recything/controllers"
 	"Backend-Recything/middlewares"
+	"Backend-Recything/models"
 	"log"
+	"os"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
@@ -17,7 +19,24 @@ func main() {
 	loadEnv()
 
 	// Inisialisasi database
-	config.InitDB()
+	if err := config.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Menjalankan subcommand CLI (misalnya "regions import") tanpa menyalakan server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// Job ekspor yang terputus saat server berhenti tidak akan pernah selesai
+	if err := controllers.FailInterruptedExportJobs(); err != nil {
+		log.Printf("Failed to mark interrupted export jobs: %v", err)
+	}
+
+	// Refresh token dan daftar tolak access token yang sudah kedaluwarsa tidak diperlukan lagi
+	if err := controllers.PurgeExpiredTokens(); err != nil {
+		log.Printf("Failed to purge expired tokens: %v", err)
+	}
 
 	// Inisialisasi Echo
 	e := echo.New()
@@ -44,9 +63,15 @@ func main() {
 
 // Rute publik (tanpa autentikasi)
 func publicRoutes(e *echo.Echo) {
-	e.POST("/api/v1/register", controllers.RegisterHandler) // Registrasi user baru
-	e.POST("/api/v1/login", controllers.LoginHandler)       // Login user
-	e.Static("/uploads", "uploads")                         // Akses file statis
+	e.POST("/api/v1/register", controllers.RegisterHandler)             // Registrasi user baru
+	e.POST("/api/v1/login", controllers.LoginHandler)                   // Login user
+	e.POST("/api/v1/register/admin", controllers.AcceptAdminInvitation) // Registrasi admin dengan token undangan
+	e.POST("/api/v1/token/refresh", controllers.RefreshTokenHandler)    // Tukar refresh token dengan token baru
+	e.Static("/uploads", "uploads")                                     // Akses file statis
+
+	// Rute verifikasi email
+	e.GET("/api/v1/verify-email", controllers.VerifyEmailByToken)              // ?token= dari link di email
+	e.POST("/api/v1/verify-email/resend", controllers.ResendVerificationEmail) // Kirim ulang berdasarkan email
 }
 
 // Rute dengan autentikasi (hanya untuk user login)
@@ -58,36 +83,124 @@ func protectedRoutes(e *echo.Echo) {
 	authGroup.GET("/logout", controllers.Logout)                  // Logout user
 	authGroup.PUT("/user/photo/:id", controllers.UpdateUserPhoto) // Update foto user
 	authGroup.GET("/users/points", controllers.GetUserPoints)
-	authGroup.PUT("/user/data/:id", controllers.UpdateUserData) // Update data diri user
+	authGroup.GET("/users/points/history", controllers.GetUserPointHistory) // Riwayat mutasi poin user
+	authGroup.PUT("/user/data/:id", controllers.UpdateUserData)             // Update data diri user
 
-	// Rute laporan sampah
-	authGroup.POST("/report-rubbish", controllers.CreateReportRubbish) // Membuat laporan
-	authGroup.GET("/report-rubbish/history", controllers.GetReportHistoryByUser)
+	authGroup.GET("/user/profile", controllers.GetMyProfile)                   // Profil user beserta badge
+	authGroup.GET("/notifications", controllers.GetNotifications)              // Notifikasi in-app
+	authGroup.PUT("/notifications/:id/read", controllers.MarkNotificationRead) // Tandai notifikasi dibaca
 
-	// Rute khusus admin (misalnya untuk memvalidasi laporan)
-	authGroup.PUT("/report-rubbish/:id/status", middlewares.RoleMiddleware("admin")(controllers.UpdateReportStatus))
+	// Rute sesi login per perangkat
+	authGroup.GET("/user/sessions", controllers.GetMySessions)          // Perangkat tempat user login
+	authGroup.DELETE("/user/sessions", controllers.RevokeAllMySessions) // Keluar dari semua perangkat
+	authGroup.DELETE("/user/sessions/:id", controllers.RevokeMySession)
 
-	adminGroup := authGroup.Group("/admin", middlewares.RoleMiddleware("admin"))
-	adminGroup.GET("/users/points", controllers.GetAllUserPoints)
-	adminGroup.POST("/users/points/deduct", controllers.DeductPointsFromUser)
+	// Rute verifikasi email user yang sedang login
+	authGroup.POST("/user/verify-email", controllers.VerifyEmailCode) // Kode 6 digit dari email
+	authGroup.POST("/user/verify-email/resend", controllers.ResendMyVerificationEmail)
 
-	adminGroup.GET("/users", controllers.GetAllUsers)
-	adminGroup.GET("/users/:id", controllers.GetUserByID) // Mendapatkan user berdasarkan ID
+	// Membuat laporan dan menukar hadiah hanya untuk user dengan email terverifikasi
+	verified := middlewares.VerifiedEmailMiddleware
 
-	adminGroup.GET("/latest-report", controllers.GetLatestReports)
-	adminGroup.GET("/report-rubbish", controllers.GetAllReportRubbish)
-	adminGroup.DELETE("/report-rubbish/:id", controllers.DeleteReportByID)
-	adminGroup.GET("/report-rubbish/:id", controllers.GetReportByID)
+	// Rute laporan sampah
+	authGroup.POST("/report-rubbish", controllers.CreateReportRubbish, verified) // Membuat laporan
+	authGroup.GET("/report-rubbish/history", controllers.GetReportHistoryByUser)
+	authGroup.GET("/report-rubbish/nearby", controllers.GetNearbyReports)                    // ?lat=&lng=&radius_m=
+	authGroup.GET("/report-rubbish/bbox", controllers.GetReportsInBoundingBox)               // ?min_lat=&min_lng=&max_lat=&max_lng=
+	authGroup.POST("/report-rubbish/:id/me-too", controllers.ConfirmReportRubbish, verified) // Konfirmasi laporan yang sama
+	authGroup.POST("/report-rubbish/:id/cleanup", controllers.SubmitReportCleanup, verified) // Foto "after" sebagai bukti pembersihan
+
+	// Rute staf: setiap rute menyatakan permission yang dibutuhkan, lihat models.DefaultRolePermissions
+	can := middlewares.PermissionMiddleware
+	authGroup.PUT("/report-rubbish/:id/status", controllers.UpdateReportStatus, can(models.PermissionReportsModerate))
+
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.GET("/users/points", controllers.GetAllUserPoints, can(models.PermissionPointsView))
+	adminGroup.POST("/users/points/deduct", controllers.DeductPointsFromUser, can(models.PermissionPointsAdjust))
+
+	// Rute aturan poin (kategori, bonus, kampanye)
+	adminGroup.GET("/point-rules", controllers.GetPointRules, can(models.PermissionPointsAdjust))
+	adminGroup.POST("/point-rules", controllers.CreatePointRule, can(models.PermissionPointsAdjust))
+	adminGroup.PUT("/point-rules/:id", controllers.UpdatePointRule, can(models.PermissionPointsAdjust))
+	adminGroup.DELETE("/point-rules/:id", controllers.DeletePointRule, can(models.PermissionPointsAdjust))
+	adminGroup.GET("/report-rubbish/:id/points-preview", controllers.PreviewReportPoints, can(models.PermissionReportsModerate)) // Dry-run poin laporan
+
+	adminGroup.GET("/users", controllers.GetAllUsers, can(models.PermissionUsersView))
+	adminGroup.GET("/users/:id", controllers.GetUserByID, can(models.PermissionUsersView)) // Mendapatkan user berdasarkan ID
+
+	adminGroup.DELETE("/users/:id/sessions", controllers.AdminRevokeUserSessions, can(models.PermissionUsersManage)) // Akhiri semua sesi user
+	adminGroup.DELETE("/users/:id/sessions/:session_id", controllers.AdminRevokeUserSession, can(models.PermissionUsersManage))
+
+	// Rute undangan admin
+	adminGroup.GET("/invitations", controllers.GetAdminInvitations, can(models.PermissionAdminsInvite))
+	adminGroup.POST("/invitations", controllers.CreateAdminInvitation, can(models.PermissionAdminsInvite))
+	adminGroup.DELETE("/invitations/:id", controllers.RevokeAdminInvitation, can(models.PermissionAdminsInvite))
+
+	adminGroup.GET("/latest-report", controllers.GetLatestReports, can(models.PermissionReportsView))
+	adminGroup.GET("/report-rubbish", controllers.GetAllReportRubbish, can(models.PermissionReportsView))
+	adminGroup.DELETE("/report-rubbish/:id", controllers.DeleteReportByID, can(models.PermissionReportsDelete))
+	adminGroup.GET("/report-rubbish/:id", controllers.GetReportByID, can(models.PermissionReportsView))
+	adminGroup.POST("/report-rubbish/:id/merge", controllers.MergeReportRubbish, can(models.PermissionReportsModerate)) // Gabungkan laporan duplikat
+	adminGroup.GET("/report-rubbish/clusters", controllers.GetReportClusters, can(models.PermissionReportsView))        // ?zoom=&min_lat=&min_lng=&max_lat=&max_lng=&mode=clusters|heatmap
+	adminGroup.GET("/report-rubbish/export/:format", controllers.ExportReportsGIS, can(models.PermissionExportsRun))    // geojson atau kml
+	adminGroup.GET("/report-cleanups", controllers.GetReportCleanups, can(models.PermissionReportsModerate))            // ?status=pending|verified|rejected
+	adminGroup.POST("/report-cleanups/:id/verify", controllers.VerifyReportCleanup, can(models.PermissionReportsModerate))
+	adminGroup.POST("/report-cleanups/:id/reject", controllers.RejectReportCleanup, can(models.PermissionReportsModerate))
+
+	// Rute tim kebersihan dan penugasan laporan
+	adminGroup.GET("/crews", controllers.GetCrews, can(models.PermissionCrewsManage))
+	adminGroup.POST("/crews", controllers.CreateCrew, can(models.PermissionCrewsManage))
+	adminGroup.PUT("/crews/:id/active", controllers.SetCrewActive, can(models.PermissionCrewsManage))
+	adminGroup.POST("/crews/:id/members", controllers.AddCrewMember, can(models.PermissionCrewsManage))
+	adminGroup.DELETE("/crews/:id/members/:user_id", controllers.RemoveCrewMember, can(models.PermissionCrewsManage))
+	adminGroup.POST("/report-rubbish/auto-assign", controllers.AutoAssignReports, can(models.PermissionCrewsManage))
+	adminGroup.POST("/report-rubbish/:id/assign", controllers.AssignReport, can(models.PermissionCrewsManage)) // crew_id kosong = pilih otomatis
+
+	crewGroup := authGroup.Group("/crew", can(models.PermissionCrewTasks))
+	crewGroup.GET("/tasks", controllers.GetCrewTasks) // ?lat=&lng=, urut dari yang terdekat
+	crewGroup.POST("/tasks/:id/accept", controllers.AcceptCrewTask)
+	crewGroup.POST("/tasks/:id/start", controllers.StartCrewTask)
+	crewGroup.POST("/tasks/:id/complete", controllers.CompleteCrewTask) // Form sama dengan bukti pembersihan
+
+	adminGroup.GET("/exports/jobs", controllers.GetExportJobs, can(models.PermissionExportsRun))
+	adminGroup.GET("/exports/jobs/:id", controllers.GetExportJob, can(models.PermissionExportsRun))
+	adminGroup.GET("/exports/jobs/:id/download", controllers.DownloadExportJob, can(models.PermissionExportsRun))
+	adminGroup.GET("/exports/:resource", controllers.ExportTable, can(models.PermissionExportsRun)) // reports, users, points, point-transactions; ?format=csv|xlsx&columns=&lang=en|id&tz=
+
+	// Rute wilayah administratif
+	authGroup.GET("/regions", controllers.GetRegions) // ?level=&parent_id=
+	authGroup.GET("/regions/:id/summary", controllers.GetRegionSummary)
+
+	// Rute leaderboard kontributor
+	authGroup.GET("/leaderboard", controllers.GetLeaderboard)                              // ?period=week|month|all&city=
+	authGroup.PUT("/user/leaderboard-visibility", controllers.UpdateLeaderboardVisibility) // Opt-out (anonim)
+
+	// Rute katalog dan penukaran hadiah
+	authGroup.GET("/rewards", controllers.GetRewards)
+	authGroup.GET("/rewards/:id", controllers.GetRewardByID)
+	authGroup.POST("/rewards/:id/redeem", controllers.RedeemReward, verified) // Tukar poin dengan hadiah
+	authGroup.GET("/redemptions", controllers.GetMyRedemptions)               // Riwayat penukaran user
+	adminGroup.POST("/rewards", controllers.CreateReward, can(models.PermissionRewardsManage))
+	adminGroup.PUT("/rewards/:id", controllers.UpdateReward, can(models.PermissionRewardsManage))
+	adminGroup.DELETE("/rewards/:id", controllers.DeleteReward, can(models.PermissionRewardsManage))
+	adminGroup.GET("/redemptions", controllers.GetAllRedemptions, can(models.PermissionRewardsManage))
+	adminGroup.PUT("/redemptions/:id/fulfill", controllers.FulfillRedemption, can(models.PermissionRewardsManage))
+	adminGroup.PUT("/redemptions/:id/cancel", controllers.CancelRedemption, can(models.PermissionRewardsManage)) // Batalkan dan kembalikan poin
 
 	// Rute Artikel Edukasi
-	adminGroup.POST("/articles", controllers.BikinArtikel)
-	adminGroup.PUT("/articles/:id", controllers.UpdateArtikel)
-	adminGroup.DELETE("/article/:id", controllers.DeleteArtikel) // Menghapus artikel berdasarkan ID
+	adminGroup.POST("/articles", controllers.BikinArtikel, can(models.PermissionArticlesPublish))
+	adminGroup.PUT("/articles/:id", controllers.UpdateArtikel, can(models.PermissionArticlesPublish))
+	adminGroup.DELETE("/article/:id", controllers.DeleteArtikel, can(models.PermissionArticlesPublish)) // Menghapus artikel berdasarkan ID
 	authGroup.GET("/articles", controllers.AmbilSemuaArtikel)
 	authGroup.GET("/articles/:id", controllers.AmbilArtikelByID)
 
 	//rute statistik
-	adminGroup.GET("/reports/statistics", controllers.FetchStatistics)
+	adminGroup.GET("/reports/statistics", controllers.FetchStatistics, can(models.PermissionReportsView))
+
+	// Rute pengaturan role dan permission
+	adminGroup.GET("/roles", controllers.GetRoles, can(models.PermissionRolesManage))
+	adminGroup.PUT("/roles/:role/permissions", controllers.UpdateRolePermissions, can(models.PermissionRolesManage))
+	adminGroup.PUT("/users/:id/role", controllers.UpdateUserRole, can(models.PermissionUsersManage, models.PermissionRolesManage))
 
 }
 
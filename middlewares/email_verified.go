@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// VerifiedEmailMiddleware middleware untuk route yang hanya boleh dipakai user dengan email terverifikasi,
+// misalnya membuat laporan dan menukar hadiah. Status dibaca dari database agar langsung berlaku setelah verifikasi.
+func VerifiedEmailMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, ok := c.Get("userID").(uint)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid user ID from token", http.StatusUnauthorized, "error", nil))
+		}
+
+		var user models.User
+		err := config.DB.Select("id, email_verified_at").First(&user, userID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusUnauthorized, helper.APIResponse("User not found", http.StatusUnauthorized, "error", nil))
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to check email verification", http.StatusInternalServerError, "error", nil))
+		}
+		if user.EmailVerifiedAt == nil {
+			return c.JSON(http.StatusForbidden, helper.APIResponse("Email address is not verified", http.StatusForbidden, "error", nil))
+		}
+		return next(c)
+	}
+}
@@ -1,7 +1,10 @@
 package middlewares
 
 import (
+	"Backend-Recything/config"
 	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -9,13 +12,15 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 // jwtCustomClaims struct untuk klaim JWT
 type jwtCustomClaims struct {
-	Name   string `json:"name"`
-	UserID uint   `json:"userID"`
-	Role   string `json:"role"`
+	Name      string `json:"name"`
+	UserID    uint   `json:"userID"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -53,38 +58,39 @@ func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return []byte(os.Getenv("JWT_SECRET_KEY")), nil
 		})
 
-		// Validasi token
-		if err != nil || !token.Valid {
+		// Validasi token. Token lama tanpa jti tidak bisa dicabut sehingga ikut ditolak.
+		if err != nil || !token.Valid || claims.ID == "" || claims.ExpiresAt == nil {
 			return c.JSON(http.StatusUnauthorized, helper.APIResponse("Invalid token", http.StatusUnauthorized, "error", nil))
 		}
 
+		// Menolak token dari sesi yang sudah diakhiri (logout, "keluar dari semua perangkat", atau oleh admin)
+		if claims.SessionID != "" {
+			var session models.UserSession
+			err := config.DB.Select("id, revoked_at").Where("family_id = ?", claims.SessionID).First(&session).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.RevokedAt != nil) {
+				return c.JSON(http.StatusUnauthorized, helper.APIResponse("Session has been revoked", http.StatusUnauthorized, "error", nil))
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to validate token", http.StatusInternalServerError, "error", nil))
+			}
+		}
+
+		// Menolak token yang sudah dicabut saat logout
+		var revoked int64
+		if err := config.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil {
+			return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to validate token", http.StatusInternalServerError, "error", nil))
+		}
+		if revoked > 0 {
+			return c.JSON(http.StatusUnauthorized, helper.APIResponse("Token has been revoked", http.StatusUnauthorized, "error", nil))
+		}
+
 		// Menyimpan klaim di context untuk diakses di handler berikutnya
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
+		c.Set("tokenID", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+		c.Set("sessionID", claims.SessionID)
 
 		return next(c)
 	}
 }
-
-// RoleMiddleware middleware untuk memvalidasi peran pengguna
-func RoleMiddleware(allowedRoles ...string) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// Mendapatkan role pengguna dari context
-			userRole, ok := c.Get("userRole").(string)
-			if !ok {
-				return c.JSON(http.StatusUnauthorized, helper.APIResponse("Missing or invalid user role", http.StatusUnauthorized, "error", nil))
-			}
-
-			// Memeriksa apakah role pengguna sesuai dengan role yang diizinkan
-			for _, role := range allowedRoles {
-				if userRole == role {
-					return next(c)
-				}
-			}
-
-			// Jika role tidak cocok, berikan respons "Access Denied"
-			return c.JSON(http.StatusForbidden, helper.APIResponse("Access denied", http.StatusForbidden, "error", nil))
-		}
-	}
-}
\ No newline at end of file
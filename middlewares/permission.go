@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/helper"
+	"Backend-Recything/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rolePermissions mengambil permission milik role dari tabel role_permissions.
+// Admin selalu memiliki semua permission agar tidak bisa terkunci dari pengaturan RBAC.
+func rolePermissions(role string) ([]string, error) {
+	if role == models.RoleAdmin {
+		return models.Permissions, nil
+	}
+	permissions := []string{}
+	err := config.DB.Model(&models.RolePermission{}).Where("role = ?", role).Pluck("permission", &permissions).Error
+	return permissions, err
+}
+
+// PermissionMiddleware middleware untuk memastikan role pengguna memiliki semua permission yang dibutuhkan route.
+// Permission role disimpan di context sebagai "userPermissions" untuk dipakai handler.
+func PermissionMiddleware(requiredPermissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userRole, ok := c.Get("userRole").(string)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, helper.APIResponse("Missing or invalid user role", http.StatusUnauthorized, "error", nil))
+			}
+
+			permissions, err := rolePermissions(userRole)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, helper.APIResponse("Failed to load permissions", http.StatusInternalServerError, "error", nil))
+			}
+
+			granted := make(map[string]bool, len(permissions))
+			for _, permission := range permissions {
+				granted[permission] = true
+			}
+			for _, permission := range requiredPermissions {
+				if !granted[permission] {
+					return c.JSON(http.StatusForbidden, helper.APIResponse("Access denied: missing permission "+permission, http.StatusForbidden, "error", nil))
+				}
+			}
+
+			c.Set("userPermissions", permissions)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"Backend-Recything/config"
+	"Backend-Recything/internal/testdb"
+	"Backend-Recything/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newPermissionTestDB memasang database di memori berisi izin role bawaan sebagai config.DB
+func newPermissionTestDB(t *testing.T) {
+	t.Helper()
+	db := testdb.Open(t, &models.RolePermission{})
+	for role, permissions := range models.DefaultRolePermissions {
+		for _, permission := range permissions {
+			if err := db.Create(&models.RolePermission{Role: role, Permission: permission}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	newPermissionTestDB(t)
+
+	tests := []struct {
+		name      string
+		role      interface{} // nil berarti role tidak ada di context
+		required  []string
+		wantCode  int
+		wantGrant []string // Isi "userPermissions" yang diteruskan ke handler
+	}{
+		{name: "admin has every permission", role: models.RoleAdmin, required: []string{models.PermissionRolesManage, models.PermissionPointsAdjust}, wantCode: http.StatusOK, wantGrant: models.Permissions},
+		{name: "moderator can moderate reports", role: models.RoleModerator, required: []string{models.PermissionReportsModerate}, wantCode: http.StatusOK, wantGrant: models.DefaultRolePermissions[models.RoleModerator]},
+		{name: "moderator cannot adjust points", role: models.RoleModerator, required: []string{models.PermissionPointsAdjust}, wantCode: http.StatusForbidden},
+		{name: "every required permission is checked", role: models.RoleModerator, required: []string{models.PermissionReportsView, models.PermissionExportsRun}, wantCode: http.StatusForbidden},
+		{name: "editor publishes articles", role: models.RoleEditor, required: []string{models.PermissionArticlesPublish}, wantCode: http.StatusOK, wantGrant: models.DefaultRolePermissions[models.RoleEditor]},
+		{name: "crew works on tasks", role: models.RoleCrew, required: []string{models.PermissionCrewTasks}, wantCode: http.StatusOK, wantGrant: models.DefaultRolePermissions[models.RoleCrew]},
+		{name: "crew cannot view reports list", role: models.RoleCrew, required: []string{models.PermissionReportsView}, wantCode: http.StatusForbidden},
+		{name: "user has no staff permissions", role: models.RoleUser, required: []string{models.PermissionUsersView}, wantCode: http.StatusForbidden},
+		{name: "unknown role", role: "superuser", required: []string{models.PermissionUsersView}, wantCode: http.StatusForbidden},
+		{name: "no required permissions", role: models.RoleUser, wantCode: http.StatusOK, wantGrant: []string{}},
+		{name: "missing role", role: nil, required: []string{models.PermissionUsersView}, wantCode: http.StatusUnauthorized},
+		{name: "role with wrong type", role: 1, required: []string{models.PermissionUsersView}, wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			if tt.role != nil {
+				c.Set("userRole", tt.role)
+			}
+
+			var granted []string
+			handler := PermissionMiddleware(tt.required...)(func(c echo.Context) error {
+				granted, _ = c.Get("userPermissions").([]string)
+				return c.NoContent(http.StatusOK)
+			})
+			if err := handler(c); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if granted != nil {
+					t.Error("handler ran although the request was rejected")
+				}
+				return
+			}
+			got := append([]string{}, granted...)
+			want := append([]string{}, tt.wantGrant...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("userPermissions = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// Perubahan izin di tabel role_permissions langsung berlaku tanpa restart
+func TestPermissionMiddlewareReadsCurrentGrants(t *testing.T) {
+	newPermissionTestDB(t)
+
+	check := func() int {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.Set("userRole", models.RoleEditor)
+		PermissionMiddleware(models.PermissionExportsRun)(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})(c)
+		return rec.Code
+	}
+
+	if code := check(); code != http.StatusForbidden {
+		t.Fatalf("before grant status = %d, want %d", code, http.StatusForbidden)
+	}
+	config.DB.Create(&models.RolePermission{Role: models.RoleEditor, Permission: models.PermissionExportsRun})
+	if code := check(); code != http.StatusOK {
+		t.Fatalf("after grant status = %d, want %d", code, http.StatusOK)
+	}
+	config.DB.Where("role = ? AND permission = ?", models.RoleEditor, models.PermissionExportsRun).Delete(&models.RolePermission{})
+	if code := check(); code != http.StatusForbidden {
+		t.Fatalf("after revoke status = %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// Metrik yang dapat dipakai sebagai syarat achievement
+const (
+	AchievementMetricReportsCreated    = "reports_created"
+	AchievementMetricReportsApproved   = "reports_approved"
+	AchievementMetricDistinctDistricts = "distinct_districts"
+	AchievementMetricReportStreakDays  = "report_streak_days"
+	AchievementMetricRedemptions       = "redemptions"
+)
+
+// Event yang memicu evaluasi achievement
+const (
+	AchievementEventReportCreated  = "report_created"
+	AchievementEventReportApproved = "report_approved"
+	AchievementEventRedemption     = "redemption"
+)
+
+// Achievement adalah definisi badge beserta syarat untuk membukanya
+type Achievement struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code        string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	IconURL     string    `gorm:"type:varchar(255)" json:"icon_url"`
+	Metric      string    `gorm:"type:varchar(50);index;not null" json:"metric"`
+	Threshold   int       `gorm:"not null" json:"threshold"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserAchievement menyimpan progres dan waktu terbukanya badge milik user
+type UserAchievement struct {
+	ID            uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        uint        `gorm:"uniqueIndex:idx_user_achievement;not null" json:"user_id"`
+	AchievementID uint        `gorm:"uniqueIndex:idx_user_achievement;not null" json:"achievement_id"`
+	Progress      int         `gorm:"default:0" json:"progress"`
+	UnlockedAt    *time.Time  `json:"unlocked_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	User          User        `gorm:"foreignKey:UserID" json:"-"`
+	Achievement   Achievement `gorm:"foreignKey:AchievementID" json:"-"`
+}
+
+// DefaultAchievements adalah badge bawaan yang dibuat saat migrasi jika belum ada
+var DefaultAchievements = []Achievement{
+	{Code: "first_report", Name: "Laporan Pertama", Description: "Mengirim laporan sampah pertama", Metric: AchievementMetricReportsCreated, Threshold: 1, Active: true},
+	{Code: "approved_10", Name: "10 Laporan Disetujui", Description: "Memiliki 10 laporan yang disetujui admin", Metric: AchievementMetricReportsApproved, Threshold: 10, Active: true},
+	{Code: "districts_5", Name: "Penjelajah Wilayah", Description: "Melapor di 5 wilayah yang berbeda", Metric: AchievementMetricDistinctDistricts, Threshold: 5, Active: true},
+	{Code: "streak_30", Name: "Konsisten 30 Hari", Description: "Melapor 30 hari berturut-turut", Metric: AchievementMetricReportStreakDays, Threshold: 30, Active: true},
+	{Code: "first_redemption", Name: "Penukaran Pertama", Description: "Menukar poin dengan hadiah untuk pertama kali", Metric: AchievementMetricRedemptions, Threshold: 1, Active: true},
+}
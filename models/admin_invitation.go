@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminInvitation adalah undangan sekali pakai untuk mendaftar sebagai admin.
+// Token hanya ditampilkan sekali saat dibuat; yang disimpan hanya hash-nya.
+type AdminInvitation struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Email          string     `gorm:"type:varchar(255);index;not null" json:"email"`
+	TokenHash      string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	InvitedByID    uint       `gorm:"index" json:"invited_by_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	AcceptedUserID *uint      `json:"accepted_user_id"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken disimpan sebagai hash. Setiap refresh menghasilkan token baru di family yang sama
+// (rotasi); token lama yang dipakai ulang berarti token bocor sehingga seluruh family dicabut.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID  string     `gorm:"type:varchar(36);index;not null" json:"family_id"` // Sama dengan klaim "sid" di access token
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // Sudah ditukar dengan token baru
+	RevokedAt *time.Time `json:"revoked_at"` // Dicabut karena logout atau terdeteksi dipakai ulang
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RevokedToken adalah daftar access token (berdasarkan jti) yang ditolak sebelum masa berlakunya habis
+type RevokedToken struct {
+	JTI       string    `gorm:"type:varchar(36);primaryKey" json:"jti"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"` // Baris boleh dihapus setelah token kedaluwarsa
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Crew adalah tim kebersihan yang menerima laporan sebagai perintah kerja.
+// RegionID kosong berarti tim bisa ditugaskan ke seluruh wilayah.
+type Crew struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	Name      string       `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	RegionID  *uint        `gorm:"index" json:"region_id"`
+	Active    bool         `gorm:"default:true" json:"active"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Members   []CrewMember `gorm:"foreignKey:CrewID" json:"members"`
+}
+
+// CrewMember menghubungkan user ber-role crew ke timnya. Satu user hanya bisa berada di satu tim.
+type CrewMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CrewID    uint      `gorm:"index;not null" json:"crew_id"`
+	UserID    uint      `gorm:"uniqueIndex;not null" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+}
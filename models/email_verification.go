@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailVerification menyimpan link dan kode verifikasi email yang dikirim ke user.
+// Hanya hash token dan kode yang disimpan; verifikasi baru membuat verifikasi lama tidak berlaku.
+type EmailVerification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Email     string     `gorm:"type:varchar(255);not null" json:"email"` // Email tujuan, verifikasi batal jika email user berubah
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	CodeHash  string     `gorm:"type:char(64);not null" json:"-"`
+	Attempts  int        `gorm:"default:0" json:"attempts"` // Percobaan kode yang salah
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
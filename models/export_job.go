@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Status job ekspor yang dijalankan di background
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+)
+
+// ExportJob mencatat ekspor CSV/XLSX besar yang diproses di background.
+// Hasilnya disimpan sebagai file di server dan bisa diunduh oleh admin setelah selesai.
+type ExportJob struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Resource    string     `gorm:"type:varchar(30);not null" json:"resource"` // reports, users atau points
+	Format      string     `gorm:"type:varchar(10);not null" json:"format"`   // csv atau xlsx
+	Query       string     `gorm:"type:text" json:"query"`                    // Query string permintaan ekspor
+	Status      string     `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	FileName    string     `gorm:"type:varchar(255)" json:"file_name"`
+	FilePath    string     `gorm:"type:varchar(500)" json:"-"`
+	RowCount    int        `json:"row_count"`
+	Error       string     `gorm:"type:text" json:"error"`
+	RequestedBy uint       `gorm:"index" json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// GeocodeCache menyimpan hasil geocoding alamat/koordinat agar tidak memanggil provider berulang kali
+type GeocodeCache struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	QueryKey    string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"query_key"` // "fwd:<alamat>" atau "rev:<lat>,<lng>"
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	DisplayName string    `json:"display_name"`
+	Street      string    `json:"street"`
+	Kelurahan   string    `gorm:"type:varchar(100)" json:"kelurahan"`
+	Kecamatan   string    `gorm:"type:varchar(100)" json:"kecamatan"`
+	City        string    `gorm:"type:varchar(100)" json:"city"`
+	Province    string    `gorm:"type:varchar(100)" json:"province"`
+	Provider    string    `gorm:"type:varchar(20)" json:"provider"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
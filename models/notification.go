@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Jenis notifikasi
+const (
+	NotificationTypeAchievementUnlocked = "achievement_unlocked"
+)
+
+// Notification adalah pesan in-app untuk user
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Type      string     `gorm:"type:varchar(50);not null" json:"type"`
+	Title     string     `gorm:"type:varchar(255);not null" json:"title"`
+	Message   string     `gorm:"type:text" json:"message"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+}
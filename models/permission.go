@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Role pengguna
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleEditor    = "editor"
+	RoleCrew      = "crew"
+	RoleUser      = "user"
+)
+
+// Roles adalah daftar role yang dikenal sistem
+var Roles = []string{RoleAdmin, RoleModerator, RoleEditor, RoleCrew, RoleUser}
+
+// Permission yang dipakai route untuk otorisasi
+const (
+	PermissionUsersView       = "users.view"       // Melihat daftar dan detail user
+	PermissionUsersManage     = "users.manage"     // Mengubah role user dan mengakhiri sesinya
+	PermissionAdminsInvite    = "admins.invite"    // Mengundang admin baru
+	PermissionReportsView     = "reports.view"     // Melihat semua laporan, cluster dan statistik
+	PermissionReportsModerate = "reports.moderate" // Mengubah status, menggabungkan laporan dan memverifikasi pembersihan
+	PermissionReportsDelete   = "reports.delete"   // Menghapus laporan
+	PermissionCrewsManage     = "crews.manage"     // Mengelola tim kebersihan dan penugasan laporan
+	PermissionCrewTasks       = "crew.tasks"       // Mengerjakan tugas tim kebersihan
+	PermissionPointsView      = "points.view"      // Melihat poin semua user
+	PermissionPointsAdjust    = "points.adjust"    // Memotong poin dan mengelola aturan poin
+	PermissionRewardsManage   = "rewards.manage"   // Mengelola hadiah dan penukaran
+	PermissionArticlesPublish = "articles.publish" // Membuat, mengubah dan menghapus artikel
+	PermissionExportsRun      = "exports.run"      // Mengekspor data
+	PermissionRolesManage     = "roles.manage"     // Mengatur permission setiap role
+)
+
+// Permissions adalah daftar semua permission yang dikenal sistem
+var Permissions = []string{
+	PermissionUsersView, PermissionUsersManage, PermissionAdminsInvite,
+	PermissionReportsView, PermissionReportsModerate, PermissionReportsDelete,
+	PermissionCrewsManage, PermissionCrewTasks,
+	PermissionPointsView, PermissionPointsAdjust, PermissionRewardsManage,
+	PermissionArticlesPublish, PermissionExportsRun, PermissionRolesManage,
+}
+
+// DefaultRolePermissions diisi ke database saat tabel role_permissions masih kosong.
+// Admin tidak ada di sini karena selalu memiliki semua permission.
+var DefaultRolePermissions = map[string][]string{
+	RoleModerator: {PermissionUsersView, PermissionReportsView, PermissionReportsModerate, PermissionCrewsManage},
+	RoleEditor:    {PermissionArticlesPublish},
+	RoleCrew:      {PermissionCrewTasks},
+	RoleUser:      {},
+}
+
+// RolePermission memberikan satu permission ke satu role
+type RolePermission struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Role       string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_role_permission" json:"role"`
+	Permission string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_role_permission" json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsRole mengecek apakah role dikenal sistem
+func IsRole(role string) bool {
+	return containsString(Roles, role)
+}
+
+// IsPermission mengecek apakah permission dikenal sistem
+func IsPermission(permission string) bool {
+	return containsString(Permissions, permission)
+}
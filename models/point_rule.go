@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Jenis aturan poin
+const (
+	PointRuleKindBase              = "base"                 // Poin dasar per kategori dan hasil
+	PointRuleKindFirstOfMonthBonus = "first_of_month_bonus" // Bonus untuk laporan pertama di bulan berjalan
+	PointRuleKindCampaign          = "campaign"             // Pengali poin selama periode kampanye
+)
+
+// Hasil (outcome) yang dapat menghasilkan poin
+const (
+	PointOutcomeReportApproved  = "report_approved"
+	PointOutcomeReportConfirmed = "report_confirmed" // Pelapor tambahan pada laporan yang disetujui
+	PointOutcomeReportCleaned   = "report_cleaned"   // Pembersihan laporan yang sudah diverifikasi
+	PointOutcomeManualAward     = "manual_award"
+)
+
+// PointRule adalah aturan pemberian poin yang dikelola admin
+type PointRule struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"type:varchar(255);not null" json:"name"`
+	Kind       string     `gorm:"type:varchar(30);index;not null" json:"kind"`
+	Category   string     `gorm:"type:varchar(50)" json:"category"` // Kosong berarti berlaku untuk semua kategori
+	Outcome    string     `gorm:"type:varchar(50);index;not null" json:"outcome"`
+	Points     int        `gorm:"default:0" json:"points"`
+	Multiplier float64    `gorm:"default:1" json:"multiplier"`
+	StartsAt   *time.Time `json:"starts_at"` // Kosong berarti berlaku sejak awal
+	EndsAt     *time.Time `json:"ends_at"`   // Kosong berarti berlaku tanpa batas
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// AppliesAt mengecek apakah aturan aktif pada waktu tertentu
+func (r PointRule) AppliesAt(at time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	if r.StartsAt != nil && at.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && at.After(*r.EndsAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Alasan transaksi poin yang dicatat di ledger
+const (
+	PointReasonOpeningBalance       = "opening_balance"       // Saldo awal hasil migrasi dari kolom users.points
+	PointReasonReportAward          = "report_award"          // Poin dari laporan yang disetujui
+	PointReasonReportReversal       = "report_reversal"       // Pembatalan poin laporan yang ditolak/dihapus
+	PointReasonManualAward          = "manual_award"          // Poin yang ditambahkan manual
+	PointReasonConfirmationAward    = "confirmation_award"    // Poin untuk pelapor tambahan ("me too"/duplikat yang digabung)
+	PointReasonConfirmationReversal = "confirmation_reversal" // Pembatalan poin pelapor tambahan
+	PointReasonCleanupAward         = "cleanup_award"         // Bonus untuk user yang membersihkan sampah laporan
+	PointReasonAdminDeduction       = "admin_deduction"       // Pengurangan poin oleh admin
+	PointReasonRedemption           = "redemption"            // Penukaran poin dengan hadiah
+	PointReasonRedemptionRefund     = "redemption_refund"     // Pengembalian poin dari penukaran yang dibatalkan
+)
+
+// PointTransaction adalah entri ledger poin yang bersifat append-only.
+// Saldo user (User.Points) selalu diperbarui di transaksi DB yang sama dengan entri ini.
+type PointTransaction struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`
+	Delta        int       `gorm:"not null" json:"delta"`
+	BalanceAfter uint      `gorm:"not null" json:"balance_after"`
+	Reason       string    `gorm:"type:varchar(50);index;not null" json:"reason"`
+	ReportID     *uint     `gorm:"index" json:"report_id"`
+	RedemptionID *uint     `gorm:"index" json:"redemption_id"`
+	ActorID      *uint     `json:"actor_id"`
+	Note         string    `gorm:"type:varchar(255)" json:"note"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+	User         User      `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// EarningPointReasons adalah alasan transaksi yang dihitung sebagai poin yang diperoleh
+// (bukan saldo bersih setelah penukaran), dipakai untuk leaderboard.
+var EarningPointReasons = []string{
+	PointReasonReportAward,
+	PointReasonReportReversal,
+	PointReasonConfirmationAward,
+	PointReasonConfirmationReversal,
+	PointReasonCleanupAward,
+	PointReasonManualAward,
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Tingkat wilayah administratif, dari yang paling kecil
+const (
+	RegionLevelKelurahan = "kelurahan"
+	RegionLevelKecamatan = "kecamatan"
+	RegionLevelCity      = "city"
+	RegionLevelProvince  = "province"
+)
+
+// RegionLevels berisi tingkat wilayah yang valid, urut dari yang paling kecil
+var RegionLevels = []string{RegionLevelKelurahan, RegionLevelKecamatan, RegionLevelCity, RegionLevelProvince}
+
+// RegionLevelRank mengembalikan urutan tingkat wilayah (0 paling kecil), atau -1 jika tidak dikenal
+func RegionLevelRank(level string) int {
+	for i, l := range RegionLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Region adalah batas wilayah administratif yang diimpor dari GeoJSON.
+// Kotak pembatas disimpan terpisah agar kandidat wilayah bisa dicari lewat index
+// sebelum pengecekan point-in-polygon pada geometri lengkap.
+type Region struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name      string    `gorm:"type:varchar(150);not null" json:"name"`
+	Level     string    `gorm:"type:varchar(20);index;not null" json:"level"`
+	ParentID  *uint     `gorm:"index" json:"parent_id"`
+	Geometry  string    `gorm:"type:longtext;not null" json:"-"` // Geometri GeoJSON (Polygon/MultiPolygon)
+	MinLat    float64   `gorm:"index:idx_region_bbox" json:"min_lat"`
+	MinLng    float64   `gorm:"index:idx_region_bbox" json:"min_lng"`
+	MaxLat    float64   `gorm:"index:idx_region_bbox" json:"max_lat"`
+	MaxLng    float64   `gorm:"index:idx_region_bbox" json:"max_lng"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
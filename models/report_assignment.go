@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Status penugasan laporan ke tim kebersihan
+const (
+	AssignmentStatusAssigned   = "assigned"
+	AssignmentStatusAccepted   = "accepted"
+	AssignmentStatusInProgress = "in_progress"
+	AssignmentStatusCompleted  = "completed" // Bukti pembersihan sudah dikirim dan menunggu verifikasi admin
+	AssignmentStatusCancelled  = "cancelled"
+)
+
+// ActiveAssignmentStatuses adalah status penugasan yang masih dihitung sebagai beban kerja tim
+var ActiveAssignmentStatuses = []string{AssignmentStatusAssigned, AssignmentStatusAccepted, AssignmentStatusInProgress}
+
+// Cara laporan ditugaskan
+const (
+	AssignmentMethodManual = "manual"
+	AssignmentMethodAuto   = "auto"
+)
+
+// ReportAssignment adalah perintah kerja pembersihan sebuah laporan untuk satu tim
+type ReportAssignment struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	ReportID     uint          `gorm:"index;not null" json:"report_id"`
+	CrewID       uint          `gorm:"index;not null" json:"crew_id"`
+	Method       string        `gorm:"type:varchar(10);not null" json:"method"`
+	Status       string        `gorm:"type:varchar(20);index;not null" json:"status"`
+	AssignedByID *uint         `json:"assigned_by_id"` // Kosong untuk penugasan otomatis
+	AcceptedByID *uint         `json:"accepted_by_id"` // Anggota tim yang menangani
+	AcceptedAt   *time.Time    `json:"accepted_at"`
+	StartedAt    *time.Time    `json:"started_at"`
+	CompletedAt  *time.Time    `json:"completed_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Crew         Crew          `gorm:"foreignKey:CrewID" json:"-"`
+	Report       ReportRubbish `gorm:"foreignKey:ReportID" json:"-"`
+}
+
+// Langkah penugasan yang dicatat di ReportAssignmentEvent
+const (
+	AssignmentEventAssigned  = "assigned"
+	AssignmentEventAccepted  = "accepted"
+	AssignmentEventStarted   = "started"
+	AssignmentEventCompleted = "completed"
+	AssignmentEventReopened  = "reopened" // Bukti pembersihan ditolak, tim harus mengerjakan ulang
+	AssignmentEventCancelled = "cancelled"
+)
+
+// ReportAssignmentEvent mencatat setiap langkah penugasan agar dispatcher tahu siapa menangani apa
+type ReportAssignmentEvent struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	AssignmentID uint      `gorm:"index;not null" json:"assignment_id"`
+	ReportID     uint      `gorm:"index;not null" json:"report_id"`
+	Action       string    `gorm:"type:varchar(20);not null" json:"action"` // assigned, accepted, started, completed, reopened, cancelled
+	ActorID      *uint     `json:"actor_id"`
+	Note         string    `gorm:"type:varchar(255)" json:"note"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+	Actor        *User     `gorm:"foreignKey:ActorID" json:"-"`
+}
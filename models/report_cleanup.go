@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Status pengajuan bukti pembersihan
+const (
+	CleanupStatusPending  = "pending"
+	CleanupStatusVerified = "verified"
+	CleanupStatusRejected = "rejected"
+)
+
+// Sumber koordinat foto "after"
+const (
+	CleanupLocationExif = "exif" // GPS dari EXIF foto
+	CleanupLocationForm = "form" // Koordinat yang dikirim client, dipakai jika foto tanpa GPS
+)
+
+// ReportCleanup adalah bukti pembersihan ("after") untuk laporan yang sudah disetujui.
+// Pengajuan diverifikasi admin sebelum laporan pindah ke status cleaned_up.
+type ReportCleanup struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ReportID      uint       `gorm:"index;not null" json:"report_id"`
+	SubmittedByID uint       `gorm:"index;not null" json:"submitted_by_id"`
+	Photo         string     `gorm:"type:varchar(500);not null" json:"photo"`
+	Note          string     `gorm:"type:text" json:"note"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	DistanceM     float64    `json:"distance_m"` // Jarak lokasi foto "after" ke koordinat laporan
+	LocationFrom  string     `gorm:"type:varchar(10);default:'form'" json:"location_from"`
+	Status        string     `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	ReviewedByID  *uint      `json:"reviewed_by_id"`
+	ReviewNote    string     `gorm:"type:text" json:"review_note"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	SubmittedBy   User       `gorm:"foreignKey:SubmittedByID" json:"-"`
+}
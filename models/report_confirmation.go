@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Sumber konfirmasi laporan
+const (
+	ConfirmationSourceMeToo  = "me_too" // User menandai laporan yang sudah ada sebagai laporan yang sama
+	ConfirmationSourceMerged = "merged" // Berasal dari laporan duplikat yang digabung admin
+)
+
+// ReportConfirmation mencatat pelapor lain untuk tumpukan sampah yang sama beserta fotonya
+type ReportConfirmation struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	ReportID           uint      `gorm:"index;not null" json:"report_id"`
+	UserID             uint      `gorm:"index;not null" json:"user_id"`
+	Source             string    `gorm:"type:varchar(20);not null" json:"source"`
+	Photo              string    `json:"photo"`
+	Description        string    `json:"description"`
+	Latitude           float64   `json:"latitude"`
+	Longitude          float64   `json:"longitude"`
+	MergedFromReportID *uint     `gorm:"index" json:"merged_from_report_id"`
+	CreatedAt          time.Time `json:"created_at"`
+	User               User      `gorm:"foreignKey:UserID" json:"-"`
+}
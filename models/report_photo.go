@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Hasil cek keaslian foto berdasarkan metadata EXIF
+const (
+	PhotoFlagExifMissing       = "exif_missing"        // Tidak ada EXIF atau waktu pengambilan
+	PhotoFlagGPSMissing        = "gps_missing"         // EXIF tidak menyimpan koordinat
+	PhotoFlagLocationMismatch  = "location_mismatch"   // Koordinat EXIF jauh dari lokasi laporan
+	PhotoFlagTakenBeforeReport = "taken_before_report" // Foto diambil jauh sebelum tanggal laporan
+)
+
+// ReportPhoto adalah satu foto di galeri laporan, diurutkan berdasarkan Position.
+// Foto pertama juga disimpan di ReportRubbish.Photo sebagai foto sampul.
+type ReportPhoto struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ReportID  uint      `gorm:"index;not null" json:"report_id"`
+	URL       string    `gorm:"type:varchar(500);not null" json:"url"`
+	Caption   string    `gorm:"type:varchar(255)" json:"caption"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Metadata EXIF asli, hanya ditampilkan ke admin. GPS sudah dihapus dari file yang diunggah.
+	ExifTakenAt   *time.Time `json:"-"`
+	ExifLatitude  *float64   `json:"-"`
+	ExifLongitude *float64   `json:"-"`
+	ExifCamera    string     `gorm:"type:varchar(100)" json:"-"`
+	ExifDistanceM *float64   `json:"-"`                          // Jarak koordinat EXIF ke lokasi laporan
+	Flags         string     `gorm:"type:varchar(255)" json:"-"` // Daftar PhotoFlag dipisah koma
+}
+
+// FlagList mengembalikan Flags sebagai slice
+func (p ReportPhoto) FlagList() []string {
+	if p.Flags == "" {
+		return []string{}
+	}
+	return strings.Split(p.Flags, ",")
+}
@@ -4,18 +4,42 @@ import (
 	"time"
 )
 
+// ReportGeohashPrecision adalah presisi geohash yang disimpan untuk setiap laporan (sel sekitar 5 meter)
+const ReportGeohashPrecision = 9
+
+// Asal koordinat laporan
+const (
+	LocationSourceGPS     = "gps"     // Koordinat dari GPS perangkat, alamat hasil reverse geocoding
+	LocationSourceAddress = "address" // Koordinat hasil geocoding alamat yang diketik user
+)
+
 type ReportRubbish struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	UserID         uint      `json:"user_id"`
-	Location       string    `json:"location"`
-	Description    string    `json:"description"`
-	Photo          string    `json:"photo"`
-	Status         string    `json:"status"`
-	Latitude       float64   `json:"latitude"`
-	Longitude      float64   `json:"longitude"`
-	TanggalLaporan time.Time `json:"tanggal_laporan"`
-	Category       string    `gorm:"type:varchar(50);not null"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	User           User      `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	UserID         uint          `json:"user_id"`
+	Location       string        `json:"location"`
+	Description    string        `json:"description"`
+	Photo          string        `json:"photo"`
+	Status         string        `gorm:"type:varchar(20);index;default:'submitted'" json:"status"`
+	Latitude       float64       `gorm:"index:idx_report_lat_lng" json:"latitude"`
+	Longitude      float64       `gorm:"index:idx_report_lat_lng" json:"longitude"`
+	Geohash        string        `gorm:"type:varchar(12);index" json:"geohash"` // Dipakai untuk pencarian lokasi terdekat
+	Street         string        `gorm:"type:varchar(255)" json:"street"`
+	Kelurahan      string        `gorm:"type:varchar(100);index" json:"kelurahan"`
+	Kecamatan      string        `gorm:"type:varchar(100);index" json:"kecamatan"`
+	City           string        `gorm:"type:varchar(100);index" json:"city"` // Kota/kabupaten hasil reverse geocoding koordinat
+	Province       string        `gorm:"type:varchar(100);index" json:"province"`
+	LocationSource string        `gorm:"type:varchar(10)" json:"location_source"` // gps atau address
+	AccuracyM      *float64      `json:"accuracy_m"`                              // Akurasi GPS perangkat dalam meter
+	RegionID       *uint         `gorm:"index" json:"region_id"`                  // Wilayah terkecil yang memuat koordinat laporan
+	MergedIntoID   *uint         `gorm:"index" json:"merged_into_id"`             // Laporan utama jika laporan ini duplikat
+	AfterPhoto     string        `gorm:"type:varchar(500)" json:"after_photo"`    // Foto setelah dibersihkan yang sudah diverifikasi
+	CleanedByID    *uint         `gorm:"index" json:"cleaned_by_id"`              // User yang membersihkan sampah
+	CleanedAt      *time.Time    `json:"cleaned_at"`
+	PhotoFlagged   bool          `gorm:"index;default:false" json:"-"` // Ada foto yang gagal cek keaslian EXIF, hanya untuk admin
+	TanggalLaporan time.Time     `json:"tanggal_laporan"`
+	Category       string        `gorm:"type:varchar(50);not null"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	User           User          `json:"user" gorm:"foreignKey:UserID;references:ID"`
+	Photos         []ReportPhoto `json:"photos" gorm:"foreignKey:ReportID"` // Galeri foto laporan
 }
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status siklus hidup laporan sampah
+const (
+	ReportStatusSubmitted = "submitted"
+	ReportStatusInReview  = "in_review"
+	ReportStatusApproved  = "approved"
+	ReportStatusRejected  = "rejected"
+	ReportStatusCleanedUp = "cleaned_up"
+	ReportStatusClosed    = "closed"
+	ReportStatusMerged    = "merged" // Duplikat yang digabung ke laporan lain
+)
+
+// ReportTransition mendefinisikan satu perpindahan status yang diizinkan
+type ReportTransition struct {
+	From           string
+	To             string
+	Permission     string   // Permission yang dibutuhkan untuk melakukan transisi
+	RequiredFields []string // Field laporan yang wajib terisi sebelum transisi
+	RequiresReason bool     // Alasan wajib diisi (misalnya untuk penolakan)
+}
+
+// ReportTransitions adalah satu-satunya sumber aturan perpindahan status laporan
+var ReportTransitions = []ReportTransition{
+	{From: ReportStatusSubmitted, To: ReportStatusInReview, Permission: PermissionReportsModerate},
+	{From: ReportStatusSubmitted, To: ReportStatusApproved, Permission: PermissionReportsModerate, RequiredFields: []string{"photo", "coordinates"}},
+	{From: ReportStatusSubmitted, To: ReportStatusRejected, Permission: PermissionReportsModerate, RequiresReason: true},
+	{From: ReportStatusInReview, To: ReportStatusApproved, Permission: PermissionReportsModerate, RequiredFields: []string{"photo", "coordinates"}},
+	{From: ReportStatusInReview, To: ReportStatusRejected, Permission: PermissionReportsModerate, RequiresReason: true},
+	{From: ReportStatusApproved, To: ReportStatusRejected, Permission: PermissionReportsModerate, RequiresReason: true},
+	{From: ReportStatusApproved, To: ReportStatusCleanedUp, Permission: PermissionReportsModerate, RequiredFields: []string{"after_photo"}},
+	{From: ReportStatusRejected, To: ReportStatusInReview, Permission: PermissionReportsModerate},
+	{From: ReportStatusRejected, To: ReportStatusClosed, Permission: PermissionReportsModerate},
+	{From: ReportStatusCleanedUp, To: ReportStatusClosed, Permission: PermissionReportsModerate},
+	{From: ReportStatusSubmitted, To: ReportStatusMerged, Permission: PermissionReportsModerate},
+	{From: ReportStatusInReview, To: ReportStatusMerged, Permission: PermissionReportsModerate},
+	{From: ReportStatusApproved, To: ReportStatusMerged, Permission: PermissionReportsModerate},
+}
+
+// TransitionError menjelaskan kenapa sebuah perpindahan status ditolak
+type TransitionError struct {
+	From          string
+	To            string
+	Allowed       []string // Status berikutnya yang sah dari status saat ini
+	Forbidden     bool     // Transisi sah tetapi role tidak diizinkan
+	MissingFields []string // Field laporan yang belum terisi
+}
+
+func (e *TransitionError) Error() string {
+	switch {
+	case e.Forbidden:
+		return fmt.Sprintf("role is not allowed to move report from %s to %s", e.From, e.To)
+	case len(e.MissingFields) > 0:
+		return fmt.Sprintf("missing required fields %v to move report to %s", e.MissingFields, e.To)
+	default:
+		return fmt.Sprintf("cannot move report from %s to %s", e.From, e.To)
+	}
+}
+
+// AllowedReportTransitions mengembalikan status berikutnya yang sah dari status saat ini
+func AllowedReportTransitions(from string) []string {
+	allowed := []string{}
+	for _, t := range ReportTransitions {
+		if t.From == from {
+			allowed = append(allowed, t.To)
+		}
+	}
+	return allowed
+}
+
+// CheckReportTransition memvalidasi perpindahan status laporan oleh user dengan permission tertentu
+func CheckReportTransition(report ReportRubbish, to string, permissions []string, reason string) error {
+	for _, t := range ReportTransitions {
+		if t.From != report.Status || t.To != to {
+			continue
+		}
+
+		if !containsString(permissions, t.Permission) {
+			return &TransitionError{From: report.Status, To: to, Allowed: AllowedReportTransitions(report.Status), Forbidden: true}
+		}
+
+		var missing []string
+		for _, field := range t.RequiredFields {
+			if !reportHasField(report, field) {
+				missing = append(missing, field)
+			}
+		}
+		if t.RequiresReason && strings.TrimSpace(reason) == "" {
+			missing = append(missing, "reason")
+		}
+		if len(missing) > 0 {
+			return &TransitionError{From: report.Status, To: to, Allowed: AllowedReportTransitions(report.Status), MissingFields: missing}
+		}
+		return nil
+	}
+
+	return &TransitionError{From: report.Status, To: to, Allowed: AllowedReportTransitions(report.Status)}
+}
+
+// reportHasField mengecek apakah field laporan yang dibutuhkan transisi sudah terisi
+func reportHasField(report ReportRubbish, field string) bool {
+	switch field {
+	case "photo":
+		return report.Photo != ""
+	case "coordinates":
+		return report.Latitude != 0 || report.Longitude != 0
+	case "description":
+		return report.Description != ""
+	case "after_photo":
+		return report.AfterPhoto != ""
+	default:
+		return false
+	}
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
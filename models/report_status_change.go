@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// ReportStatusChange mencatat setiap perpindahan status laporan beserta alasannya
+type ReportStatusChange struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ReportID      uint      `gorm:"index;not null" json:"report_id"`
+	FromStatus    string    `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus      string    `gorm:"type:varchar(20);not null" json:"to_status"`
+	ChangedByID   uint      `gorm:"not null" json:"changed_by_id"`
+	Reason        string    `gorm:"type:text" json:"reason"`         // Ditampilkan ke pelapor
+	InternalNotes string    `gorm:"type:text" json:"internal_notes"` // Hanya untuk admin
+	CreatedAt     time.Time `json:"created_at"`
+	ChangedBy     User      `gorm:"foreignKey:ChangedByID" json:"-"`
+}
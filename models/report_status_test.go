@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckReportTransition(t *testing.T) {
+	moderator := []string{PermissionReportsModerate}
+	complete := ReportRubbish{Photo: "photo.jpg", Latitude: -6.2, Longitude: 106.8, AfterPhoto: "after.jpg"}
+	withStatus := func(report ReportRubbish, status string) ReportRubbish {
+		report.Status = status
+		return report
+	}
+
+	tests := []struct {
+		name          string
+		report        ReportRubbish
+		to            string
+		permissions   []string
+		reason        string
+		wantErr       bool
+		wantForbidden bool
+		wantMissing   []string
+	}{
+		{name: "submitted to in_review", report: withStatus(complete, ReportStatusSubmitted), to: ReportStatusInReview, permissions: moderator},
+		{name: "in_review to approved", report: withStatus(complete, ReportStatusInReview), to: ReportStatusApproved, permissions: moderator},
+		{name: "approved to cleaned_up", report: withStatus(complete, ReportStatusApproved), to: ReportStatusCleanedUp, permissions: moderator},
+		{name: "cleaned_up to closed", report: withStatus(complete, ReportStatusCleanedUp), to: ReportStatusClosed, permissions: moderator},
+		{name: "rejected back to in_review", report: withStatus(complete, ReportStatusRejected), to: ReportStatusInReview, permissions: moderator},
+		{name: "reject with reason", report: withStatus(complete, ReportStatusSubmitted), to: ReportStatusRejected, permissions: moderator, reason: "blurry photo"},
+		{name: "merge approved duplicate", report: withStatus(complete, ReportStatusApproved), to: ReportStatusMerged, permissions: moderator},
+		{
+			name: "submitted cannot skip to cleaned_up", report: withStatus(complete, ReportStatusSubmitted), to: ReportStatusCleanedUp,
+			permissions: moderator, wantErr: true,
+		},
+		{
+			name: "closed is final", report: withStatus(complete, ReportStatusClosed), to: ReportStatusInReview,
+			permissions: moderator, wantErr: true,
+		},
+		{
+			name: "merged is final", report: withStatus(complete, ReportStatusMerged), to: ReportStatusApproved,
+			permissions: moderator, wantErr: true,
+		},
+		{
+			name: "unknown target status", report: withStatus(complete, ReportStatusSubmitted), to: "archived",
+			permissions: moderator, wantErr: true,
+		},
+		{
+			name: "missing permission", report: withStatus(complete, ReportStatusSubmitted), to: ReportStatusApproved,
+			permissions: []string{PermissionReportsView}, wantErr: true, wantForbidden: true,
+		},
+		{
+			name: "no permissions", report: withStatus(complete, ReportStatusSubmitted), to: ReportStatusInReview,
+			wantErr: true, wantForbidden: true,
+		},
+		{
+			name: "approve without photo and coordinates", report: ReportRubbish{Status: ReportStatusSubmitted}, to: ReportStatusApproved,
+			permissions: moderator, wantErr: true, wantMissing: []string{"photo", "coordinates"},
+		},
+		{
+			name: "reject without reason", report: withStatus(complete, ReportStatusInReview), to: ReportStatusRejected,
+			permissions: moderator, reason: "   ", wantErr: true, wantMissing: []string{"reason"},
+		},
+		{
+			name: "clean up without after photo", report: ReportRubbish{Status: ReportStatusApproved, Photo: "photo.jpg"}, to: ReportStatusCleanedUp,
+			permissions: moderator, wantErr: true, wantMissing: []string{"after_photo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReportTransition(tt.report, tt.to, tt.permissions, tt.reason)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("CheckReportTransition() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var transitionErr *TransitionError
+			if !errors.As(err, &transitionErr) {
+				t.Fatalf("CheckReportTransition() error = %v, want *TransitionError", err)
+			}
+			if transitionErr.Forbidden != tt.wantForbidden {
+				t.Errorf("Forbidden = %v, want %v", transitionErr.Forbidden, tt.wantForbidden)
+			}
+			if !reflect.DeepEqual(transitionErr.MissingFields, tt.wantMissing) {
+				t.Errorf("MissingFields = %v, want %v", transitionErr.MissingFields, tt.wantMissing)
+			}
+			if want := AllowedReportTransitions(tt.report.Status); !reflect.DeepEqual(transitionErr.Allowed, want) {
+				t.Errorf("Allowed = %v, want %v", transitionErr.Allowed, want)
+			}
+		})
+	}
+}
+
+func TestAllowedReportTransitions(t *testing.T) {
+	tests := []struct {
+		from string
+		want []string
+	}{
+		{ReportStatusSubmitted, []string{ReportStatusInReview, ReportStatusApproved, ReportStatusRejected, ReportStatusMerged}},
+		{ReportStatusApproved, []string{ReportStatusRejected, ReportStatusCleanedUp, ReportStatusMerged}},
+		{ReportStatusCleanedUp, []string{ReportStatusClosed}},
+		{ReportStatusClosed, []string{}},
+		{ReportStatusMerged, []string{}},
+	}
+	for _, tt := range tests {
+		if got := AllowedReportTransitions(tt.from); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AllowedReportTransitions(%q) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Status penukaran hadiah
+const (
+	RedemptionStatusPending   = "pending"
+	RedemptionStatusFulfilled = "fulfilled"
+	RedemptionStatusCancelled = "cancelled"
+)
+
+// Reward adalah item katalog yang dapat ditukar dengan poin
+type Reward struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string     `gorm:"type:varchar(255);not null" json:"name"`
+	Description string     `gorm:"type:text" json:"description"`
+	ImageURL    string     `gorm:"type:varchar(255)" json:"image_url"`
+	Cost        uint       `gorm:"not null" json:"cost"`
+	Stock       uint       `gorm:"not null;default:0" json:"stock"`
+	ValidFrom   *time.Time `json:"valid_from"`
+	ValidUntil  *time.Time `json:"valid_until"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AvailableAt mengecek apakah hadiah aktif dan masih dalam masa berlaku
+func (r Reward) AvailableAt(at time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	if r.ValidFrom != nil && at.Before(*r.ValidFrom) {
+		return false
+	}
+	if r.ValidUntil != nil && at.After(*r.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// Redemption mencatat penukaran poin user dengan sebuah hadiah
+type Redemption struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint       `gorm:"index;not null" json:"user_id"`
+	RewardID     uint       `gorm:"index;not null" json:"reward_id"`
+	Cost         uint       `gorm:"not null" json:"cost"`
+	VoucherCode  string     `gorm:"type:varchar(32);uniqueIndex;not null" json:"voucher_code"`
+	Status       string     `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	HandledByID  *uint      `json:"handled_by_id"`
+	CancelReason string     `gorm:"type:varchar(255)" json:"cancel_reason"`
+	FulfilledAt  *time.Time `json:"fulfilled_at"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	User         User       `gorm:"foreignKey:UserID" json:"-"`
+	Reward       Reward     `gorm:"foreignKey:RewardID" json:"-"`
+}
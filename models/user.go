@@ -17,4 +17,8 @@ type User struct {
 	Reports      []ReportRubbish `gorm:"foreignKey:UserID" json:"reports"`
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
+
+	LeaderboardOptOut bool `gorm:"default:false" json:"leaderboard_opt_out"` // Tampil anonim di leaderboard
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // Kosong berarti email belum diverifikasi
 }
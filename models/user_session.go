@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession adalah satu login di satu perangkat. ID family refresh token dipakai sebagai
+// kunci sesi dan ikut tertulis di klaim "sid" access token.
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID   string     `gorm:"type:varchar(36);uniqueIndex;not null" json:"-"`
+	DeviceName string     `gorm:"type:varchar(100)" json:"device_name"`
+	IPAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`
+}